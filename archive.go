@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"flag"
+
+	"github.com/Debian/dh-make-golang/pkg/golangdeb"
+)
+
+// archiveFlags holds the command-line flags selecting the distribution and
+// its archive, and looks up Go packages in the archive they select.
+type archiveFlags struct {
+	distroName string
+	source     string
+	suite      string
+}
+
+// addArchiveFlags registers the -distro, -archive and -suite flags on fs.
+func addArchiveFlags(fs *flag.FlagSet) *archiveFlags {
+	var f archiveFlags
+	fs.StringVar(&f.distroName,
+		"distro",
+		golangdeb.DebianDistro.Name,
+		"Distribution to create packages for, \"debian\" or \"ubuntu\", or the\n"+
+			"name or path of a custom JSON profile (see dh-make-golang(1)).")
+	fs.StringVar(&f.source,
+		"archive",
+		"",
+		"Where to look up which Go packages are in the distribution (default:\n"+
+			"\"ftp-master\" for Debian, or \"mirror\" with -suite), one of:\n"+
+			` * "ftp-master": the ftp-master API, covering all suites`+"\n"+
+			` * "apt": the Packages and Sources indices in `+golangdeb.AptListsDir+"\n"+
+			` * "mirror": the Sources indices on the mirror of the distribution`+"\n"+
+			" * the URL of a mirror, or of a Packages or Sources index\n"+
+			" * the path or glob pattern of local Packages or Sources indices")
+	fs.StringVar(&f.suite,
+		"suite",
+		"",
+		"Only consider Go packages in this suite (e.g. sid, trixie or\n"+
+			"bookworm-backports). Not supported with -archive=ftp-master.")
+	return &f
+}
+
+// distro returns the profile selected by the -distro flag.
+func (f *archiveFlags) distro() (*golangdeb.DistroProfile, error) {
+	return golangdeb.LoadDistroProfile(f.distroName)
+}
+
+// target returns the suite selected by the -suite flag, by default the
+// development suite of the distribution.
+func (f *archiveFlags) target() (golangdeb.TargetSuite, error) {
+	d, err := f.distro()
+	if err != nil {
+		return golangdeb.TargetSuite{}, err
+	}
+	return d.ParseSuite(f.suite)
+}
+
+// unstableBinaries returns the Go packages in the development suite if the
+// -suite flag selects a suite to which missing dependencies need to be
+// backported, and nil otherwise.
+func (f *archiveFlags) unstableBinaries(ctx context.Context) (map[string]golangdeb.DebianPackage, error) {
+	d, err := f.distro()
+	if err != nil {
+		return nil, err
+	}
+	ts, err := d.ParseSuite(f.suite)
+	if err != nil || !ts.NeedsBackports() {
+		return nil, err
+	}
+	source := f.source
+	if source == "" || source == "ftp-master" {
+		// The ftp-master API covers all suites, including experimental,
+		// and would count packages which are not in unstable.
+		source = "mirror"
+	}
+	a, err := golangdeb.NewArchive(d, source, d.DevelSuite)
+	if err != nil {
+		return nil, err
+	}
+	return a.GolangBinaries(ctx)
+}
+
+func (f *archiveFlags) golangBinaries(ctx context.Context) (map[string]golangdeb.DebianPackage, error) {
+	d, err := f.distro()
+	if err != nil {
+		return nil, err
+	}
+	a, err := golangdeb.NewArchive(d, f.source, f.suite)
+	if err != nil {
+		return nil, err
+	}
+	return a.GolangBinaries(ctx)
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Debian/dh-make-golang/pkg/golangdeb"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestUnstableBinaries(t *testing.T) {
+	// A mirror with a Go package in sid and one only in experimental.
+	sources := map[string]string{
+		"/dists/sid/main/source/Sources.gz":          "Package: golang-foo\nBinary: golang-foo-dev\nVersion: 1.0-1\nXS-Go-Import-Path: example.org/foo\n",
+		"/dists/experimental/main/source/Sources.gz": "Package: golang-bar\nBinary: golang-bar-dev\nVersion: 2.0-1\nXS-Go-Import-Path: example.org/bar\n",
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, ok := sources[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		gz := gzip.NewWriter(w)
+		gz.Write([]byte(content))
+		gz.Close()
+	}))
+	defer ts.Close()
+	profile := filepath.Join(t.TempDir(), "test.json")
+	if err := os.WriteFile(profile, []byte(`{"name": "test", "mirror": "`+ts.URL+`"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]golangdeb.DebianPackage{
+		"example.org/foo": {Binary: "golang-foo-dev", Source: "golang-foo", Version: "1.0-1"},
+	}
+	for _, source := range []string{"", "ftp-master", "mirror"} {
+		f := &archiveFlags{distroName: profile, source: source, suite: "bookworm-backports"}
+		got, err := f.unstableBinaries(t.Context())
+		if err != nil {
+			t.Fatalf("-archive=%q: %v", source, err)
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("-archive=%q: unstableBinaries: diff (-want +got):\n%s", source, diff)
+		}
+	}
+
+	f := &archiveFlags{distroName: profile, suite: "sid"}
+	if got, err := f.unstableBinaries(t.Context()); err != nil || got != nil {
+		t.Errorf("-suite=sid: unstableBinaries = %v, %v, want nil", got, err)
+	}
+}
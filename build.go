@@ -0,0 +1,264 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/Debian/dh-make-golang/pkg/golangdeb"
+)
+
+const defaultGocodeDir = "/usr/share/gocode"
+
+var (
+	// cannotFindPackageRegexp matches the error printed by the go command
+	// in GOPATH mode when an imported package is not available.
+	cannotFindPackageRegexp = regexp.MustCompile(`cannot find package "([^"]+)"`)
+
+	// noRequiredModuleRegexp matches the error printed by the go command
+	// when a package is not provided by any module in GOPATH.
+	noRequiredModuleRegexp = regexp.MustCompile(`no required module provides package ([^;:\s]+)`)
+
+	// testFailRegexp matches the per-test failure lines of “go test”.
+	testFailRegexp = regexp.MustCompile(`^\s*--- FAIL: (\S+)`)
+
+	// packageFailRegexp matches the per-package failure lines of “go test”.
+	packageFailRegexp = regexp.MustCompile(`^FAIL\s+(\S+)`)
+)
+
+// buildReport summarizes the result of a trial build of the generated
+// package source.
+type buildReport struct {
+	missing     []string     // import paths not available in the build GOPATH
+	buildFailed bool         // whether “go build” failed
+	testsFailed []string     // failing tests, as package.TestName or package
+	todos       []todoMarker // leftover TODO markers in debian/
+}
+
+// todoMarker is a line containing “TODO” in one of the packaging files.
+type todoMarker struct {
+	file string // path relative to the package directory
+	line int    // 1-based line number
+	text string // line content, without leading or trailing whitespace
+}
+
+func (t todoMarker) String() string {
+	return fmt.Sprintf("%s:%d: %s", t.file, t.line, t.text)
+}
+
+// ok reports whether the trial build found nothing that requires attention.
+func (r *buildReport) ok() bool {
+	return !r.buildFailed && len(r.missing) == 0 && len(r.testsFailed) == 0
+}
+
+// buildEnv returns the environment for building in the given GOPATH list,
+// without network access and without the module cache, similar to what
+// dh-golang does during a package build.
+func buildEnv(gopath, gocache string) []string {
+	return append([]string{
+		"GOPATH=" + gopath,
+		"GO111MODULE=off",
+		"GOPROXY=off",
+		"GOFLAGS=",
+		"GOCACHE=" + gocache,
+	}, golangdeb.PassthroughEnv()...)
+}
+
+// parseMissingPackages extracts the import paths the go command could not
+// find from its output.
+func parseMissingPackages(out []byte) []string {
+	seen := make(map[string]bool)
+	var missing []string
+	for _, re := range []*regexp.Regexp{cannotFindPackageRegexp, noRequiredModuleRegexp} {
+		for _, m := range re.FindAllSubmatch(out, -1) {
+			pkg := string(m[1])
+			if !seen[pkg] {
+				seen[pkg] = true
+				missing = append(missing, pkg)
+			}
+		}
+	}
+	slices.Sort(missing)
+	return missing
+}
+
+// parseTestFailures extracts the failed tests from the output of “go test”.
+// Packages which failed without a failing test (e.g. because they did not
+// compile) are listed by their import path.
+func parseTestFailures(out []byte) []string {
+	var failures []string
+	var pending []string
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if m := testFailRegexp.FindStringSubmatch(line); m != nil {
+			pending = append(pending, m[1])
+			continue
+		}
+		if m := packageFailRegexp.FindStringSubmatch(line); m != nil {
+			if len(pending) == 0 {
+				failures = append(failures, m[1])
+			}
+			for _, test := range pending {
+				failures = append(failures, m[1]+"."+test)
+			}
+			pending = nil
+		}
+	}
+	return failures
+}
+
+// findTODOs returns all lines containing a TODO marker within the debian/
+// directory of dir.
+func findTODOs(dir string) ([]todoMarker, error) {
+	var todos []todoMarker
+	debdir := filepath.Join(dir, "debian")
+	err := filepath.Walk(debdir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if info.Name() == "upstream_debian" || info.Name() == "_build" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return fmt.Errorf("filepath.Rel: %w", err)
+		}
+		for i, line := range strings.Split(string(b), "\n") {
+			if strings.Contains(line, "TODO") {
+				todos = append(todos, todoMarker{file: rel, line: i + 1, text: strings.TrimSpace(line)})
+			}
+		}
+		return nil
+	})
+	return todos, err
+}
+
+// trialBuild builds and tests the package source in dir as gopkg, in a
+// clean GOPATH which only contains gocodeDir (usually /usr/share/gocode,
+// where the -dev packages install their sources) besides the package itself.
+func trialBuild(ctx context.Context, dir, gopkg, gocodeDir string) (*buildReport, error) {
+	gopath, err := os.MkdirTemp("", "dh-make-golang")
+	if err != nil {
+		return nil, fmt.Errorf("create temp dir: %w", err)
+	}
+	defer func() {
+		if err := golangdeb.ForceRemoveAll(gopath); err != nil {
+			log.Printf("could not remove all %s: %v", gopath, err)
+		}
+	}()
+
+	// Copy instead of symlinking the sources, as the go command does not
+	// follow symlinks when expanding “...” patterns.
+	pkgdir := filepath.Join(gopath, "src", gopkg)
+	if err := os.MkdirAll(pkgdir, 0755); err != nil {
+		return nil, fmt.Errorf("mkdir: %w", err)
+	}
+	cmd := exec.CommandContext(ctx, "cp", "-a", dir+"/.", pkgdir)
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("copy sources: %w", err)
+	}
+	// Just like dh-golang, build the sources without upstream debian/ and .git/.
+	for _, sub := range []string{".git", "debian"} {
+		if err := os.RemoveAll(filepath.Join(pkgdir, sub)); err != nil {
+			return nil, fmt.Errorf("remove all: %w", err)
+		}
+	}
+
+	gopathList := gopath
+	if gocodeDir != "" {
+		if _, err := os.Stat(gocodeDir); err != nil {
+			log.Printf("WARNING: %s not found, building without installed Go packages", gocodeDir)
+		} else {
+			gopathList += string(filepath.ListSeparator) + gocodeDir
+		}
+	}
+	env := buildEnv(gopathList, filepath.Join(gopath, "cache"))
+
+	var report buildReport
+
+	log.Printf("Trial build: running \"go build %s/...\"", gopkg)
+	cmd = exec.CommandContext(ctx, "go", "build", gopkg+"/...")
+	cmd.Dir = pkgdir
+	cmd.Env = env
+	out, err := cmd.CombinedOutput()
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if err != nil {
+		report.buildFailed = true
+		fmt.Fprint(os.Stderr, string(out))
+	}
+	report.missing = parseMissingPackages(out)
+
+	if !report.buildFailed {
+		log.Printf("Trial build: running \"go test %s/...\"", gopkg)
+		cmd = exec.CommandContext(ctx, "go", "test", gopkg+"/...")
+		cmd.Dir = pkgdir
+		cmd.Env = env
+		out, err = cmd.CombinedOutput()
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if err != nil {
+			report.testsFailed = parseTestFailures(out)
+			if len(report.testsFailed) == 0 {
+				// Could not determine anything more specific.
+				report.testsFailed = []string{gopkg + "/..."}
+			}
+			fmt.Fprint(os.Stderr, string(out))
+		}
+	}
+
+	report.todos, err = findTODOs(dir)
+	if err != nil {
+		return &report, fmt.Errorf("find TODOs: %w", err)
+	}
+
+	return &report, nil
+}
+
+// printBuildReport prints the trial build report in a human-readable form.
+func printBuildReport(report *buildReport) {
+	fmt.Printf("Trial build results:\n")
+	if report.ok() {
+		fmt.Printf("    Build and tests succeeded\n")
+	} else if report.buildFailed {
+		fmt.Printf("    Build FAILED\n")
+	}
+	if len(report.missing) > 0 {
+		fmt.Printf("    Missing Go packages (not found in the build GOPATH):\n")
+		for _, pkg := range report.missing {
+			fmt.Printf("        %s\n", pkg)
+		}
+	}
+	if len(report.testsFailed) > 0 {
+		fmt.Printf("    Failing tests:\n")
+		for _, test := range report.testsFailed {
+			fmt.Printf("        %s\n", test)
+		}
+	}
+	if len(report.todos) > 0 {
+		fmt.Printf("    Remaining TODOs in debian/:\n")
+		for _, todo := range report.todos {
+			fmt.Printf("        %s\n", todo)
+		}
+	}
+	fmt.Printf("\n")
+}
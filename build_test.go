@@ -0,0 +1,72 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseMissingPackages(t *testing.T) {
+	out := `src/example.com/foo/foo.go:5:2: cannot find package "github.com/bar/baz" in any of:
+	/usr/lib/go/src/github.com/bar/baz (from $GOROOT)
+	/tmp/dh-make-golang123/src/github.com/bar/baz (from $GOPATH)
+src/example.com/foo/cmd/foo/main.go:6:2: cannot find package "github.com/bar/baz" in any of:
+src/example.com/foo/qux.go:7:2: cannot find package "example.org/qux" in any of:
+`
+	got := parseMissingPackages([]byte(out))
+	want := []string{"example.org/qux", "github.com/bar/baz"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseMissingPackages() => %v, want %v", got, want)
+	}
+}
+
+func TestParseTestFailures(t *testing.T) {
+	out := `ok  	example.com/foo	0.004s
+--- FAIL: TestBar (0.00s)
+    bar_test.go:10: unexpected result
+--- FAIL: TestBaz (0.00s)
+FAIL
+FAIL	example.com/foo/bar	0.005s
+# example.com/foo/broken
+broken/broken.go:3:1: syntax error
+FAIL	example.com/foo/broken [build failed]
+FAIL
+`
+	got := parseTestFailures([]byte(out))
+	want := []string{
+		"example.com/foo/bar.TestBar",
+		"example.com/foo/bar.TestBaz",
+		"example.com/foo/broken",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseTestFailures() => %v, want %v", got, want)
+	}
+}
+
+func TestFindTODOs(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "debian", "upstream_debian"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"debian/control":                  "Source: foo\nSection: TODO\n",
+		"debian/rules":                    "#!/usr/bin/make -f\n",
+		"debian/upstream_debian/foo":      "TODO: ignored\n",
+		"upstream-file-outside-debian.go": "// TODO: ignored\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := findTODOs(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []todoMarker{{file: "debian/control", line: 2, text: "Section: TODO"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("findTODOs() => %v, want %v", got, want)
+	}
+}
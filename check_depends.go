@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"log"
@@ -8,8 +9,8 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/Debian/dh-make-golang/pkg/golangdeb"
 	"golang.org/x/mod/modfile"
-	"golang.org/x/tools/go/vcs"
 	"pault.ag/go/debian/control"
 )
 
@@ -19,7 +20,7 @@ type dependency struct {
 	// todo version?
 }
 
-func execCheckDepends(args []string) {
+func execCheckDepends(ctx context.Context, args []string) {
 	fs := flag.NewFlagSet("check-depends", flag.ExitOnError)
 	fs.Usage = func() {
 		fmt.Fprintf(os.Stderr, `Usage: %s check-depends
@@ -35,6 +36,8 @@ This command takes no arguments.
 `, os.Args[0])
 	}
 
+	arch := addArchiveFlags(fs)
+
 	if err := fs.Parse(args); err != nil {
 		log.Fatal(err)
 	}
@@ -50,13 +53,19 @@ This command takes no arguments.
 	}
 
 	// Load the already packaged Go modules
-	golangBinaries, err := getGolangBinaries()
+	golangBinaries, err := arch.golangBinaries(ctx)
 	if err != nil {
 		log.Fatalf("error while getting packaged Go modules: %s", err)
 	}
 
+	// Load the Go modules packaged in unstable, if they need to be backported
+	unstableBinaries, err := arch.unstableBinaries(ctx)
+	if err != nil {
+		log.Fatalf("error while getting packaged Go modules in unstable: %s", err)
+	}
+
 	// Load the dependencies defined in the Go module (go.mod)
-	goModDepds, err := parseGoModDependencies(cwd, golangBinaries)
+	goModDepds, err := parseGoModDependencies(ctx, cwd, golangBinaries)
 	if err != nil {
 		log.Fatalf("error while parsing go.mod: %s", err)
 	}
@@ -74,6 +83,10 @@ This command takes no arguments.
 		found := false
 
 		if goModDep.packageName == "" {
+			if pkg, ok := unstableBinaries[goModDep.importPath]; ok {
+				fmt.Printf("NEW dependency %s (%s) needs to be backported to %s\n", goModDep.importPath, pkg.Binary, arch.suite)
+				continue
+			}
 			fmt.Printf("NEW dependency %s is NOT yet packaged in Debian\n", goModDep.importPath)
 			continue
 		}
@@ -116,7 +129,7 @@ This command takes no arguments.
 // parseGoModDependencies parse ALL dependencies listed in go.mod
 // i.e. it returns the one defined in go.mod as well as the transitively ones
 // TODO: this may not be the best way of doing thing since it requires the package to be converted to go module
-func parseGoModDependencies(directory string, goBinaries map[string]debianPackage) ([]dependency, error) {
+func parseGoModDependencies(ctx context.Context, directory string, goBinaries map[string]golangdeb.DebianPackage) ([]dependency, error) {
 	b, err := os.ReadFile(filepath.Join(directory, "go.mod"))
 	if err != nil {
 		return nil, err
@@ -127,27 +140,34 @@ func parseGoModDependencies(directory string, goBinaries map[string]debianPackag
 		return nil, err
 	}
 
-	var dependencies []dependency
+	var direct []string
 	for _, require := range modFile.Require {
 		if !require.Indirect {
-			packageName := ""
-
-			// Translate all packages to the root of their repository
-			rr, err := vcs.RepoRootForImportPath(require.Mod.Path, false)
-			if err != nil {
-				log.Printf("Could not determine repo path for import path %q: %v\n", require.Mod.Path, err)
-				continue
-			}
+			direct = append(direct, require.Mod.Path)
+		}
+	}
 
-			if val, exists := goBinaries[rr.Root]; exists {
-				packageName = val.binary
-			}
+	// Translate all packages to the root of their repository
+	roots, err := golangdeb.DefaultRepoRootResolver.RepoRoots(ctx, direct)
+	if err != nil {
+		return nil, err
+	}
 
-			dependencies = append(dependencies, dependency{
-				importPath:  rr.Root,
-				packageName: packageName,
-			})
+	var dependencies []dependency
+	for _, path := range direct {
+		root, ok := roots[path]
+		if !ok {
+			continue
 		}
+		packageName := ""
+		if val, exists := goBinaries[root]; exists {
+			packageName = val.Binary
+		}
+
+		dependencies = append(dependencies, dependency{
+			importPath:  root,
+			packageName: packageName,
+		})
 	}
 
 	return dependencies, nil
@@ -7,6 +7,8 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+
+	"github.com/Debian/dh-make-golang/pkg/golangdeb"
 )
 
 func TestParseDebianControlDependencies(t *testing.T) {
@@ -112,10 +114,10 @@ require (
 		t.Fatalf("Could not create dummy Debian package: %v", err)
 	}
 
-	deps, err := parseGoModDependencies(filepath.Join(tmpDir, "dummy-package"), map[string]debianPackage{
-		"github.com/charmbracelet/glamour": {binary: "golang-github-charmbracelet-glamour-dev", source: "golang-github-charmbracelet-glamour"},
-		"github.com/google/go-github":      {binary: "golang-github-google-go-github-dev", source: "golang-github-google-go-github"},
-		"github.com/gregjones/httpcache":   {binary: "golang-github-gregjones-httpcache-dev", source: "golang-github-gregjones-httpcache"},
+	deps, err := parseGoModDependencies(t.Context(), filepath.Join(tmpDir, "dummy-package"), map[string]golangdeb.DebianPackage{
+		"github.com/charmbracelet/glamour": {Binary: "golang-github-charmbracelet-glamour-dev", Source: "golang-github-charmbracelet-glamour"},
+		"github.com/google/go-github":      {Binary: "golang-github-google-go-github-dev", Source: "golang-github-google-go-github"},
+		"github.com/gregjones/httpcache":   {Binary: "golang-github-gregjones-httpcache-dev", Source: "golang-github-gregjones-httpcache"},
 	})
 	if err != nil {
 		t.Fatalf("Could not parse go.mod dependencies: %v", err)
@@ -1,38 +1,452 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"log"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/Debian/dh-make-golang/pkg/golangdeb"
+	"golang.org/x/sync/errgroup"
+	"pault.ag/go/debian/control"
 )
 
-func execClone(args []string) {
+// parseVcsGit splits the value of a Vcs-Git field, e.g.
+// “https://salsa.debian.org/go-team/packages/foo.git -b debian/sid”, into
+// the repository URL and the branch, if any.
+func parseVcsGit(value string) (repo, branch string) {
+	fields := strings.Fields(value)
+	if len(fields) == 0 {
+		return "", ""
+	}
+	repo = fields[0]
+	for i := 1; i < len(fields)-1; i++ {
+		if fields[i] == "-b" {
+			branch = fields[i+1]
+		}
+	}
+	return repo, branch
+}
+
+// sourceForImportPath returns the source package shipping importPath, or
+// the closest of its parents, according to golangBinaries.
+func sourceForImportPath(importPath string, golangBinaries map[string]golangdeb.DebianPackage) (string, bool) {
+	for p := importPath; ; {
+		if pkg, ok := golangBinaries[p]; ok {
+			return pkg.Source, true
+		}
+		i := strings.LastIndex(p, "/")
+		if i < 0 {
+			return "", false
+		}
+		p = p[:i]
+	}
+}
+
+// isDEP14Branch reports whether branch is one of the branches DEP-14
+// defines for packaging repositories.
+func isDEP14Branch(branch string) bool {
+	switch {
+	case branch == "upstream", branch == "pristine-tar":
+		return true
+	case strings.HasPrefix(branch, "upstream/"),
+		strings.HasPrefix(branch, golangdeb.DebianDistro.BranchPrefix),
+		strings.HasPrefix(branch, golangdeb.UbuntuDistro.BranchPrefix):
+		return true
+	}
+	return false
+}
+
+// clonePackagingRepository clones the packaging repository at repo into
+// dir, checking out branch (the default branch if empty), and creates
+// local branches tracking all remote DEP-14 branches, so that gbp and
+// pristine-tar find them.
+func clonePackagingRepository(ctx context.Context, repo, branch, dir string) error {
+	args := []string{"clone"}
+	if branch != "" {
+		args = append(args, "--branch", branch)
+	}
+	args = append(args, repo, dir)
+	log.Printf("Running \"git %s\"\n", strings.Join(args, " "))
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("git clone %s: %w", repo, err)
+	}
+
+	cmd = exec.CommandContext(ctx, "git", "for-each-ref", "--format=%(refname:lstrip=3)", "refs/remotes/origin/")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("git for-each-ref: %w", err)
+	}
+	cmd = exec.CommandContext(ctx, "git", "rev-parse", "--abbrev-ref", "HEAD")
+	cmd.Dir = dir
+	head, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("git rev-parse HEAD: %w", err)
+	}
+	for remoteBranch := range strings.FieldsSeq(string(out)) {
+		if remoteBranch == "HEAD" || remoteBranch == strings.TrimSpace(string(head)) || !isDEP14Branch(remoteBranch) {
+			continue
+		}
+		if err := golangdeb.RunGitCommandIn(ctx, dir, "branch", "--track", remoteBranch, "origin/"+remoteBranch); err != nil {
+			return fmt.Errorf("git branch --track %s: %w", remoteBranch, err)
+		}
+	}
+	return nil
+}
+
+// goImportPath returns the (first) XS-Go-Import-Path of the package in dir.
+func goImportPath(dir string) (string, error) {
+	ctrl, err := control.ParseControlFile(filepath.Join(dir, "debian", "control"))
+	if err != nil {
+		return "", fmt.Errorf("parse debian/control: %w", err)
+	}
+	paths := golangdeb.SplitList(ctrl.Source.Values["XS-Go-Import-Path"])
+	if len(paths) == 0 {
+		return "", fmt.Errorf("debian/control has no XS-Go-Import-Path field")
+	}
+	return paths[0], nil
+}
+
+// cloner clones packaging repositories of a distribution.
+type cloner struct {
+	d                      *golangdeb.DistroProfile
+	sources                map[string]sourceEntry             // may be nil
+	golangBinaries         map[string]golangdeb.DebianPackage // needed for import paths
+	allowUnknownHoster     bool
+	includeUpstreamHistory bool
+	origtargz              bool // download the orig tarball with origtargz
+}
+
+// sourcePackage maps arg, a source package name or a Go import path, to
+// a source package name.
+func (c *cloner) sourcePackage(arg string) (string, error) {
+	if !strings.Contains(arg, "/") {
+		return arg, nil
+	}
+	src, ok := sourceForImportPath(arg, c.golangBinaries)
+	if !ok {
+		return "", fmt.Errorf("%s is not packaged in %s", arg, c.d.Name)
+	}
+	return src, nil
+}
+
+// clone clones the packaging repository of the source package src into
+// the directory src and sets it up like golangdeb.CreateGitRepository does. It
+// reports whether src was skipped because the directory already exists.
+func (c *cloner) clone(ctx context.Context, src string) (skipped bool, err error) {
+	if _, err := os.Stat(src); err == nil {
+		return true, nil
+	}
+
+	repo, branch := c.d.VcsURL+src+".git", ""
+	if entry, ok := c.sources[src]; ok && entry.vcsGit != "" {
+		repo, branch = parseVcsGit(entry.vcsGit)
+	} else {
+		log.Printf("No Vcs-Git field for %s in the Sources indices, assuming %s", src, repo)
+	}
+
+	defer func() {
+		if err != nil {
+			// Do not leave a partial clone behind, which the next run would
+			// skip as already cloned.
+			os.RemoveAll(src)
+		}
+	}()
+
+	if err := clonePackagingRepository(ctx, repo, branch, src); err != nil {
+		return false, err
+	}
+	if err := golangdeb.ConfigureGitRepository(ctx, src); err != nil {
+		return false, err
+	}
+	if err := golangdeb.ConfigureOriginPush(ctx, src); err != nil {
+		return false, err
+	}
+
+	if c.includeUpstreamHistory {
+		err := func() error {
+			gopkg, err := goImportPath(src)
+			if err != nil {
+				return err
+			}
+			remote, err := golangdeb.UpstreamRemoteName(gopkg, c.allowUnknownHoster)
+			if err != nil {
+				return err
+			}
+			rr, err := golangdeb.ResolveRepoRoot(ctx, gopkg)
+			if err != nil {
+				return fmt.Errorf("determine repo root of %s: %w", gopkg, err)
+			}
+			return golangdeb.AddUpstreamRemote(ctx, src, remote, rr.Repo)
+		}()
+		if err != nil {
+			log.Printf("WARNING: %s: not adding the upstream remote: %v", src, err)
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	if c.origtargz {
+		cmd := exec.CommandContext(ctx, "origtargz")
+		cmd.Dir = src
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			return false, fmt.Errorf("could not run %v: %w", cmd.Args, err)
+		}
+	}
+	return false, nil
+}
+
+// cloneResult is the outcome of cloning one package in a batch.
+type cloneResult struct {
+	src     string
+	skipped bool
+	err     error
+}
+
+// cloneAll clones the packaging repositories of srcs, at most jobs at a
+// time, and returns the result for each of them in the same order.
+func (c *cloner) cloneAll(ctx context.Context, srcs []string, jobs int) []cloneResult {
+	results := make([]cloneResult, len(srcs))
+	var eg errgroup.Group
+	eg.SetLimit(jobs)
+	for i, src := range srcs {
+		eg.Go(func() error {
+			skipped, err := c.clone(ctx, src)
+			results[i] = cloneResult{src: src, skipped: skipped, err: err}
+			return nil // reported in the summary
+		})
+	}
+	eg.Wait()
+	return results
+}
+
+// readPackageList reads the package names or import paths listed in the
+// file at path, one per line. Empty lines and lines starting with # are
+// ignored.
+func readPackageList(path string) ([]string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var list []string
+	for line := range strings.Lines(string(b)) {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		list = append(list, line)
+	}
+	return list, nil
+}
+
+// packagedDependencies returns the source packages in golangBinaries which
+// ship importpath or one of the modules it depends on, transitively.
+func packagedDependencies(ctx context.Context, importpath string, golangBinaries map[string]golangdeb.DebianPackage) ([]string, error) {
+	gopath, repodir, cleanup, err := getInDummyModule(ctx, importpath, "", "")
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	cmd := exec.CommandContext(ctx, "go", "list", "-m", "-f", "{{if not .Main}}{{.Path}}{{end}}", "all")
+	cmd.Dir = repodir
+	cmd.Stderr = os.Stderr
+	cmd.Env = append([]string{
+		"GOPATH=" + gopath,
+	}, golangdeb.PassthroughEnv()...)
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("go list: args: %v; error: %w", cmd.Args, err)
+	}
+
+	var srcs []string
+	for mod := range strings.FieldsSeq(string(out)) {
+		if src, ok := sourceForImportPath(mod, golangBinaries); ok && !slices.Contains(srcs, src) {
+			srcs = append(srcs, src)
+		}
+	}
+	slices.Sort(srcs)
+	return srcs, nil
+}
+
+func execClone(ctx context.Context, args []string) {
 	fs := flag.NewFlagSet("clone", flag.ExitOnError)
 
 	fs.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: %s clone <package-name>\n", os.Args[0])
-		fmt.Fprintf(os.Stderr, "Clone a Go package from Salsa\n"+
-			"and download the appropriate tarball.\n")
+		fmt.Fprintf(os.Stderr, "Usage: %s clone [flags] <package-name|go-package-importpath>\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s clone [flags] -deps <go-package-importpath>\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s clone [flags] -from-file <file>\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Clone the packaging repository of a Go package, add the upstream\n"+
+			"repository as remote and download the appropriate tarball.\n")
 		fmt.Fprintf(os.Stderr, "Example: %s clone golang-github-mmcdole-goxpp\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Example: %s clone github.com/mmcdole/goxpp\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Example: %s clone -deps github.com/mmcdole/gofeed\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "\n")
+		fmt.Fprintf(os.Stderr, "Flags:\n")
+		fs.PrintDefaults()
 	}
 
+	arch := addArchiveFlags(fs)
+
+	var sourcesGlob string
+	fs.StringVar(&sourcesGlob,
+		"sources",
+		defaultSourcesGlob,
+		"Glob pattern of the APT Sources indices (plain or .gz) to look up\n"+
+			"the Vcs-Git field in. Without matching indices, the repository is\n"+
+			"assumed to be where new packages of the distribution are hosted.")
+
+	var allowUnknownHoster bool
+	fs.BoolVar(&allowUnknownHoster,
+		"allow_unknown_hoster",
+		false,
+		"The pkg-go naming conventions use a canonical identifier for\n"+
+			"the hostname (see https://go-team.pages.debian.net/packaging.html),\n"+
+			"and the mapping is hardcoded into dh-make-golang.\n"+
+			"In case you want to package a Go package living on an unknown hoster,\n"+
+			"you may set this flag to true and double-check that the resulting\n"+
+			"remote name is sane. Contact pkg-go if unsure.")
+
+	var includeUpstreamHistory bool
+	fs.BoolVar(&includeUpstreamHistory,
+		"upstream_git_history",
+		true,
+		"Add the upstream repository as remote and fetch its tags.")
+
+	var deps bool
+	fs.BoolVar(&deps,
+		"deps",
+		false,
+		"Clone the packages of the given Go import path and of all its\n"+
+			"(transitive) dependencies which are packaged.")
+
+	var fromFile string
+	fs.StringVar(&fromFile,
+		"from-file",
+		"",
+		"Clone the packages listed in this file, one package name or\n"+
+			"Go import path per line.")
+
+	var jobs int
+	fs.IntVar(&jobs,
+		"jobs",
+		4,
+		"Number of packages to clone in parallel with -deps or -from-file.")
+
 	err := fs.Parse(args)
 	if err != nil {
 		log.Fatalf("parse args: %s", err)
 	}
 
-	if fs.NArg() != 1 {
+	wantArgs := 1
+	if fromFile != "" {
+		wantArgs = 0
+	}
+	if fs.NArg() != wantArgs || (deps && fromFile != "") {
 		fs.Usage()
 		os.Exit(1)
 	}
 
-	cmd := exec.Command("gbp", "clone", fmt.Sprintf("vcsgit:%s", fs.Arg(0)), "--postclone=origtargz")
-	cmd.Stderr = os.Stderr
-	if err := cmd.Run(); err != nil {
-		log.Fatalf("Could not run %v: %v", cmd.Args, err)
+	d, err := arch.distro()
+	if err != nil {
+		log.Fatal(err)
+	}
+	c := &cloner{
+		d:                      d,
+		allowUnknownHoster:     allowUnknownHoster,
+		includeUpstreamHistory: includeUpstreamHistory,
+	}
+
+	if _, err := exec.LookPath("origtargz"); err == nil {
+		c.origtargz = true
+	} else {
+		log.Printf("origtargz (devscripts) is not installed, not downloading orig tarballs")
+	}
+
+	list := fs.Args()
+	if fromFile != "" {
+		if list, err = readPackageList(fromFile); err != nil {
+			log.Fatalf("read package list: %v", err)
+		}
+	}
+	if deps || slices.ContainsFunc(list, func(arg string) bool { return strings.Contains(arg, "/") }) {
+		if c.golangBinaries, err = arch.golangBinaries(ctx); err != nil {
+			log.Fatalf("get Go packages: %v", err)
+		}
+	}
+	if c.sources, err = loadSourcesIndices(ctx, sourcesGlob); err != nil {
+		log.Printf("Could not load Sources indices: %v", err)
+	}
+
+	if !deps && fromFile == "" {
+		src, err := c.sourcePackage(fs.Arg(0))
+		if err != nil {
+			log.Fatal(err)
+		}
+		if src != fs.Arg(0) {
+			log.Printf("%s is packaged in source package %s\n", fs.Arg(0), src)
+		}
+		skipped, err := c.clone(ctx, src)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if skipped {
+			log.Fatalf("%s already exists", src)
+		}
+		fmt.Printf("Successfully cloned %s into %s\n", fs.Arg(0), src)
+		return
+	}
+
+	var srcs []string
+	var failed []cloneResult
+	if deps {
+		log.Printf("Determining the dependencies of %s\n", fs.Arg(0))
+		if srcs, err = packagedDependencies(ctx, fs.Arg(0), c.golangBinaries); err != nil {
+			log.Fatalf("determine dependencies: %v", err)
+		}
+	} else {
+		for _, arg := range list {
+			src, err := c.sourcePackage(arg)
+			if err != nil {
+				failed = append(failed, cloneResult{src: arg, err: err})
+				continue
+			}
+			if !slices.Contains(srcs, src) {
+				srcs = append(srcs, src)
+			}
+		}
 	}
 
-	fmt.Printf("Successfully cloned %s\n", fs.Arg(0))
+	var cloned, skipped []string
+	for _, r := range c.cloneAll(ctx, srcs, jobs) {
+		switch {
+		case r.err != nil:
+			failed = append(failed, r)
+		case r.skipped:
+			skipped = append(skipped, r.src)
+		default:
+			cloned = append(cloned, r.src)
+		}
+	}
+
+	fmt.Printf("\nCloned %d packages, skipped %d already present, %d failed\n", len(cloned), len(skipped), len(failed))
+	for _, src := range skipped {
+		fmt.Printf("  skipped %s\n", src)
+	}
+	for _, r := range failed {
+		fmt.Printf("  FAILED  %s: %v\n", r.src, r.err)
+	}
+	if len(failed) > 0 {
+		os.Exit(1)
+	}
 }
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Debian/dh-make-golang/pkg/golangdeb"
+	"github.com/google/go-cmp/cmp"
+)
+
+func gitCmdOrFatal(t *testing.T, tempdir string, arg ...string) {
+	cmd := exec.Command("git", arg...)
+	cmd.Dir = tempdir
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("Could not run %v: %v", cmd.Args, err)
+	}
+}
+
+func TestParseVcsGit(t *testing.T) {
+	for _, tt := range []struct {
+		value, repo, branch string
+	}{
+		{"https://salsa.debian.org/go-team/packages/foo.git", "https://salsa.debian.org/go-team/packages/foo.git", ""},
+		{"https://salsa.debian.org/go-team/packages/foo.git -b debian/sid", "https://salsa.debian.org/go-team/packages/foo.git", "debian/sid"},
+		{"https://salsa.debian.org/foo.git -b debian/sid [golang/foo]", "https://salsa.debian.org/foo.git", "debian/sid"},
+		{"", "", ""},
+	} {
+		repo, branch := parseVcsGit(tt.value)
+		if repo != tt.repo || branch != tt.branch {
+			t.Errorf("parseVcsGit(%q) = %q, %q, want %q, %q", tt.value, repo, branch, tt.repo, tt.branch)
+		}
+	}
+}
+
+func TestSourceForImportPath(t *testing.T) {
+	golangBinaries := map[string]golangdeb.DebianPackage{
+		"github.com/foo/bar":    {Binary: "golang-github-foo-bar-dev", Source: "golang-github-foo-bar"},
+		"github.com/foo/bar/v2": {Binary: "golang-github-foo-bar-v2-dev", Source: "golang-github-foo-bar-v2"},
+	}
+	for _, tt := range []struct {
+		importPath, want string
+	}{
+		{"github.com/foo/bar", "golang-github-foo-bar"},
+		{"github.com/foo/bar/cmd/bar", "golang-github-foo-bar"},
+		{"github.com/foo/bar/v2/baz", "golang-github-foo-bar-v2"},
+		{"github.com/foo/baz", ""},
+	} {
+		got, ok := sourceForImportPath(tt.importPath, golangBinaries)
+		if got != tt.want || ok != (tt.want != "") {
+			t.Errorf("sourceForImportPath(%q) = %q, %v, want %q", tt.importPath, got, ok, tt.want)
+		}
+	}
+}
+
+func TestClonePackagingRepository(t *testing.T) {
+	tempdir := t.TempDir()
+
+	// The upstream repository, with a release tag.
+	upstream := filepath.Join(tempdir, "upstream")
+	gitCmdOrFatal(t, tempdir, "init", "--initial-branch=main", upstream)
+	gitCmdOrFatal(t, upstream, "config", "user.email", "unittest@example.com")
+	gitCmdOrFatal(t, upstream, "config", "user.name", "Unit Test")
+	if err := os.WriteFile(filepath.Join(upstream, "foo.go"), []byte("package foo\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	gitCmdOrFatal(t, upstream, "add", "foo.go")
+	gitCmdOrFatal(t, upstream, "commit", "-m", "initial commit")
+	gitCmdOrFatal(t, upstream, "tag", "v1.0.0")
+
+	// The packaging repository, with DEP-14 branches and a topic branch.
+	packaging := filepath.Join(tempdir, "packaging")
+	gitCmdOrFatal(t, tempdir, "init", "--initial-branch=debian/sid", packaging)
+	gitCmdOrFatal(t, packaging, "config", "user.email", "unittest@example.com")
+	gitCmdOrFatal(t, packaging, "config", "user.name", "Unit Test")
+	if err := os.MkdirAll(filepath.Join(packaging, "debian"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	const control = "Source: golang-example-foo\nXS-Go-Import-Path: example.org/foo,\n example.org/foo/v1\n\nPackage: golang-example-foo-dev\nArchitecture: all\n"
+	if err := os.WriteFile(filepath.Join(packaging, "debian", "control"), []byte(control), 0644); err != nil {
+		t.Fatal(err)
+	}
+	gitCmdOrFatal(t, packaging, "add", "debian")
+	gitCmdOrFatal(t, packaging, "commit", "-m", "initial packaging")
+	for _, branch := range []string{"debian/bookworm-backports", "upstream", "pristine-tar", "wip"} {
+		gitCmdOrFatal(t, packaging, "branch", branch)
+	}
+
+	dir := filepath.Join(tempdir, "golang-example-foo")
+	if err := clonePackagingRepository(t.Context(), packaging, "debian/sid", dir); err != nil {
+		t.Fatal(err)
+	}
+	out, err := exec.Command("git", "-C", dir, "for-each-ref", "--format=%(refname:short) %(upstream:short)", "refs/heads/").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"debian/bookworm-backports origin/debian/bookworm-backports",
+		"debian/sid origin/debian/sid",
+		"pristine-tar origin/pristine-tar",
+		"upstream origin/upstream",
+	}
+	if diff := cmp.Diff(want, strings.Split(strings.TrimSpace(string(out)), "\n")); diff != "" {
+		t.Errorf("local branches: diff (-want +got):\n%s", diff)
+	}
+
+	gopkg, err := goImportPath(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "example.org/foo"; gopkg != want {
+		t.Errorf("goImportPath = %q, want %q", gopkg, want)
+	}
+
+	if err := golangdeb.AddUpstreamRemote(t.Context(), dir, "example", upstream); err != nil {
+		t.Fatal(err)
+	}
+	if err := exec.Command("git", "-C", dir, "rev-parse", "--verify", "refs/tags/v1.0.0").Run(); err != nil {
+		t.Errorf("upstream tag v1.0.0 was not fetched: %v", err)
+	}
+}
+
+func TestReadPackageList(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "list.txt")
+	if err := os.WriteFile(path, []byte("# dependencies of foo\ngolang-github-foo-bar\n\n  github.com/foo/baz  \n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := readPackageList(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"golang-github-foo-bar", "github.com/foo/baz"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("readPackageList: diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestCloneAll(t *testing.T) {
+	tempdir := t.TempDir()
+	sources := make(map[string]sourceEntry)
+	for _, src := range []string{"golang-foo", "golang-bar"} {
+		repo := filepath.Join(tempdir, "remote", src)
+		gitCmdOrFatal(t, tempdir, "init", "--initial-branch=debian/sid", repo)
+		gitCmdOrFatal(t, repo, "config", "user.email", "unittest@example.com")
+		gitCmdOrFatal(t, repo, "config", "user.name", "Unit Test")
+		gitCmdOrFatal(t, repo, "commit", "--allow-empty", "-m", "initial commit")
+		gitCmdOrFatal(t, repo, "branch", "upstream")
+		sources[src] = sourceEntry{name: src, vcsGit: repo + " -b debian/sid"}
+	}
+	sources["golang-gone"] = sourceEntry{name: "golang-gone", vcsGit: filepath.Join(tempdir, "remote", "golang-gone")}
+
+	t.Chdir(tempdir)
+	if err := os.Mkdir("golang-bar", 0755); err != nil {
+		t.Fatal(err)
+	}
+	c := &cloner{d: &golangdeb.DebianDistro, sources: sources}
+	results := c.cloneAll(t.Context(), []string{"golang-foo", "golang-bar", "golang-gone"}, 2)
+	if len(results) != 3 {
+		t.Fatalf("cloneAll returned %d results, want 3", len(results))
+	}
+	if r := results[0]; r.src != "golang-foo" || r.skipped || r.err != nil {
+		t.Errorf("golang-foo: got %+v, want it cloned", r)
+	}
+	if r := results[1]; r.src != "golang-bar" || !r.skipped || r.err != nil {
+		t.Errorf("golang-bar: got %+v, want it skipped", r)
+	}
+	if r := results[2]; r.src != "golang-gone" || r.err == nil {
+		t.Errorf("golang-gone: got %+v, want an error", r)
+	}
+
+	if _, err := os.Stat("golang-gone"); !os.IsNotExist(err) {
+		t.Errorf("golang-gone: got %v, want the failed clone removed", err)
+	}
+
+	out, err := exec.Command("git", "-C", "golang-foo", "config", "--get-all", "remote.origin.push").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(out), "+refs/heads/*:refs/heads/*\n+refs/tags/*:refs/tags/*\n"; got != want {
+		t.Errorf("remote.origin.push = %q, want %q", got, want)
+	}
+	if err := exec.Command("git", "-C", "golang-foo", "rev-parse", "--verify", "refs/heads/upstream").Run(); err != nil {
+		t.Errorf("upstream branch is not tracked: %v", err)
+	}
+}
+
+func TestCloneFailureCleanup(t *testing.T) {
+	tempdir := t.TempDir()
+	repo := filepath.Join(tempdir, "remote", "golang-foo")
+	gitCmdOrFatal(t, tempdir, "init", "--initial-branch=debian/sid", repo)
+	gitCmdOrFatal(t, repo, "config", "user.email", "unittest@example.com")
+	gitCmdOrFatal(t, repo, "config", "user.name", "Unit Test")
+	gitCmdOrFatal(t, repo, "commit", "--allow-empty", "-m", "initial commit")
+
+	// An origtargz which fails, e.g. because the upstream tarball is gone.
+	bin := filepath.Join(tempdir, "bin")
+	if err := os.Mkdir(bin, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(bin, "origtargz"), []byte("#!/bin/sh\nexit 1\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	t.Chdir(tempdir)
+	c := &cloner{
+		d:         &golangdeb.DebianDistro,
+		sources:   map[string]sourceEntry{"golang-foo": {name: "golang-foo", vcsGit: repo + " -b debian/sid"}},
+		origtargz: true,
+	}
+	if _, err := c.clone(t.Context(), "golang-foo"); err == nil {
+		t.Fatal("clone with failing origtargz: got no error")
+	}
+	if _, err := os.Stat("golang-foo"); !os.IsNotExist(err) {
+		t.Errorf("got %v, want the partial clone removed", err)
+	}
+
+	// A rerun retries it instead of skipping it.
+	if skipped, err := c.clone(t.Context(), "golang-foo"); skipped || err == nil {
+		t.Errorf("rerun: got skipped %v, error %v, want another failure", skipped, err)
+	}
+}
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"io"
@@ -8,27 +10,195 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/Debian/dh-make-golang/pkg/golangdeb"
 )
 
-func execCreateSalsaProject(args []string) {
-	fs := flag.NewFlagSet("create-salsa-project", flag.ExitOnError)
+const (
+	salsaURL = "https://salsa.debian.org"
 
-	fs.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: %s create-salsa-project <project-name>\n", os.Args[0])
-		fmt.Fprintf(os.Stderr, "Example: %s create-salsa-project golang-github-mattn-go-sqlite3\n", os.Args[0])
+	// tagpendingWebhookURL tags the bugs closed in debian/changelog as
+	// pending when pushed.
+	tagpendingWebhookURL = "https://webhook.salsa.debian.org/tagpending/"
+
+	// kgbWebhookURL announces pushes on IRC.
+	kgbWebhookURL = "http://kgb.debian.net:9418/webhook/"
+)
+
+// gitlabClient is a minimal client for the GitLab REST API (v4), which is
+// what salsa.debian.org runs.
+type gitlabClient struct {
+	baseURL string // e.g. https://salsa.debian.org
+	token   string // personal access token with api scope
+}
+
+// gitlabProject is the subset of a GitLab project we use.
+type gitlabProject struct {
+	ID                int    `json:"id"`
+	PathWithNamespace string `json:"path_with_namespace"`
+	SSHURLToRepo      string `json:"ssh_url_to_repo"`
+	WebURL            string `json:"web_url"`
+}
+
+// do calls the given API endpoint (relative to /api/v4/) with params as
+// form values and decodes the JSON response into v, unless v is nil.
+func (c *gitlabClient) do(ctx context.Context, method, endpoint string, params url.Values, v any) error {
+	u := strings.TrimSuffix(c.baseURL, "/") + "/api/v4/" + endpoint
+	var body io.Reader
+	if method == http.MethodGet {
+		if len(params) > 0 {
+			u += "?" + params.Encode()
+		}
+	} else {
+		body = strings.NewReader(params.Encode())
 	}
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	req.Header.Set("PRIVATE-TOKEN", c.token)
+	resp, err := golangdeb.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s %s: %w", method, endpoint, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		b, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("%s %s: unexpected HTTP status code %d (response: %s)", method, endpoint, resp.StatusCode, string(b))
+	}
+	if v == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("%s %s: decode: %w", method, endpoint, err)
+	}
+	return nil
+}
 
-	if err := fs.Parse(args); err != nil {
-		log.Fatalf("parse: %s", err)
+// namespaceID returns the ID of the group or user namespace with the given
+// full path, e.g. “go-team/packages”.
+func (c *gitlabClient) namespaceID(ctx context.Context, path string) (int, error) {
+	var ns struct {
+		ID int `json:"id"`
+	}
+	if err := c.do(ctx, http.MethodGet, "namespaces/"+url.PathEscape(path), nil, &ns); err != nil {
+		return 0, err
 	}
+	return ns.ID, nil
+}
 
-	if fs.NArg() != 1 {
-		fs.Usage()
-		os.Exit(1)
+// createProject creates a public project in the given namespace.
+func (c *gitlabClient) createProject(ctx context.Context, namespaceID int, name, defaultBranch, ciConfigPath string) (*gitlabProject, error) {
+	var p gitlabProject
+	err := c.do(ctx, http.MethodPost, "projects", url.Values{
+		"name":           {name},
+		"path":           {name},
+		"namespace_id":   {strconv.Itoa(namespaceID)},
+		"visibility":     {"public"},
+		"default_branch": {defaultBranch},
+		"ci_config_path": {ciConfigPath},
+	}, &p)
+	if err != nil {
+		return nil, err
 	}
+	return &p, nil
+}
 
-	projectName := fs.Arg(0)
+// setDefaultBranch sets the default branch of the project, which must exist.
+func (c *gitlabClient) setDefaultBranch(ctx context.Context, projectID int, branch string) error {
+	return c.do(ctx, http.MethodPut, "projects/"+strconv.Itoa(projectID), url.Values{
+		"default_branch": {branch},
+	}, nil)
+}
 
+// addWebhook adds a webhook triggered by pushes of branches and tags.
+func (c *gitlabClient) addWebhook(ctx context.Context, projectID int, hookURL string) error {
+	return c.do(ctx, http.MethodPost, "projects/"+strconv.Itoa(projectID)+"/hooks", url.Values{
+		"url":                     {hookURL},
+		"push_events":             {"true"},
+		"tag_push_events":         {"true"},
+		"enable_ssl_verification": {"true"},
+	}, nil)
+}
+
+// salsaWebhooks returns the URLs of the webhooks packaging repositories
+// on salsa should have: tagpending, and KGB unless kgbChannel is empty.
+func salsaWebhooks(project, kgbChannel string) []string {
+	hooks := []string{tagpendingWebhookURL + project}
+	if kgbChannel != "" {
+		hooks = append(hooks, kgbWebhookURL+"?"+url.Values{
+			"channel": {kgbChannel},
+			"network": {"oftc"},
+		}.Encode())
+	}
+	return hooks
+}
+
+// createGitLabProject creates the project, configures it and, if dir is
+// not empty, pushes the git repository in dir to it.
+func createGitLabProject(ctx context.Context, c *gitlabClient, namespace, name, branch, ciConfigPath, kgbChannel, dir string) (*gitlabProject, error) {
+	nsID, err := c.namespaceID(ctx, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("look up namespace %q: %w", namespace, err)
+	}
+	log.Printf("Creating project %s/%s\n", namespace, name)
+	p, err := c.createProject(ctx, nsID, name, branch, ciConfigPath)
+	if err != nil {
+		return nil, fmt.Errorf("create project: %w", err)
+	}
+	for _, hook := range salsaWebhooks(name, kgbChannel) {
+		log.Printf("Adding webhook %s\n", hook)
+		if err := c.addWebhook(ctx, p.ID, hook); err != nil {
+			return p, fmt.Errorf("add webhook: %w", err)
+		}
+	}
+
+	if dir == "" {
+		return p, nil
+	}
+	if err := pushToProject(ctx, dir, p.SSHURLToRepo); err != nil {
+		return p, err
+	}
+	// The default branch can only be set once it exists.
+	log.Printf("Setting default branch to %s\n", branch)
+	if err := c.setDefaultBranch(ctx, p.ID, branch); err != nil {
+		return p, fmt.Errorf("set default branch: %w", err)
+	}
+	return p, nil
+}
+
+// pushToProject points the origin remote of the repository in dir, as set
+// up by golangdeb.CreateGitRepository, to pushURL and pushes all branches and tags.
+func pushToProject(ctx context.Context, dir, pushURL string) error {
+	cmd := exec.CommandContext(ctx, "git", "remote", "get-url", "origin")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("git remote get-url origin: %w", err)
+	}
+	if origin := strings.TrimSpace(string(out)); origin != pushURL {
+		log.Printf("Changing URL of remote \"origin\" from %q to %q\n", origin, pushURL)
+		if err := golangdeb.RunGitCommandIn(ctx, dir, "remote", "set-url", "origin", pushURL); err != nil {
+			return fmt.Errorf("git remote set-url origin: %w", err)
+		}
+	}
+	// golangdeb.CreateGitRepository configures origin to push all branches and tags.
+	log.Printf("Pushing %s to %s\n", dir, pushURL)
+	if err := golangdeb.RunGitCommandIn(ctx, dir, "push", "origin"); err != nil {
+		return fmt.Errorf("git push origin: %w", err)
+	}
+	return nil
+}
+
+// createPgtProject creates a project in the go-team/packages namespace
+// through pgt-api-server, which does not require a token.
+func createPgtProject(ctx context.Context, projectName string) error {
 	// The source code of the corresponding server can be found at:
 	// https://salsa.debian.org/go-team/infra/pkg-go-tools/-/tree/master/cmd/pgt-api-server
 	u, _ := url.Parse("https://pgt-api-server.debian.net/v1/createrepo")
@@ -36,12 +206,113 @@ func execCreateSalsaProject(args []string) {
 	q.Set("repo", projectName)
 	u.RawQuery = q.Encode()
 
-	resp, err := http.Post(u.String(), "", nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := golangdeb.HTTPClient.Do(req)
 	if err != nil {
-		log.Fatalf("http post: %s", err)
+		return fmt.Errorf("http post: %w", err)
 	}
+	defer resp.Body.Close()
 	if got, want := resp.StatusCode, http.StatusOK; got != want {
 		b, _ := io.ReadAll(resp.Body)
-		log.Fatalf("unexpected HTTP status code: got %d, want %d (response: %s)", got, want, string(b))
+		return fmt.Errorf("unexpected HTTP status code: got %d, want %d (response: %s)", got, want, string(b))
+	}
+	return nil
+}
+
+func execCreateSalsaProject(ctx context.Context, args []string) {
+	fs := flag.NewFlagSet("create-salsa-project", flag.ExitOnError)
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s create-salsa-project [flags] <project-name>\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Example: %s create-salsa-project golang-github-mattn-go-sqlite3\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Example: %s create-salsa-project -backend=gitlab -namespace=jdoe -push=golang-foo golang-foo\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "\n")
+		fmt.Fprintf(os.Stderr, "Flags:\n")
+		fs.PrintDefaults()
+	}
+
+	var backend string
+	fs.StringVar(&backend,
+		"backend",
+		"pgt-api-server",
+		"How to create the project:\n"+
+			` * "pgt-api-server": in go-team/packages, through the Go team's API server`+"\n"+
+			` * "gitlab": through the GitLab API, authenticating with the token in`+"\n"+
+			"   the SALSA_TOKEN environment variable (api scope). Supports other\n"+
+			"   namespaces, and configures the project (see below).")
+
+	var gitlabURL string
+	fs.StringVar(&gitlabURL,
+		"gitlab-url",
+		salsaURL,
+		"URL of the GitLab instance (-backend=gitlab).")
+
+	var namespace string
+	fs.StringVar(&namespace,
+		"namespace",
+		"go-team/packages",
+		"Group or user to create the project in (-backend=gitlab).")
+
+	var branch string
+	fs.StringVar(&branch,
+		"branch",
+		golangdeb.DebianDistro.BranchPrefix+golangdeb.DebianDistro.DevelSuite,
+		"DEP-14 branch to make the default branch (-backend=gitlab).")
+
+	var ciConfigPath string
+	fs.StringVar(&ciConfigPath,
+		"ci-config-path",
+		"debian/salsa-ci.yml",
+		"CI configuration file of the project (-backend=gitlab).")
+
+	var kgbChannel string
+	fs.StringVar(&kgbChannel,
+		"kgb-channel",
+		"debian-golang",
+		"IRC channel the KGB webhook announces pushes in, empty to not add\n"+
+			"it (-backend=gitlab). The tagpending webhook is always added.")
+
+	var pushDir string
+	fs.StringVar(&pushDir,
+		"push",
+		"",
+		"Push the git repository in this directory (e.g. as created by\n"+
+			"\"make\") to the new project (-backend=gitlab).")
+
+	if err := fs.Parse(args); err != nil {
+		log.Fatalf("parse: %s", err)
+	}
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	projectName := fs.Arg(0)
+
+	switch backend {
+	case "pgt-api-server":
+		if err := createPgtProject(ctx, projectName); err != nil {
+			log.Fatal(err)
+		}
+	case "gitlab":
+		token := os.Getenv("SALSA_TOKEN")
+		if token == "" {
+			log.Fatalf("-backend=gitlab requires a personal access token in SALSA_TOKEN")
+		}
+		c := &gitlabClient{baseURL: gitlabURL, token: token}
+		p, err := createGitLabProject(ctx, c, namespace, projectName, branch, ciConfigPath, kgbChannel, pushDir)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("Project created at %s\n", p.WebURL)
+		if pushDir == "" {
+			fmt.Printf("Push the packaging to %s, then set the default branch to %s\n", p.SSHURLToRepo, branch)
+		}
+	default:
+		log.Fatalf("unknown backend %q, want \"pgt-api-server\" or \"gitlab\"", backend)
 	}
 }
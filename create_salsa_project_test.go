@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCreateGitLabProject(t *testing.T) {
+	remote := filepath.Join(t.TempDir(), "golang-foo.git")
+	gitCmdOrFatal(t, filepath.Dir(remote), "init", "--bare", remote)
+
+	dir := t.TempDir()
+	gitCmdOrFatal(t, dir, "init", "--initial-branch=debian/sid")
+	gitCmdOrFatal(t, dir, "config", "user.email", "unittest@example.com")
+	gitCmdOrFatal(t, dir, "config", "user.name", "Unit Test")
+	gitCmdOrFatal(t, dir, "remote", "add", "origin", "git@salsa.debian.org:go-team/packages/golang-foo.git")
+	gitCmdOrFatal(t, dir, "config", "--add", "remote.origin.push", "+refs/heads/*:refs/heads/*")
+	gitCmdOrFatal(t, dir, "config", "--add", "remote.origin.push", "+refs/tags/*:refs/tags/*")
+	if err := os.WriteFile(filepath.Join(dir, "README"), []byte("test"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	gitCmdOrFatal(t, dir, "add", "README")
+	gitCmdOrFatal(t, dir, "commit", "-m", "initial commit")
+	gitCmdOrFatal(t, dir, "branch", "upstream")
+	gitCmdOrFatal(t, dir, "tag", "upstream/1.0")
+
+	var requests []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got, want := r.Header.Get("PRIVATE-TOKEN"), "secret"; got != want {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			t.Error(err)
+		}
+		requests = append(requests, r.Method+" "+r.URL.EscapedPath()+" "+r.PostForm.Encode())
+		switch r.Method + " " + r.URL.EscapedPath() {
+		case "GET /api/v4/namespaces/jdoe%2Fgo":
+			json.NewEncoder(w).Encode(map[string]any{"id": 42})
+		case "POST /api/v4/projects":
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(gitlabProject{
+				ID:                7,
+				PathWithNamespace: "jdoe/go/golang-foo",
+				SSHURLToRepo:      remote,
+				WebURL:            "https://salsa.example/jdoe/go/golang-foo",
+			})
+		case "POST /api/v4/projects/7/hooks":
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte("{}"))
+		case "PUT /api/v4/projects/7":
+			w.Write([]byte("{}"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	c := &gitlabClient{baseURL: ts.URL, token: "secret"}
+	p, err := createGitLabProject(t.Context(), c, "jdoe/go", "golang-foo", "debian/sid", "debian/salsa-ci.yml", "debian-golang", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := p.WebURL, "https://salsa.example/jdoe/go/golang-foo"; got != want {
+		t.Errorf("WebURL = %q, want %q", got, want)
+	}
+
+	want := []string{
+		"GET /api/v4/namespaces/jdoe%2Fgo ",
+		"POST /api/v4/projects ci_config_path=debian%2Fsalsa-ci.yml&default_branch=debian%2Fsid&name=golang-foo&namespace_id=42&path=golang-foo&visibility=public",
+		"POST /api/v4/projects/7/hooks enable_ssl_verification=true&push_events=true&tag_push_events=true&url=https%3A%2F%2Fwebhook.salsa.debian.org%2Ftagpending%2Fgolang-foo",
+		"POST /api/v4/projects/7/hooks enable_ssl_verification=true&push_events=true&tag_push_events=true&url=http%3A%2F%2Fkgb.debian.net%3A9418%2Fwebhook%2F%3Fchannel%3Ddebian-golang%26network%3Doftc",
+		"PUT /api/v4/projects/7 default_branch=debian%2Fsid",
+	}
+	if diff := cmp.Diff(want, requests); diff != "" {
+		t.Errorf("requests: diff (-want +got):\n%s", diff)
+	}
+
+	cmd := exec.Command("git", "for-each-ref", "--format=%(refname)")
+	cmd.Dir = remote
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantRefs := "refs/heads/debian/sid\nrefs/heads/upstream\nrefs/tags/upstream/1.0\n"
+	if got := string(out); got != wantRefs {
+		t.Errorf("pushed refs: got %q, want %q", got, wantRefs)
+	}
+
+	if _, err := createGitLabProject(t.Context(), &gitlabClient{baseURL: ts.URL, token: "wrong"}, "jdoe/go", "golang-foo", "debian/sid", "", "", ""); err == nil ||
+		!strings.Contains(err.Error(), "401") {
+		t.Errorf("createGitLabProject with a wrong token: got %v, want a 401 error", err)
+	}
+}
@@ -2,10 +2,13 @@ package main
 
 import (
 	"bytes"
+	"cmp"
+	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
+	"maps"
 	"net/http"
 	"os"
 	"os/exec"
@@ -15,12 +18,10 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/Debian/dh-make-golang/pkg/golangdeb"
 	"github.com/mattn/go-isatty"
-	"golang.org/x/tools/go/vcs"
-)
-
-const (
-	sourcesInNewURL = "https://api.ftp-master.debian.org/sources_in_suite/new"
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
 )
 
 // majorVersionRegexp checks if an import path contains a major version suffix.
@@ -39,12 +40,33 @@ var (
 	hyperlink = func(url, txt string) string { return txt }
 )
 
-func getSourcesInNew() (map[string]string, error) {
+// trackerLink generates an OSC 8 hyperlink to the tracker for the given
+// source package name.
+func trackerLink(d *golangdeb.DistroProfile, pkg string) string {
+	return hyperlink(d.TrackerURL+pkg, pkg)
+}
+
+// newPackageLine generates a line for packages in NEW, including an OSC 8
+// hyperlink to the NEW queue for the given source package.
+func newPackageLine(d *golangdeb.DistroProfile, mod, debpkg, version string) string {
+	txt := fmt.Sprintf("in NEW as %v %v", debpkg, version)
+	if d.NewQueueURL != "" {
+		txt = hyperlink(d.NewQueueURL+debpkg, txt)
+	}
+	return cyanf("%v (%v)", mod, txt)
+}
+
+// getSourcesInNew returns the versions of the source packages in the NEW
+// queue of d, if it has one.
+func getSourcesInNew(ctx context.Context, d *golangdeb.DistroProfile) (map[string]string, error) {
 	sourcesInNew := make(map[string]string)
+	if d.SourcesInNewURL == "" {
+		return sourcesInNew, nil
+	}
 
-	resp, err := http.Get(sourcesInNewURL)
+	resp, err := golangdeb.HTTPGet(ctx, d.SourcesInNewURL)
 	if err != nil {
-		return nil, fmt.Errorf("getting %q: %w", sourcesInNewURL, err)
+		return nil, fmt.Errorf("getting %q: %w", d.SourcesInNewURL, err)
 	}
 	defer resp.Body.Close()
 	if got, want := resp.StatusCode, http.StatusOK; got != want {
@@ -63,10 +85,10 @@ func getSourcesInNew() (map[string]string, error) {
 	return sourcesInNew, nil
 }
 
-func get(gopath, repodir, repo, rev string) error {
+func get(ctx context.Context, gopath, repodir, repo, rev string) error {
 	done := make(chan struct{})
 	defer close(done)
-	go progressSize("go get", gopath, done)
+	go golangdeb.ProgressSize("go get", gopath, done)
 
 	// As per https://groups.google.com/forum/#!topic/golang-nuts/N5apfenE4m4,
 	// the arguments to “go get” are packages, not repositories. Hence, we
@@ -79,14 +101,14 @@ func get(gopath, repodir, repo, rev string) error {
 	if rev != "" {
 		packages += "@" + rev
 	}
-	cmd := exec.Command("go", "get", "-t", packages)
+	cmd := exec.CommandContext(ctx, "go", "get", "-t", packages)
 
 	out := bytes.Buffer{}
 	cmd.Dir = repodir
 	cmd.Stderr = &out
 	cmd.Env = append([]string{
 		"GOPATH=" + gopath,
-	}, passthroughEnv()...)
+	}, golangdeb.PassthroughEnv()...)
 	err := cmd.Run()
 	if err != nil {
 		fmt.Fprint(os.Stderr, "\n", out.String())
@@ -96,13 +118,13 @@ func get(gopath, repodir, repo, rev string) error {
 
 // getModuleDir returns the path of the directory containing a module for the
 // given GOPATH and repository dir values.
-func getModuleDir(gopath, repodir, module string) (string, error) {
-	cmd := exec.Command("go", "list", "-m", "-f", "{{.Dir}}", module)
+func getModuleDir(ctx context.Context, gopath, repodir, module string) (string, error) {
+	cmd := exec.CommandContext(ctx, "go", "list", "-m", "-f", "{{.Dir}}", module)
 	cmd.Dir = repodir
 	cmd.Stderr = os.Stderr
 	cmd.Env = append([]string{
 		"GOPATH=" + gopath,
-	}, passthroughEnv()...)
+	}, golangdeb.PassthroughEnv()...)
 	out, err := cmd.Output()
 	if err != nil {
 		return "", fmt.Errorf("go list: args: %v; error: %w", cmd.Args, err)
@@ -114,8 +136,8 @@ func getModuleDir(gopath, repodir, module string) (string, error) {
 // module for the given GOPATH and repository dir values. It first finds the
 // directory that contains this module, then uses go list in this directory
 // to get its direct dependencies.
-func getDirectDependencies(gopath, repodir, module string) (map[string]bool, error) {
-	dir, err := getModuleDir(gopath, repodir, module)
+func getDirectDependencies(ctx context.Context, gopath, repodir, module string) (map[string]bool, error) {
+	dir, err := getModuleDir(ctx, gopath, repodir, module)
 	if err != nil {
 		return nil, fmt.Errorf("get module dir: %w", err)
 	}
@@ -125,12 +147,12 @@ func getDirectDependencies(gopath, repodir, module string) (map[string]bool, err
 	if _, err := os.Stat(filepath.Join(dir, "go.mod")); err != nil {
 		return nil, nil
 	}
-	cmd := exec.Command("go", "list", "-m", "-f", "{{if not .Indirect}}{{.Path}}{{end}}", "all")
+	cmd := exec.CommandContext(ctx, "go", "list", "-m", "-f", "{{if not .Indirect}}{{.Path}}{{end}}", "all")
 	cmd.Dir = dir
 	cmd.Stderr = os.Stderr
 	cmd.Env = append([]string{
 		"GOPATH=" + gopath,
-	}, passthroughEnv()...)
+	}, golangdeb.PassthroughEnv()...)
 	out, err := cmd.Output()
 	if err != nil {
 		return nil, fmt.Errorf("go list: args: %v; error: %w", cmd.Args, err)
@@ -184,97 +206,155 @@ func otherVersions(mod string) (mods []string) {
 // findOtherVersion search in m for potential other versions of the given
 // module and returns the number of the major version found, 0 if not,
 // along with the corresponding package name.
-func findOtherVersion(m map[string]debianPackage, mod string) (int, debianPackage) {
+func findOtherVersion(m map[string]golangdeb.DebianPackage, mod string) (int, golangdeb.DebianPackage) {
 	versions := otherVersions(mod)
 	for i, version := range versions {
 		if pkg, ok := m[version]; ok {
 			return len(versions) - i, pkg
 		}
 	}
-	return 0, debianPackage{}
+	return 0, golangdeb.DebianPackage{}
 }
 
-// trackerLink generates an OSC 8 hyperlink to the tracker for the given Debian
-// package name.
-func trackerLink(pkg string) string {
-	return hyperlink("https://tracker.debian.org/pkg/"+pkg, pkg)
-}
-
-// newPackageLine generates a line for packages in NEW, including an OSC 8
-// hyperlink to the FTP masters website for the given Debian package.
-func newPackageLine(mod, debpkg, version string) string {
-	url := "https://dfsg-new-queue.debian.org/reviews/" + debpkg
-	return cyanf("%v (%v)", mod, hyperlink(url, fmt.Sprintf("in NEW as %v %v", debpkg, version)))
-}
-
-func estimate(importpath, revision string) error {
+// getInDummyModule fetches importpath at revision (and its dependencies,
+// including vendored ones) into a dummy module in a temporary directory,
+// using a separate temporary GOPATH. cleanup removes both. If dir is not
+// empty, the module is taken from the local directory dir instead (see
+// dummyModFile), and revision is ignored.
+func getInDummyModule(ctx context.Context, importpath, revision, dir string) (gopath, repodir string, cleanup func(), err error) {
 	removeTemp := func(path string) {
-		if err := forceRemoveAll(path); err != nil {
+		if err := golangdeb.ForceRemoveAll(path); err != nil {
 			log.Printf("could not remove all %s: %v", path, err)
 		}
 	}
 
 	// construct a separate GOPATH in a temporary directory
-	gopath, err := os.MkdirTemp("", "dh-make-golang")
+	gopath, err = os.MkdirTemp("", "dh-make-golang")
 	if err != nil {
-		return fmt.Errorf("create temp dir: %w", err)
+		return "", "", nil, fmt.Errorf("create temp dir: %w", err)
 	}
-	defer removeTemp(gopath)
 	// second temporary directosy for the repo sources
-	repodir, err := os.MkdirTemp("", "dh-make-golang")
+	repodir, err = os.MkdirTemp("", "dh-make-golang")
 	if err != nil {
-		return fmt.Errorf("create temp dir: %w", err)
+		removeTemp(gopath)
+		return "", "", nil, fmt.Errorf("create temp dir: %w", err)
 	}
-	defer removeTemp(repodir)
+	cleanup = func() {
+		removeTemp(gopath)
+		removeTemp(repodir)
+	}
+	defer func() {
+		if err != nil {
+			cleanup()
+		}
+	}()
 
 	// Create a dummy go module in repodir to be able to use go get.
-	err = os.WriteFile(filepath.Join(repodir, "go.mod"), []byte("module dummymod\n"), 0644)
+	gomod, err := dummyModFile(dir)
+	if err != nil {
+		return "", "", nil, fmt.Errorf("create dummymod: %w", err)
+	}
+	err = os.WriteFile(filepath.Join(repodir, "go.mod"), gomod, 0644)
 	if err != nil {
-		return fmt.Errorf("create dummymod: %w", err)
+		return "", "", nil, fmt.Errorf("create dummymod: %w", err)
+	}
+	if dir != "" {
+		revision = ""
 	}
 
-	if err := get(gopath, repodir, importpath, revision); err != nil {
-		return fmt.Errorf("go get: %w", err)
+	if err := get(ctx, gopath, repodir, importpath, revision); err != nil {
+		return "", "", nil, fmt.Errorf("go get: %w", err)
 	}
 
 	found, err := removeVendor(repodir)
 	if err != nil {
-		return fmt.Errorf("remove vendor: %w", err)
+		return "", "", nil, fmt.Errorf("remove vendor: %w", err)
 	}
 
 	if found {
 		// Fetch un-vendored dependencies
-		if err := get(gopath, repodir, importpath, revision); err != nil {
-			return fmt.Errorf("fetch un-vendored: go get: %w", err)
+		if err := get(ctx, gopath, repodir, importpath, revision); err != nil {
+			return "", "", nil, fmt.Errorf("fetch un-vendored: go get: %w", err)
 		}
 	}
 
+	return gopath, repodir, cleanup, nil
+}
+
+// dummyModFile returns the go.mod of the dummy module of getInDummyModule.
+// If dir is not empty, it requires the module in dir and replaces it with
+// dir, so that go get uses the local sources of the module, but still
+// downloads its dependencies.
+func dummyModFile(dir string) ([]byte, error) {
+	if dir == "" {
+		return []byte("module dummymod\n"), nil
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	mod, err := golangdeb.ReadModulePath(abs)
+	if err != nil {
+		return nil, err
+	}
+	// The version is irrelevant with the replace directive, but needs the
+	// major version of the module path.
+	_, pathMajor, _ := module.SplitPathVersion(mod)
+	version := cmp.Or(module.PathMajorPrefix(pathMajor), "v0") + ".0.0"
+	return fmt.Appendf(nil, "module dummymod\n\nrequire %s %s\n\nreplace %[1]s => %[3]s\n",
+		mod, version, modfile.AutoQuote(abs)), nil
+}
+
+// estimateDependencies walks the dependency graph of importpath and returns
+// the lines printed by the estimate command, as well as the repository roots
+// of the dependencies which are not packaged yet. If dir is not empty, the
+// local sources of importpath in dir are used, see getInDummyModule.
+func estimateDependencies(ctx context.Context, importpath, revision, dir string, arch *archiveFlags) (lines, missing []string, _ error) {
+	d, err := arch.distro()
+	if err != nil {
+		return nil, nil, fmt.Errorf("-distro: %w", err)
+	}
+	suite, err := d.ParseSuite(arch.suite)
+	if err != nil {
+		return nil, nil, fmt.Errorf("-suite: %w", err)
+	}
+
+	gopath, repodir, cleanup, err := getInDummyModule(ctx, importpath, revision, dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer cleanup()
+
 	// Get dependency graph from go mod graph
-	cmd := exec.Command("go", "mod", "graph")
+	cmd := exec.CommandContext(ctx, "go", "mod", "graph")
 	cmd.Dir = repodir
 	cmd.Stderr = os.Stderr
 	cmd.Env = append([]string{
 		"GOPATH=" + gopath,
-	}, passthroughEnv()...)
+	}, golangdeb.PassthroughEnv()...)
 	out, err := cmd.Output()
 	if err != nil {
-		return fmt.Errorf("go mod graph: args: %v; error: %w", cmd.Args, err)
+		return nil, nil, fmt.Errorf("go mod graph: args: %v; error: %w", cmd.Args, err)
 	}
 
 	// Get direct dependencies, to filter out indirect ones from go mod graph output
-	directDeps, err := getDirectDependencies(gopath, repodir, importpath)
+	directDeps, err := getDirectDependencies(ctx, gopath, repodir, importpath)
 	if err != nil {
-		return fmt.Errorf("get direct dependencies: %w", err)
+		return nil, nil, fmt.Errorf("get direct dependencies: %w", err)
 	}
 
 	// Retrieve already-packaged ones
-	golangBinaries, err := getGolangBinaries()
+	golangBinaries, err := arch.golangBinaries(ctx)
 	if err != nil {
-		return fmt.Errorf("get golang debian packages: %w", err)
+		return nil, nil, fmt.Errorf("get golang debian packages: %w", err)
 	}
-	sourcesInNew, err := getSourcesInNew()
+	unstableBinaries, err := arch.unstableBinaries(ctx)
 	if err != nil {
-		return fmt.Errorf("get packages in new: %w", err)
+		return nil, nil, fmt.Errorf("get golang debian packages in unstable: %w", err)
+	}
+	sourcesInNew, err := getSourcesInNew(ctx, d)
+	if err != nil {
+		return nil, nil, fmt.Errorf("get packages in new: %w", err)
 	}
 
 	// Build a graph in memory from the output of go mod graph
@@ -325,13 +405,39 @@ func estimate(importpath, revision string) error {
 		srcNode.children = append(srcNode.children, depNode)
 	}
 
+	// Resolve the repository roots of all modules not packaged in Debian
+	// upfront, as resolving them one by one while walking the graph is
+	// slow for vanity import paths.
+	unpackaged := make(map[string]bool)
+	for name := range nodes {
+		mod, _, _ := strings.Cut(name, "@")
+		if _, ok := golangBinaries[mod]; ok {
+			continue
+		}
+		if _, ok := unstableBinaries[mod]; ok {
+			continue
+		}
+		if v, _ := findOtherVersion(golangBinaries, mod); v != 0 {
+			continue
+		}
+		if mod != "go" && mod != "toolchain" {
+			unpackaged[mod] = true
+		}
+	}
+	repoRoots, err := golangdeb.DefaultRepoRootResolver.RepoRoots(ctx, slices.Collect(maps.Keys(unpackaged)))
+	if err != nil {
+		return nil, nil, err
+	}
+
 	// Analyse the dependency graph
-	var lines []string
 	seen := make(map[string]bool)
 	rrseen := make(map[string]bool)
 	needed := make(map[string]int)
 	var visit func(n *Node, indent int)
 	visit = func(n *Node, indent int) {
+		if ctx.Err() != nil {
+			return
+		}
 		output := func(line string) {
 			lines = append(lines, strings.Repeat("  ", indent)+line)
 		}
@@ -352,18 +458,20 @@ func estimate(importpath, revision string) error {
 				return
 			}
 			if pkg, ok := golangBinaries[mod]; ok {
-				if version, ok := sourcesInNew[pkg.source]; ok {
-					output(newPackageLine(mod, pkg.source, version))
+				if version, ok := sourcesInNew[pkg.Source]; ok {
+					output(newPackageLine(d, mod, pkg.Source, version))
 				}
 				return // already packaged in Debian
 			}
-			var repoRoot string
-			rr, err := vcs.RepoRootForImportPath(mod, false)
-			if err != nil {
-				log.Printf("Could not determine repo path for import path %q: %v\n", mod, err)
-				repoRoot = mod
-			} else {
-				repoRoot = rr.Root
+			if pkg, ok := unstableBinaries[mod]; ok {
+				// Packaged, but its dependencies might need to be
+				// backported as well.
+				output(cyanf("%v (needs backport of %v to %v)", mod, trackerLink(d, pkg.Source), suite.Name))
+				needed[mod] = 1
+				for _, n := range n.children {
+					visit(n, indent+1)
+				}
+				return
 			}
 			// Check for potential other major versions already in Debian.
 			v, pkg := findOtherVersion(golangBinaries, mod)
@@ -371,24 +479,28 @@ func estimate(importpath, revision string) error {
 				// Log info to indicate that it is an approximate match
 				// but consider that it is packaged and skip the children.
 				if v == 1 {
-					log.Printf("%s has no version string in Debian (%s)", mod, trackerLink(pkg.source))
+					log.Printf("%s has no version string in Debian (%s)", mod, trackerLink(d, pkg.Source))
 				} else {
-					log.Printf("%s is v%d in Debian (%s)", mod, v, trackerLink(pkg.source))
+					log.Printf("%s is v%d in Debian (%s)", mod, v, trackerLink(d, pkg.Source))
 				}
-				if version, ok := sourcesInNew[pkg.source]; ok {
-					output(newPackageLine(mod, pkg.source, version))
+				if version, ok := sourcesInNew[pkg.Source]; ok {
+					output(newPackageLine(d, mod, pkg.Source, version))
 				}
 				return
 			}
+			repoRoot, ok := repoRoots[mod]
+			if !ok {
+				repoRoot = mod
+			}
 			// When multiple modules are developped in the same repo,
 			// the repo root is often used as the import path metadata
 			// in Debian, so we do a last try with that.
 			if pkg, ok := golangBinaries[repoRoot]; ok {
 				// Log info to indicate that it is an approximate match
 				// but consider that it is packaged and skip the children.
-				log.Printf("%s is packaged as %s in Debian (%s)", mod, repoRoot, trackerLink(pkg.source))
-				if version, ok := sourcesInNew[pkg.source]; ok {
-					output(newPackageLine(mod, pkg.source, version))
+				log.Printf("%s is packaged as %s in Debian (%s)", mod, repoRoot, trackerLink(d, pkg.Source))
+				if version, ok := sourcesInNew[pkg.Source]; ok {
+					output(newPackageLine(d, mod, pkg.Source, version))
 				}
 				return
 			}
@@ -405,6 +517,9 @@ func estimate(importpath, revision string) error {
 			} else {
 				output(mod)
 			}
+			if !rrseen[repoRoot] {
+				missing = append(missing, repoRoot)
+			}
 			rrseen[repoRoot] = true
 			needed[mod] = 1
 		}
@@ -414,7 +529,18 @@ func estimate(importpath, revision string) error {
 	}
 
 	visit(root, 0)
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
 
+	return lines, missing, nil
+}
+
+func estimate(ctx context.Context, importpath, revision, dir string, arch *archiveFlags) error {
+	lines, _, err := estimateDependencies(ctx, importpath, revision, dir, arch)
+	if err != nil {
+		return err
+	}
 	if len(lines) == 0 {
 		log.Printf("%s is already fully packaged in Debian", importpath)
 		return nil
@@ -427,11 +553,11 @@ func estimate(importpath, revision string) error {
 	return nil
 }
 
-func execEstimate(args []string) {
+func execEstimate(ctx context.Context, args []string) {
 	fs := flag.NewFlagSet("estimate", flag.ExitOnError)
 
 	fs.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: %s estimate <go-module-importpath>\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Usage: %s estimate <go-module-importpath>|-from-dir <dir>\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "Estimates the work necessary to bring <go-module-importpath> into Debian\n"+
 			"by printing all currently unpacked repositories.\n")
 		fmt.Fprintf(os.Stderr, "Example: %s estimate github.com/Debian/dh-make-golang\n", os.Args[0])
@@ -448,6 +574,16 @@ func execEstimate(args []string) {
 			"to estimate, defaulting to the default behavior of go get.\n"+
 			"Useful in case you do not want to estimate the latest version.")
 
+	var fromDir string
+	fs.StringVar(&fromDir,
+		"from-dir",
+		"",
+		"Estimate the Go module in this local directory (e.g. a checkout of\n"+
+			"upstream) instead of downloading it. The import path defaults to\n"+
+			"the module path in its go.mod.")
+
+	arch := addArchiveFlags(fs)
+
 	validColorModes := []string{"auto", "never", "always"}
 	autoColor := os.Getenv("NO_COLOR") == "" && os.Getenv("TERM") != "dumb" &&
 		isatty.IsTerminal(os.Stdout.Fd())
@@ -485,14 +621,28 @@ func execEstimate(args []string) {
 		}
 	}
 
-	if fs.NArg() != 1 {
+	if fs.NArg() > 1 || (fs.NArg() != 1 && fromDir == "") {
 		fs.Usage()
 		os.Exit(1)
 	}
 
 	gitRevision = strings.TrimSpace(gitRevision)
+	importpath := fs.Arg(0)
+	if fromDir != "" {
+		if gitRevision != "" {
+			log.Fatalf("-git_revision cannot be combined with -from-dir, check out the revision instead")
+		}
+		mod, err := golangdeb.ReadModulePath(fromDir)
+		if err != nil {
+			log.Fatalf("-from-dir: %v", err)
+		}
+		if importpath != "" && importpath != mod {
+			log.Printf("Continuing with module path %q of %s instead of specified import path %q", mod, fromDir, importpath)
+		}
+		importpath = mod
+	}
 
-	if err := estimate(fs.Arg(0), gitRevision); err != nil {
+	if err := estimate(ctx, importpath, gitRevision, fromDir, arch); err != nil {
 		log.Fatalf("estimate: %s", err)
 	}
 }
@@ -11,7 +11,6 @@ require (
 	golang.org/x/mod v0.19.0
 	golang.org/x/net v0.27.0
 	golang.org/x/sync v0.7.0
-	golang.org/x/tools/go/vcs v0.1.0-deprecated
 	pault.ag/go/debian v0.16.0
 )
 
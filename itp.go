@@ -0,0 +1,415 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Debian/dh-make-golang/pkg/golangdeb"
+	"pault.ag/go/debian/changelog"
+	"pault.ag/go/debian/control"
+)
+
+// itpReasoning explains in the ITP why a package is being packaged.
+type itpReasoning struct {
+	neededBy   string    // import path of the package which needs this one, see -for
+	missing    []string  // other dependencies of neededBy which are not packaged yet
+	lastCommit time.Time // date of the latest upstream commit, if known
+	latestTag  string    // latest upstream tag, if any
+	tagDate    time.Time // date of latestTag, if known
+}
+
+// write writes the reasoning paragraphs of the ITP for debsrc to w.
+func (r itpReasoning) write(w io.Writer, debsrc string, d *golangdeb.DistroProfile) {
+	const dateFormat = "2006-01-02"
+	if r.neededBy != "" {
+		fmt.Fprintf(w, "%s is needed by %s, which is being packaged.\n", debsrc, r.neededBy)
+		if len(r.missing) > 0 {
+			fmt.Fprintf(w, "The following dependencies of %s are not packaged yet either:\n", r.neededBy)
+			for _, mod := range r.missing {
+				fmt.Fprintf(w, "  %s\n", mod)
+			}
+		}
+	} else {
+		fmt.Fprintf(w, "TODO: perhaps reasoning\n")
+	}
+	fmt.Fprintf(w, "\n")
+
+	if !r.lastCommit.IsZero() {
+		fmt.Fprintf(w, "The latest upstream commit is from %s", r.lastCommit.Format(dateFormat))
+		switch {
+		case r.latestTag == "":
+			fmt.Fprintf(w, ", upstream has not tagged any release yet.\n")
+		case r.tagDate.IsZero():
+			fmt.Fprintf(w, ", the latest release is %s.\n", r.latestTag)
+		default:
+			fmt.Fprintf(w, ", the latest release is %s (%s).\n", r.latestTag, r.tagDate.Format(dateFormat))
+		}
+		fmt.Fprintf(w, "\n")
+	}
+
+	if d.Maintainer == golangdeb.GoTeamMaintainer {
+		fmt.Fprintf(w, "The package will be maintained within the Debian Go Packaging Team.\n")
+	} else {
+		fmt.Fprintf(w, "The package will be maintained by %s.\n", d.Maintainer)
+	}
+	fmt.Fprintf(w, "The packaging repository will be hosted at %s%s.\n", d.VcsURL, debsrc)
+}
+
+// otherMissingDependencies returns the repository roots in missing except
+// for those of the given import paths, i.e. the package needing the others
+// and the one being packaged.
+func otherMissingDependencies(missing []string, importPaths ...string) []string {
+	var others []string
+	for _, root := range missing {
+		if !slices.ContainsFunc(importPaths, func(importPath string) bool {
+			return importPath == root || strings.HasPrefix(importPath, root+"/")
+		}) {
+			others = append(others, root)
+		}
+	}
+	return others
+}
+
+// mailer sends email messages.
+type mailer interface {
+	send(ctx context.Context, msg []byte) error
+}
+
+// sendmailMailer pipes messages to a sendmail compatible command, which
+// takes the recipients from the message headers.
+type sendmailMailer struct {
+	command []string
+}
+
+func (m sendmailMailer) send(ctx context.Context, msg []byte) error {
+	if len(m.command) == 0 {
+		return fmt.Errorf("empty sendmail command")
+	}
+	cmd := exec.CommandContext(ctx, m.command[0], m.command[1:]...)
+	cmd.Stdin = bytes.NewReader(msg)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s: %w", strings.Join(m.command, " "), err)
+	}
+	return nil
+}
+
+// smtpMailer sends messages through an SMTP server, authenticating with the
+// SMTP_USERNAME and SMTP_PASSWORD environment variables if set.
+type smtpMailer struct {
+	addr string // host:port
+}
+
+func (m smtpMailer) send(ctx context.Context, msg []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	parsed, err := mail.ReadMessage(bytes.NewReader(msg))
+	if err != nil {
+		return fmt.Errorf("parse message: %w", err)
+	}
+	from, err := parsed.Header.AddressList("From")
+	if err != nil || len(from) == 0 {
+		return fmt.Errorf("parse From: %v", err)
+	}
+	to, err := parsed.Header.AddressList("To")
+	if err != nil {
+		return fmt.Errorf("parse To: %w", err)
+	}
+	recipients := make([]string, len(to))
+	for i, addr := range to {
+		recipients[i] = addr.Address
+	}
+
+	var auth smtp.Auth
+	if username := os.Getenv("SMTP_USERNAME"); username != "" {
+		host, _, _ := strings.Cut(m.addr, ":")
+		auth = smtp.PlainAuth("", username, os.Getenv("SMTP_PASSWORD"), host)
+	}
+	return smtp.SendMail(m.addr, auth, from[0].Address, recipients, msg)
+}
+
+// newMailer returns the mailer for the -send flag value.
+func newMailer(kind, sendmailCommand, smtpAddr string) (mailer, error) {
+	switch kind {
+	case "sendmail":
+		return sendmailMailer{command: strings.Fields(sendmailCommand)}, nil
+	case "smtp":
+		return smtpMailer{addr: smtpAddr}, nil
+	default:
+		return nil, fmt.Errorf("unknown mailer %q, want \"sendmail\" or \"smtp\"", kind)
+	}
+}
+
+// setChangelogCloses replaces the “Closes: TODO” written by “make” in the
+// given debian/changelog with a reference to bug.
+func setChangelogCloses(path string, bug int) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	const placeholder = "Closes: TODO"
+	if !bytes.Contains(b, []byte(placeholder)) {
+		return fmt.Errorf("%s does not contain %q", path, placeholder)
+	}
+	b = bytes.Replace(b, []byte(placeholder), []byte("Closes: #"+strconv.Itoa(bug)), 1)
+	return os.WriteFile(path, b, 0644)
+}
+
+// waitForITP polls src for the bug created by submitting the ITP for
+// debsrc, until timeout expires.
+func waitForITP(ctx context.Context, src wnppSource, submitter, debsrc string, timeout time.Duration) (int, error) {
+	const interval = 30 * time.Second
+	deadline := time.Now().Add(timeout)
+	for {
+		bugs, err := src.wnppBugs(ctx, submitter)
+		if err != nil {
+			return 0, err
+		}
+		for _, b := range bugs {
+			if kind, pkg := b.kind(); kind == "ITP" && pkg == debsrc {
+				return b.Number, nil
+			}
+		}
+		if time.Now().Add(interval).After(deadline) {
+			return 0, fmt.Errorf("no ITP bug for %s reported by %s after %v", debsrc, submitter, timeout)
+		}
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+// itpPath returns where the ITP email for the packaging in dir is kept: next
+// to dir, where make writes it.
+func itpPath(dir, debsrc string) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(abs), fmt.Sprintf("itp-%s.txt", debsrc)), nil
+}
+
+func execITP(ctx context.Context, args []string) {
+	fs := flag.NewFlagSet("itp", flag.ExitOnError)
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s itp [flags] [<package-dir>]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Writes the ITP (Intent To Package) email for an existing packaging\n"+
+			"(default: current directory), after checking WNPP for existing ITP or\n"+
+			"RFP bugs, and optionally sends it and records the bug number in the\n"+
+			"\"Closes: TODO\" of debian/changelog.\n")
+		fmt.Fprintf(os.Stderr, "Example: %s itp -send=sendmail golang-github-mmcdole-goxpp\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "\n")
+		fmt.Fprintf(os.Stderr, "Flags:\n")
+		fs.PrintDefaults()
+	}
+
+	arch := addArchiveFlags(fs)
+
+	var neededBy string
+	fs.StringVar(&neededBy,
+		"for",
+		"",
+		"Import path of the package which needs this one. The ITP then explains\n"+
+			"this and lists the other dependencies which are not packaged yet.")
+
+	var wnppLocation string
+	fs.StringVar(&wnppLocation,
+		"wnpp",
+		"",
+		"Where to look up WNPP bugs: the URL of a debbugs SOAP interface\n"+
+			"(default for Debian: "+debbugsSOAPURL+"),\n"+
+			"the URL or path of a JSON file (ending in .json) with a list of\n"+
+			"{\"bug\", \"subject\", \"submitter\", \"url\"} objects, or \"none\".")
+
+	var regenerate bool
+	fs.BoolVar(&regenerate,
+		"regenerate",
+		false,
+		"Overwrite an existing itp-<package>.txt, which is used as is otherwise.")
+
+	var sendWith string
+	fs.StringVar(&sendWith,
+		"send",
+		"",
+		"Send the ITP email using \"sendmail\" (see -sendmail) or \"smtp\"\n"+
+			"(see -smtp). Refused while the email contains TODOs.")
+
+	var sendmailCommand string
+	fs.StringVar(&sendmailCommand,
+		"sendmail",
+		"/usr/sbin/sendmail -t -oi",
+		"Command to pipe the email to with -send=sendmail.")
+
+	var smtpAddr string
+	fs.StringVar(&smtpAddr,
+		"smtp",
+		"localhost:25",
+		"SMTP server (host:port) to use with -send=smtp. The SMTP_USERNAME and\n"+
+			"SMTP_PASSWORD environment variables are used for authentication.")
+
+	var wait time.Duration
+	fs.DurationVar(&wait,
+		"wait",
+		10*time.Minute,
+		"How long to wait for the bug number after sending the ITP, 0 to not wait.")
+
+	var closes int
+	fs.IntVar(&closes,
+		"closes",
+		0,
+		"Bug number to record in debian/changelog, e.g. of an existing RFP\n"+
+			"retitled to ITP. Skips the WNPP check and sending the email.")
+
+	var force bool
+	fs.BoolVar(&force,
+		"force",
+		false,
+		"Continue even if WNPP already has an ITP or RFP bug for the package.")
+
+	if err := fs.Parse(args); err != nil {
+		log.Fatalf("parse args: %s", err)
+	}
+
+	dir := "."
+	switch fs.NArg() {
+	case 0:
+	case 1:
+		dir = fs.Arg(0)
+	default:
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	d, err := arch.distro()
+	if err != nil {
+		log.Fatalf("-distro: %s", err)
+	}
+
+	ctrl, err := control.ParseControlFile(filepath.Join(dir, "debian", "control"))
+	if err != nil {
+		log.Fatalf("parse debian/control: %s", err)
+	}
+	debsrc := ctrl.Source.Source
+	gopkg := strings.TrimSpace(ctrl.Source.Values["XS-Go-Import-Path"])
+	if gopkg == "" {
+		log.Fatalf("debian/control has no XS-Go-Import-Path field")
+	}
+	changelogPath := filepath.Join(dir, "debian", "changelog")
+	entry, err := changelog.ParseFileOne(changelogPath)
+	if err != nil {
+		log.Fatalf("parse debian/changelog: %s", err)
+	}
+
+	var wnpp wnppSource
+	switch {
+	case wnppLocation == "none":
+	case wnppLocation != "":
+		wnpp = newWNPPSource(wnppLocation)
+	case d.ITPTo == golangdeb.DebianDistro.ITPTo:
+		wnpp = newWNPPSource(debbugsSOAPURL)
+	}
+
+	if wnpp != nil && closes == 0 {
+		log.Printf("Checking WNPP for existing bugs about %s\n", debsrc)
+		bugs, err := wnpp.wnppBugs(ctx, "")
+		if err != nil {
+			log.Fatalf("Could not check WNPP: %v (use -wnpp=none to skip)", err)
+		}
+		homepage := strings.TrimSpace(ctrl.Source.Values["Homepage"])
+		if dups := findWNPPDuplicates(bugs, debsrc, gopkg, homepage); len(dups) > 0 {
+			for _, b := range dups {
+				fmt.Printf("    #%d: %s\n", b.Number, b.Subject)
+			}
+			if !force {
+				log.Fatalf("WNPP already has bugs about %s, see above. Retitle an RFP to ITP and use -closes, or use -force\n", debsrc)
+			}
+		}
+	}
+
+	itpname, err := itpPath(dir, debsrc)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if _, err := os.Stat(itpname); err == nil && !regenerate {
+		log.Printf("Using existing %s, use -regenerate to overwrite it\n", itpname)
+	} else {
+		reasoning := itpReasoning{neededBy: neededBy}
+		// make fetches the upstream history into the remote named like this
+		// (unless -upstream-git-history=false).
+		if remote, err := golangdeb.UpstreamRemoteName(gopkg, true); err == nil {
+			reasoning.lastCommit, reasoning.latestTag, reasoning.tagDate, err = golangdeb.RemoteActivity(ctx, dir, remote)
+			if err != nil {
+				log.Printf("Could not determine the latest upstream commit and release: %v\n", err)
+			}
+		}
+		if neededBy != "" {
+			_, missing, err := estimateDependencies(ctx, neededBy, "", "", arch)
+			if err != nil {
+				log.Printf("Could not determine the missing dependencies of %s: %v\n", neededBy, err)
+			}
+			reasoning.missing = otherMissingDependencies(missing, neededBy, gopkg)
+		}
+		m, err := golangdeb.FetchMetadata(ctx, newGitHubClient(), gopkg)
+		if err != nil {
+			log.Fatalf("Could not fetch metadata: %v\n", err)
+		}
+		if _, err := writeITP(m, filepath.Dir(itpname), gopkg, debsrc, entry.Version.String(), d, reasoning); err != nil {
+			log.Fatalf("Could not write ITP email: %v\n", err)
+		}
+		log.Printf("Wrote %s\n", itpname)
+	}
+
+	if sendWith != "" && closes == 0 {
+		msg, err := os.ReadFile(itpname)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if bytes.Contains(msg, []byte("TODO")) {
+			log.Fatalf("Resolve all TODOs in %s before sending it\n", itpname)
+		}
+		m, err := newMailer(sendWith, sendmailCommand, smtpAddr)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := m.send(ctx, msg); err != nil {
+			log.Fatalf("Could not send %s: %v\n", itpname, err)
+		}
+		log.Printf("Sent %s to %s\n", itpname, d.ITPTo)
+
+		if wnpp != nil && wait > 0 {
+			log.Printf("Waiting for the ITP bug to be created (up to %v)\n", wait)
+			closes, err = waitForITP(ctx, wnpp, golangdeb.DebianEmail(), debsrc, wait)
+			if err != nil {
+				log.Printf("Could not determine the ITP bug number: %v\n", err)
+			}
+		}
+	}
+
+	if closes == 0 {
+		fmt.Printf("Once the ITP bug has been created, record its number using:\n")
+		fmt.Printf("    %s itp -closes <bug> %s\n", os.Args[0], dir)
+		return
+	}
+	if err := setChangelogCloses(changelogPath, closes); err != nil {
+		log.Fatalf("Could not update debian/changelog: %v\n", err)
+	}
+	log.Printf("Recorded bug #%d in %s\n", closes, changelogPath)
+}
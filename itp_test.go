@@ -0,0 +1,257 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Debian/dh-make-golang/pkg/golangdeb"
+	"github.com/google/go-cmp/cmp"
+)
+
+var testWNPPBugs = []wnppBug{
+	{Number: 1001, Subject: "ITP: golang-github-foo-bar -- Go library for bars"},
+	{Number: 1002, Subject: "RFP: bartool -- command line tool for bars", URL: "https://github.com/foo/bartool/"},
+	{Number: 1003, Subject: "RFP: golang-github-foo-baz -- github.com/foo/baz library"},
+	{Number: 1004, Subject: "O: golang-github-foo-bar -- Go library for bars"},
+	{Number: 1005, Subject: "ITP: golang-github-other-bar -- unrelated"},
+}
+
+func TestFindWNPPDuplicates(t *testing.T) {
+	for _, tt := range []struct {
+		debsrc   string
+		gopkg    string
+		homepage string
+		want     []int
+	}{
+		{"golang-github-foo-bar", "github.com/foo/bar", "https://github.com/foo/bar", []int{1001}},
+		{"golang-github-foo-bartool", "github.com/foo/bartool", "https://github.com/foo/bartool", []int{1002}},
+		{"golang-github-foo-baz", "example.org/baz", "https://github.com/foo/baz", []int{1003}},
+		{"golang-github-foo-qux", "github.com/foo/qux", "https://github.com/foo/qux", nil},
+	} {
+		var got []int
+		for _, b := range findWNPPDuplicates(testWNPPBugs, tt.debsrc, tt.gopkg, tt.homepage) {
+			got = append(got, b.Number)
+		}
+		if diff := cmp.Diff(tt.want, got); diff != "" {
+			t.Errorf("findWNPPDuplicates(%q, %q, %q): diff (-want +got):\n%s", tt.debsrc, tt.gopkg, tt.homepage, diff)
+		}
+	}
+}
+
+const testSOAPGetBugsResponse = `<?xml version="1.0" encoding="UTF-8"?>
+<soap:Envelope soap:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/" xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/" xmlns:soapenc="http://schemas.xmlsoap.org/soap/encoding/" xmlns:xsd="http://www.w3.org/2001/XMLSchema" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">
+<soap:Body><get_bugsResponse xmlns="Debbugs/SOAP"><Array soapenc:arrayType="xsd:int[2]" xsi:type="soapenc:Array"><item xsi:type="xsd:int">1001</item><item xsi:type="xsd:int">1002</item></Array></get_bugsResponse></soap:Body>
+</soap:Envelope>`
+
+const testSOAPGetStatusResponse = `<?xml version="1.0" encoding="UTF-8"?>
+<soap:Envelope soap:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/" xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/" xmlns:soapenc="http://schemas.xmlsoap.org/soap/encoding/" xmlns:xsd="http://www.w3.org/2001/XMLSchema" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">
+<soap:Body><get_statusResponse xmlns="Debbugs/SOAP"><s-gensym3 xsi:type="apachens:Map">
+<item><key xsi:type="xsd:int">1001</key><value><subject xsi:type="xsd:string">ITP: golang-github-foo-bar -- Go library for bars</subject><originator xsi:type="xsd:string">Jane Doe &lt;jane@example.org&gt;</originator><done xsi:type="xsd:string"></done></value></item>
+<item><key xsi:type="xsd:int">1002</key><value><subject xsi:type="xsd:string">RFP: bartool -- command line tool for bars</subject><originator xsi:type="xsd:string">john@example.org</originator><done xsi:type="xsd:string">john@example.org</done></value></item>
+</s-gensym3></get_statusResponse></soap:Body>
+</soap:Envelope>`
+
+func TestDebbugsSOAP(t *testing.T) {
+	var requests []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		requests = append(requests, string(b))
+		switch {
+		case strings.Contains(string(b), "get_bugs"):
+			io.WriteString(w, testSOAPGetBugsResponse)
+		case strings.Contains(string(b), "get_status"):
+			io.WriteString(w, testSOAPGetStatusResponse)
+		default:
+			http.Error(w, "unknown method", http.StatusBadRequest)
+		}
+	}))
+	defer ts.Close()
+
+	got, err := newWNPPSource(ts.URL).wnppBugs(t.Context(), "jane@example.org")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []wnppBug{{
+		Number:    1001,
+		Subject:   "ITP: golang-github-foo-bar -- Go library for bars",
+		Submitter: "Jane Doe <jane@example.org>",
+	}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("wnppBugs: diff (-want +got):\n%s", diff)
+	}
+	if len(requests) != 2 {
+		t.Fatalf("got %d requests, want 2", len(requests))
+	}
+	for _, param := range []string{">wnpp<", ">submitter<", ">jane@example.org<"} {
+		if !strings.Contains(requests[0], param) {
+			t.Errorf("get_bugs request does not contain %q:\n%s", param, requests[0])
+		}
+	}
+	if !strings.Contains(requests[1], `<item xsi:type="xsd:int">1002</item>`) {
+		t.Errorf("get_status request does not contain bug 1002:\n%s", requests[1])
+	}
+}
+
+func TestWNPPJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wnpp.json")
+	if err := os.WriteFile(path, []byte(`[
+  {"bug": 1001, "subject": "ITP: golang-github-foo-bar -- Go library for bars", "submitter": "jane@example.org"},
+  {"bug": 1002, "subject": "RFP: bartool -- command line tool for bars", "url": "https://github.com/foo/bartool"}
+]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	src := newWNPPSource(path)
+	all, err := src.wnppBugs(t.Context(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 2 {
+		t.Errorf("wnppBugs(%q) returned %d bugs, want 2", "", len(all))
+	}
+	number, err := waitForITP(t.Context(), src, "jane@example.org", "golang-github-foo-bar", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if number != 1001 {
+		t.Errorf("waitForITP = %d, want 1001", number)
+	}
+	if _, err := waitForITP(t.Context(), src, "jane@example.org", "bartool", 0); err == nil {
+		t.Errorf("waitForITP(%q) unexpectedly succeeded", "bartool")
+	}
+}
+
+func TestSendmailMailer(t *testing.T) {
+	m, err := newMailer("sendmail", "/usr/sbin/sendmail -t -oi", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(sendmailMailer{command: []string{"/usr/sbin/sendmail", "-t", "-oi"}}, m, cmp.AllowUnexported(sendmailMailer{})); diff != "" {
+		t.Errorf("newMailer: diff (-want +got):\n%s", diff)
+	}
+
+	out := filepath.Join(t.TempDir(), "mail")
+	m = sendmailMailer{command: []string{"sh", "-c", "cat > " + out}}
+	const msg = "To: submit@bugs.debian.org\nSubject: ITP: foo\n\nbody\n"
+	if err := m.send(t.Context(), []byte(msg)); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != msg {
+		t.Errorf("sendmail got %q, want %q", got, msg)
+	}
+
+	if _, err := newMailer("pigeon", "", ""); err == nil {
+		t.Errorf("newMailer(%q) unexpectedly succeeded", "pigeon")
+	}
+}
+
+func TestSetChangelogCloses(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "changelog")
+	const changelog = `golang-github-foo-bar (1.0-1) UNRELEASED; urgency=medium
+
+  * Initial release (Closes: TODO)
+
+ -- Jane Doe <jane@example.org>  Sun, 18 Oct 2026 12:00:00 +0000
+`
+	if err := os.WriteFile(path, []byte(changelog), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := setChangelogCloses(path, 1001); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := strings.Replace(changelog, "Closes: TODO", "Closes: #1001", 1); string(got) != want {
+		t.Errorf("setChangelogCloses: got\n%s\nwant\n%s", got, want)
+	}
+	if err := setChangelogCloses(path, 1002); err == nil {
+		t.Errorf("setChangelogCloses on a changelog without TODO unexpectedly succeeded")
+	}
+}
+
+func TestOtherMissingDependencies(t *testing.T) {
+	missing := []string{"github.com/foo/app", "github.com/foo/bar", "github.com/foo/baz", "golang.org/x/exp"}
+	got := otherMissingDependencies(missing, "github.com/foo/app/cmd/app", "github.com/foo/bar")
+	want := []string{"github.com/foo/baz", "golang.org/x/exp"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("otherMissingDependencies: diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestITPReasoning(t *testing.T) {
+	r := itpReasoning{
+		neededBy:   "github.com/foo/app",
+		missing:    []string{"github.com/foo/baz"},
+		lastCommit: time.Date(2026, 9, 1, 12, 0, 0, 0, time.UTC),
+		latestTag:  "v1.2.3",
+		tagDate:    time.Date(2026, 8, 1, 12, 0, 0, 0, time.UTC),
+	}
+	var buf strings.Builder
+	r.write(&buf, "golang-github-foo-bar", &golangdeb.DebianDistro)
+	want := `golang-github-foo-bar is needed by github.com/foo/app, which is being packaged.
+The following dependencies of github.com/foo/app are not packaged yet either:
+  github.com/foo/baz
+
+The latest upstream commit is from 2026-09-01, the latest release is v1.2.3 (2026-08-01).
+
+The package will be maintained within the Debian Go Packaging Team.
+The packaging repository will be hosted at https://salsa.debian.org/go-team/packages/golang-github-foo-bar.
+`
+	if diff := cmp.Diff(want, buf.String()); diff != "" {
+		t.Errorf("itpReasoning.write: diff (-want +got):\n%s", diff)
+	}
+
+	buf.Reset()
+	itpReasoning{}.write(&buf, "golang-github-foo-bar", &golangdeb.UbuntuDistro)
+	want = `TODO: perhaps reasoning
+
+The package will be maintained by Ubuntu Developers <ubuntu-devel-discuss@lists.ubuntu.com>.
+The packaging repository will be hosted at https://git.launchpad.net/ubuntu/+source/golang-github-foo-bar.
+`
+	if diff := cmp.Diff(want, buf.String()); diff != "" {
+		t.Errorf("itpReasoning.write (ubuntu): diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestITPPathMakeLayout(t *testing.T) {
+	// make writes the packaging into <cwd>/<source> and the ITP email next
+	// to it.
+	tmp := t.TempDir()
+	t.Chdir(tmp)
+	const debsrc = "golang-github-foo-bar"
+	if err := os.MkdirAll(filepath.Join(debsrc, "debian"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	written, err := writeITP(&golangdeb.Metadata{}, ".", "github.com/foo/bar", debsrc, "1.0.0-1", &golangdeb.DebianDistro, itpReasoning{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := filepath.Abs(written)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, dir := range []string{debsrc, filepath.Join(tmp, debsrc)} {
+		if got, err := itpPath(dir, debsrc); err != nil || got != want {
+			t.Errorf("itpPath(%q) = %q, %v, want %q", dir, got, err, want)
+		}
+	}
+	t.Chdir(debsrc)
+	if got, err := itpPath(".", debsrc); err != nil || got != want {
+		t.Errorf("itpPath(\".\") in the packaging = %q, %v, want %q", got, err, want)
+	}
+}
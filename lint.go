@@ -0,0 +1,449 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Debian/dh-make-golang/pkg/golangdeb"
+	"pault.ag/go/debian/control"
+	"pault.ag/go/debian/version"
+)
+
+const (
+	severityError   = "error"
+	severityWarning = "warning"
+	severityInfo    = "info"
+)
+
+// lintFinding is a single issue found by the lint command.
+type lintFinding struct {
+	File     string `json:"file"`
+	Line     int    `json:"line,omitempty"`
+	Severity string `json:"severity"`
+	Tag      string `json:"tag"`
+	Message  string `json:"message"`
+	Fix      string `json:"fix,omitempty"`
+}
+
+func (f lintFinding) String() string {
+	loc := f.File
+	if f.Line > 0 {
+		loc += ":" + strconv.Itoa(f.Line)
+	}
+	s := fmt.Sprintf("%s: %s: %s: %s", strings.ToUpper(f.Severity[:1]), loc, f.Tag, f.Message)
+	if f.Fix != "" {
+		s += " (fix: " + f.Fix + ")"
+	}
+	return s
+}
+
+// linter collects the findings for the packaging in dir.
+type linter struct {
+	dir      string
+	d        *golangdeb.DistroProfile
+	suite    golangdeb.TargetSuite // for the expected packaging branch
+	findings []lintFinding
+}
+
+func (l *linter) add(file string, line int, severity, tag, message, fix string) {
+	l.findings = append(l.findings, lintFinding{
+		File:     file,
+		Line:     line,
+		Severity: severity,
+		Tag:      tag,
+		Message:  message,
+		Fix:      fix,
+	})
+}
+
+// readLines returns the lines of the given file within the packaging
+// directory, or nil if the file does not exist.
+func (l *linter) readLines(file string) ([]string, error) {
+	b, err := os.ReadFile(filepath.Join(l.dir, file))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return strings.Split(string(b), "\n"), nil
+}
+
+// fieldLine returns the 1-based line number of the first occurrence of the
+// given field at or after line start, or 0 if it cannot be found.
+func fieldLine(lines []string, start int, field string) int {
+	prefix := strings.ToLower(field) + ":"
+	for i := max(start-1, 0); i < len(lines); i++ {
+		if strings.HasPrefix(strings.ToLower(lines[i]), prefix) {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// buildDepends returns the Build-Depends of the source paragraph, indexed
+// by package name.
+func buildDepends(src *control.SourceParagraph) map[string]string {
+	deps := make(map[string]string)
+	for _, p := range src.BuildDepends.GetAllPossibilities() {
+		var rel string
+		if p.Version != nil {
+			rel = p.Version.Number
+		}
+		deps[strings.TrimSpace(p.Name)] = rel
+	}
+	return deps
+}
+
+func (l *linter) lintControl() error {
+	const file = "debian/control"
+	lines, err := l.readLines(file)
+	if err != nil {
+		return err
+	}
+	if lines == nil {
+		l.add(file, 0, severityError, "missing-debian-control", "debian/control does not exist", "")
+		return nil
+	}
+	ctrl, err := control.ParseControlFile(filepath.Join(l.dir, file))
+	if err != nil {
+		l.add(file, 0, severityError, "unparsable-debian-control", err.Error(), "")
+		return nil
+	}
+	src := &ctrl.Source
+	line := func(field string) int { return fieldLine(lines, 1, field) }
+
+	switch sv := strings.TrimSpace(src.Values["Standards-Version"]); {
+	case sv == "":
+		l.add(file, 0, severityError, "missing-standards-version",
+			"no Standards-Version field in the source paragraph",
+			"add \"Standards-Version: "+golangdeb.StandardsVersion+"\"")
+	case sv != golangdeb.StandardsVersion:
+		have, err := version.Parse(sv)
+		want, _ := version.Parse(golangdeb.StandardsVersion)
+		if err != nil || version.Compare(have, want) < 0 {
+			l.add(file, line("Standards-Version"), severityWarning, "out-of-date-standards-version",
+				fmt.Sprintf("Standards-Version is %s, current is %s", sv, golangdeb.StandardsVersion),
+				"review the Debian Policy upgrading checklist and set \"Standards-Version: "+golangdeb.StandardsVersion+"\"")
+		}
+	}
+
+	deps := buildDepends(src)
+	wantCompat := fmt.Sprintf("debhelper-compat (= %d)", golangdeb.DebhelperCompatLevel)
+	if compat, ok := deps["debhelper-compat"]; ok {
+		if level, err := strconv.Atoi(compat); err != nil || level < golangdeb.DebhelperCompatLevel {
+			l.add(file, line("Build-Depends"), severityWarning, "out-of-date-debhelper-compat",
+				fmt.Sprintf("debhelper-compat level is %q, current is %d", compat, golangdeb.DebhelperCompatLevel),
+				"build-depend on \""+wantCompat+"\" after reviewing debhelper(7) upgrade notes")
+		}
+	} else if _, ok := deps["debhelper"]; ok {
+		l.add(file, line("Build-Depends"), severityWarning, "uses-debhelper-without-compat",
+			"build-depends on debhelper instead of debhelper-compat",
+			"replace debhelper with \""+wantCompat+"\" and remove debian/compat")
+	} else {
+		l.add(file, line("Build-Depends"), severityError, "missing-debhelper-compat",
+			"no debhelper-compat build dependency", "build-depend on \""+wantCompat+"\"")
+	}
+
+	if _, ok := deps["dh-sequence-golang"]; !ok {
+		fix := "build-depend on dh-sequence-golang"
+		if _, ok := deps["dh-golang"]; ok {
+			fix = "replace dh-golang with dh-sequence-golang and drop \"--with=golang\" from debian/rules"
+		}
+		l.add(file, line("Build-Depends"), severityWarning, "missing-dh-sequence-golang",
+			"no dh-sequence-golang build dependency", fix)
+	}
+
+	if src.Values["XS-Go-Import-Path"] == "" {
+		l.add(file, 0, severityError, "missing-xs-go-import-path",
+			"no XS-Go-Import-Path field in the source paragraph",
+			"add \"XS-Go-Import-Path: <import path>\", which dh-golang and the archive tooling rely on")
+	}
+
+	wantBrowser := l.d.VcsURL + src.Source
+	wantGit := l.d.VcsURL + src.Source + ".git"
+	if got := strings.TrimSpace(src.Values["Vcs-Browser"]); got != wantBrowser {
+		l.add(file, line("Vcs-Browser"), severityWarning, "vcs-browser-mismatch",
+			fmt.Sprintf("Vcs-Browser is %q, want %q", got, wantBrowser),
+			"set \"Vcs-Browser: "+wantBrowser+"\"")
+	}
+	if got := strings.TrimSpace(src.Values["Vcs-Git"]); got != wantGit {
+		l.add(file, line("Vcs-Git"), severityWarning, "vcs-git-mismatch",
+			fmt.Sprintf("Vcs-Git is %q, want %q", got, wantGit),
+			"set \"Vcs-Git: "+wantGit+"\"")
+	}
+
+	for _, bin := range ctrl.Binaries {
+		start := slices.IndexFunc(lines, func(line string) bool {
+			name, ok := strings.CutPrefix(line, "Package:")
+			return ok && strings.TrimSpace(name) == bin.Package
+		}) + 1
+		binLine := func(field string) int { return fieldLine(lines, start, field) }
+		arch := strings.TrimSpace(bin.Values["Architecture"])
+		switch {
+		case arch == "all" && strings.HasSuffix(bin.Package, "-dev"):
+			if ma := strings.TrimSpace(bin.Values["Multi-Arch"]); ma != "foreign" {
+				l.add(file, start, severityWarning, "library-not-multi-arch-foreign",
+					fmt.Sprintf("%s: Multi-Arch is %q, want \"foreign\"", bin.Package, ma),
+					"set \"Multi-Arch: foreign\" in the "+bin.Package+" paragraph")
+			}
+		case arch != "all":
+			sbu := bin.Values["Static-Built-Using"]
+			if strings.Contains(sbu, "${misc:Static-Built-Using}") {
+				break
+			}
+			if strings.Contains(bin.Values["Built-Using"], "${misc:Built-Using}") {
+				l.add(file, binLine("Built-Using"), severityWarning, "uses-built-using-instead-of-static-built-using",
+					bin.Package+": uses ${misc:Built-Using}, which dh-golang no longer sets",
+					"replace the Built-Using field with \"Static-Built-Using: ${misc:Static-Built-Using}\"")
+			} else {
+				l.add(file, start, severityWarning, "missing-static-built-using",
+					bin.Package+": no Static-Built-Using field",
+					"add \"Static-Built-Using: ${misc:Static-Built-Using}\" to the "+bin.Package+" paragraph")
+			}
+		}
+	}
+	return nil
+}
+
+func (l *linter) lintWatch() error {
+	const file = "debian/watch"
+	lines, err := l.readLines(file)
+	if err != nil {
+		return err
+	}
+	if lines == nil {
+		l.add(file, 0, severityWarning, "missing-debian-watch", "debian/watch does not exist",
+			"add a debian/watch file tracking upstream tags, see uscan(1)")
+		return nil
+	}
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		after, ok := strings.CutPrefix(line, "version=")
+		if !ok {
+			break
+		}
+		if v, err := strconv.Atoi(strings.TrimSpace(after)); err != nil || v < 4 {
+			l.add(file, i+1, severityWarning, "out-of-date-watch-version",
+				fmt.Sprintf("debian/watch uses %q", line), "switch to \"version=4\"")
+		}
+		return nil
+	}
+	l.add(file, 0, severityError, "missing-watch-version", "debian/watch does not start with a version line",
+		"start debian/watch with \"version=4\"")
+	return nil
+}
+
+// parseGbpConf parses the INI-style gbp.conf(5) file at path into sections
+// of key-value pairs. Keys outside any section are stored in "DEFAULT".
+func parseGbpConf(path string) (map[string]map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	conf := make(map[string]map[string]string)
+	section := "DEFAULT"
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		if conf[section] == nil {
+			conf[section] = make(map[string]string)
+		}
+		conf[section][strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return conf, scanner.Err()
+}
+
+func (l *linter) lintGbpConf() error {
+	const file = "debian/gbp.conf"
+	lines, err := l.readLines(file)
+	if err != nil {
+		return err
+	}
+	if lines == nil {
+		l.add(file, 0, severityWarning, "missing-gbp-conf", "debian/gbp.conf does not exist",
+			"add \"[DEFAULT]\" with \"debian-branch = "+l.suite.Branch+"\" and \"dist = DEP14\"")
+		return nil
+	}
+	conf, err := parseGbpConf(filepath.Join(l.dir, file))
+	if err != nil {
+		return err
+	}
+	def := conf["DEFAULT"]
+	if branch := def["debian-branch"]; !strings.HasPrefix(branch, l.d.BranchPrefix) {
+		l.add(file, fieldLine(lines, 1, "debian-branch"), severityWarning, "non-dep14-debian-branch",
+			fmt.Sprintf("debian-branch is %q, which does not follow DEP-14", branch),
+			"rename the branch and set \"debian-branch = "+l.suite.Branch+"\"")
+	}
+	if dist := def["dist"]; dist != "DEP14" {
+		l.add(file, fieldLine(lines, 1, "dist"), severityWarning, "non-dep14-dist",
+			fmt.Sprintf("dist is %q, want \"DEP14\"", dist), "set \"dist = DEP14\"")
+	}
+	if pt := strings.ToLower(def["pristine-tar"]); pt == "true" || pt == "yes" || pt == "1" || pt == "on" {
+		l.add(file, fieldLine(lines, 1, "pristine-tar"), severityInfo, "uses-pristine-tar",
+			"pristine-tar is enabled, which the Go team no longer recommends",
+			"see https://go-team.pages.debian.net/workflow-changes.html")
+	}
+	return nil
+}
+
+func (l *linter) lintSalsaCI() error {
+	const file = "debian/salsa-ci.yml"
+	lines, err := l.readLines(file)
+	if err != nil {
+		return err
+	}
+	if lines == nil {
+		if _, err := os.Stat(filepath.Join(l.dir, "debian", "gitlab-ci.yml")); err == nil {
+			l.add("debian/gitlab-ci.yml", 0, severityWarning, "legacy-gitlab-ci-yml",
+				"CI configuration uses the old file name debian/gitlab-ci.yml",
+				"rename it to debian/salsa-ci.yml and update the CI config path on salsa")
+			return nil
+		}
+		l.add(file, 0, severityWarning, "missing-salsa-ci", "debian/salsa-ci.yml does not exist",
+			"add a debian/salsa-ci.yml including the salsa-ci-team pipeline")
+		return nil
+	}
+	if !strings.Contains(strings.Join(lines, "\n"), "salsa-ci-team/pipeline") {
+		l.add(file, 0, severityWarning, "salsa-ci-without-pipeline",
+			"debian/salsa-ci.yml does not include the salsa-ci-team pipeline",
+			"include https://salsa.debian.org/salsa-ci-team/pipeline/raw/master/recipes/debian.yml")
+	}
+	return nil
+}
+
+func (l *linter) lintTODOs() error {
+	todos, err := findTODOs(l.dir)
+	if err != nil {
+		return err
+	}
+	for _, todo := range todos {
+		l.add(todo.file, todo.line, severityWarning, "todo-marker", todo.text,
+			"resolve the TODO before uploading")
+	}
+	return nil
+}
+
+// lint checks the Go packaging in dir against the conventions that
+// dh-make-golang make uses for new packages of the distribution d in the
+// given suite.
+func lint(dir string, d *golangdeb.DistroProfile, suite golangdeb.TargetSuite) ([]lintFinding, error) {
+	l := &linter{dir: dir, d: d, suite: suite}
+	for _, check := range []struct {
+		name string
+		fn   func() error
+	}{
+		{"debian/control", l.lintControl},
+		{"debian/watch", l.lintWatch},
+		{"debian/gbp.conf", l.lintGbpConf},
+		{"debian/salsa-ci.yml", l.lintSalsaCI},
+		{"TODO markers", l.lintTODOs},
+	} {
+		if err := check.fn(); err != nil {
+			return nil, fmt.Errorf("%s: %w", check.name, err)
+		}
+	}
+	sort.SliceStable(l.findings, func(i, j int) bool {
+		if l.findings[i].File != l.findings[j].File {
+			return l.findings[i].File < l.findings[j].File
+		}
+		return l.findings[i].Line < l.findings[j].Line
+	})
+	return l.findings, nil
+}
+
+func execLint(ctx context.Context, args []string) {
+	fs := flag.NewFlagSet("lint", flag.ExitOnError)
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s lint [flags] [<package-dir>]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Checks an existing Go packaging (default: current directory)\n"+
+			"against the conventions used by \"%s make\" for new packages.\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Example: %s lint golang-github-mmcdole-goxpp\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "\n")
+		fmt.Fprintf(os.Stderr, "Flags:\n")
+		fs.PrintDefaults()
+	}
+
+	var jsonOutput bool
+	fs.BoolVar(&jsonOutput,
+		"json",
+		false,
+		"Print the findings as a JSON array instead of one finding per line.")
+
+	arch := addArchiveFlags(fs)
+
+	if err := fs.Parse(args); err != nil {
+		log.Fatalf("parse args: %s", err)
+	}
+
+	dir := "."
+	switch fs.NArg() {
+	case 0:
+	case 1:
+		dir = fs.Arg(0)
+	default:
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	d, err := arch.distro()
+	if err != nil {
+		log.Fatalf("-distro: %s", err)
+	}
+	suite, err := d.ParseSuite(arch.suite)
+	if err != nil {
+		log.Fatalf("-suite: %s", err)
+	}
+
+	findings, err := lint(dir, d, suite)
+	if err != nil {
+		log.Fatalf("lint: %s", err)
+	}
+
+	if jsonOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if findings == nil {
+			findings = []lintFinding{}
+		}
+		if err := enc.Encode(findings); err != nil {
+			log.Fatalf("encode: %s", err)
+		}
+	} else {
+		for _, f := range findings {
+			fmt.Println(f)
+		}
+	}
+
+	for _, f := range findings {
+		if f.Severity == severityError {
+			os.Exit(1)
+		}
+	}
+}
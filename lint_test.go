@@ -0,0 +1,182 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/Debian/dh-make-golang/pkg/golangdeb"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func lintTags(findings []lintFinding) []string {
+	var tags []string
+	for _, f := range findings {
+		tags = append(tags, f.Tag)
+	}
+	return tags
+}
+
+// lintSid lints dir like "dh-make-golang lint" without flags.
+func lintSid(t *testing.T, dir string) []lintFinding {
+	t.Helper()
+	suite, err := golangdeb.DebianDistro.ParseSuite("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	findings, err := lint(dir, &golangdeb.DebianDistro, suite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return findings
+}
+
+func TestLintClean(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"debian/control": `Source: golang-github-example-foo
+Section: golang
+Maintainer: Debian Go Packaging Team <team+pkg-go@tracker.debian.org>
+Build-Depends: debhelper-compat (= 13),
+               dh-sequence-golang,
+               golang-any,
+Standards-Version: 4.7.0
+Vcs-Browser: https://salsa.debian.org/go-team/packages/golang-github-example-foo
+Vcs-Git: https://salsa.debian.org/go-team/packages/golang-github-example-foo.git
+XS-Go-Import-Path: github.com/example/foo
+
+Package: golang-github-example-foo-dev
+Architecture: all
+Multi-Arch: foreign
+Depends: ${misc:Depends},
+Description: foo (library)
+ Foo.
+
+Package: foo
+Architecture: any
+Depends: ${misc:Depends},
+         ${shlibs:Depends},
+Static-Built-Using: ${misc:Static-Built-Using}
+Description: foo (program)
+ Foo.
+`,
+		"debian/watch": "version=4\nhttps://github.com/example/foo/tags .*/v?(\\d\\S*)\\.tar\\.gz debian\n",
+		"debian/gbp.conf": `[DEFAULT]
+debian-branch = debian/sid
+dist = DEP14
+`,
+		"debian/salsa-ci.yml": `---
+include:
+  - https://salsa.debian.org/salsa-ci-team/pipeline/raw/master/recipes/debian.yml
+`,
+	})
+
+	findings := lintSid(t, dir)
+	if len(findings) != 0 {
+		t.Errorf("lint() => %v, want no findings", findings)
+	}
+}
+
+func TestLintOutdated(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"debian/control": `Source: golang-github-example-foo
+Section: golang
+Maintainer: Debian Go Packaging Team <team+pkg-go@tracker.debian.org>
+Build-Depends: debhelper (>= 11),
+               dh-golang,
+               golang-any,
+Standards-Version: 4.5.0
+Vcs-Browser: https://salsa.debian.org/go-team/packages/foo
+Vcs-Git: https://salsa.debian.org/go-team/packages/golang-github-example-foo.git
+
+Package: golang-github-example-foo-dev
+Architecture: all
+Depends: ${misc:Depends},
+Description: foo (library)
+ Foo.
+
+Package: foo
+Architecture: any
+Built-Using: ${misc:Built-Using}
+Description: TODO: short description
+ Foo.
+`,
+		"debian/watch":         "version=3\n",
+		"debian/gbp.conf":      "[DEFAULT]\npristine-tar = True\n",
+		"debian/gitlab-ci.yml": "include: foo\n",
+	})
+
+	findings := lintSid(t, dir)
+	want := []string{
+		"missing-xs-go-import-path",
+		"uses-debhelper-without-compat",
+		"missing-dh-sequence-golang",
+		"out-of-date-standards-version",
+		"vcs-browser-mismatch",
+		"library-not-multi-arch-foreign",
+		"uses-built-using-instead-of-static-built-using",
+		"todo-marker",
+		"non-dep14-debian-branch",
+		"non-dep14-dist",
+		"uses-pristine-tar",
+		"legacy-gitlab-ci-yml",
+		"out-of-date-watch-version",
+	}
+	if got := lintTags(findings); !reflect.DeepEqual(got, want) {
+		t.Errorf("lint() => %v, want %v", got, want)
+	}
+}
+
+func TestLintDistro(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"debian/control": `Source: golang-github-example-foo
+Maintainer: Ubuntu Developers <ubuntu-devel-discuss@lists.ubuntu.com>
+Build-Depends: debhelper-compat (= 13),
+               dh-sequence-golang,
+Standards-Version: 4.7.0
+Vcs-Browser: https://salsa.debian.org/go-team/packages/golang-github-example-foo
+Vcs-Git: https://git.launchpad.net/ubuntu/+source/golang-github-example-foo.git
+XS-Go-Import-Path: github.com/example/foo
+`,
+		"debian/gbp.conf": "[DEFAULT]\ndebian-branch = debian/sid\ndist = DEP14\n",
+	})
+
+	suite, err := golangdeb.UbuntuDistro.ParseSuite("noble-backports")
+	if err != nil {
+		t.Fatal(err)
+	}
+	findings, err := lint(dir, &golangdeb.UbuntuDistro, suite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fixes := make(map[string]string)
+	for _, f := range findings {
+		fixes[f.Tag] = f.Fix
+	}
+	for tag, want := range map[string]string{
+		"vcs-browser-mismatch":    "set \"Vcs-Browser: https://git.launchpad.net/ubuntu/+source/golang-github-example-foo\"",
+		"non-dep14-debian-branch": "rename the branch and set \"debian-branch = ubuntu/noble-backports\"",
+	} {
+		if got, ok := fixes[tag]; !ok || got != want {
+			t.Errorf("%s: got fix %q (found: %v), want %q", tag, got, ok, want)
+		}
+	}
+	if _, ok := fixes["vcs-git-mismatch"]; ok {
+		t.Errorf("got vcs-git-mismatch for the Vcs-Git of the distribution")
+	}
+}
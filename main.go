@@ -1,18 +1,61 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
+	"github.com/Debian/dh-make-golang/pkg/golangdeb"
 	"github.com/google/go-github/v60/github"
 	"github.com/gregjones/httpcache"
 )
 
 const program = "dh-make-golang"
 
-var (
-	gitHub *github.Client
-)
+// newGitHubClient returns a GitHub API client for golangdeb.FetchMetadata,
+// with the credentials from the GITHUB_USERNAME, GITHUB_PASSWORD and
+// GITHUB_OTP environment variables, if any.
+func newGitHubClient() *github.Client {
+	transport := github.BasicAuthTransport{
+		Username:  os.Getenv("GITHUB_USERNAME"),
+		Password:  os.Getenv("GITHUB_PASSWORD"),
+		OTP:       os.Getenv("GITHUB_OTP"),
+		Transport: httpcache.NewMemoryCacheTransport(),
+	}
+	client := transport.Client()
+	client.Timeout = golangdeb.APITimeout
+	return github.NewClient(client)
+}
+
+// parseGlobalFlags parses the global flags preceding the command in args
+// and returns the remaining arguments. Only the known global flags are
+// consumed, so that "dh-make-golang -type=library <importpath>" still runs
+// the make command.
+func parseGlobalFlags(args []string) (timeout time.Duration, rest []string, _ error) {
+	fs := flag.NewFlagSet(program, flag.ContinueOnError)
+	fs.DurationVar(&timeout, "timeout", 0, "")
+	var n int
+	for n < len(args) {
+		name, _, hasValue := strings.Cut(strings.TrimLeft(args[n], "-"), "=")
+		if !strings.HasPrefix(args[n], "-") || fs.Lookup(name) == nil {
+			break
+		}
+		n++
+		if !hasValue {
+			n++ // the value is the next argument
+		}
+	}
+	n = min(n, len(args))
+	if err := fs.Parse(args[:n]); err != nil {
+		return 0, nil, err
+	}
+	return timeout, args[n:], nil
+}
 
 func usage() {
 	fmt.Fprintf(os.Stderr, `%s
@@ -22,6 +65,10 @@ func usage() {
 Usage:
 	%s [globalflags] <command> [flags] <args>
 
+Global flags:
+	-timeout duration	abort the command after this long, e.g. 30m
+				(default: no limit)
+
 %s commands:
 	make			create a Debian package
 	search			search Debian for already-existing packages
@@ -29,6 +76,10 @@ Usage:
 	create-salsa-project	create a project for hosting Debian packaging
 	clone			clone a Go package from Salsa
 	check-depends		compare go.mod and d/control to check for changes
+	lint			check an existing packaging against team conventions
+	outdated		list packages which are behind upstream releases
+	rdeps			list reverse dependencies of a Go library package
+	itp			write and send the ITP email for a packaging
 
 For backwards compatibility, when no command is specified,
 the make command is executed.
@@ -40,16 +91,26 @@ e.g. "%s make -help"
 }
 
 func main() {
-	transport := github.BasicAuthTransport{
-		Username:  os.Getenv("GITHUB_USERNAME"),
-		Password:  os.Getenv("GITHUB_PASSWORD"),
-		OTP:       os.Getenv("GITHUB_OTP"),
-		Transport: httpcache.NewMemoryCacheTransport(),
+	// Retrieve args and Shift binary name off argument list.
+	timeout, args, err := parseGlobalFlags(os.Args[1:])
+	if err != nil {
+		os.Exit(2)
 	}
-	gitHub = github.NewClient(transport.Client())
 
-	// Retrieve args and Shift binary name off argument list.
-	args := os.Args[1:]
+	// Cancel the context on Ctrl-C, so that subprocesses are stopped and
+	// temporary files removed. A second Ctrl-C exits immediately.
+	sigCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-sigCtx.Done()
+		stop()
+	}()
+	ctx := sigCtx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
 
 	// Retrieve command name as first argument.
 	cmd := ""
@@ -61,19 +122,27 @@ func main() {
 	case "help":
 		usage()
 	case "search":
-		execSearch(args[1:])
+		execSearch(ctx, args[1:])
 	case "create-salsa-project":
-		execCreateSalsaProject(args[1:])
+		execCreateSalsaProject(ctx, args[1:])
 	case "estimate":
-		execEstimate(args[1:])
+		execEstimate(ctx, args[1:])
 	case "make":
-		execMake(args[1:], nil)
+		execMake(ctx, args[1:], nil)
 	case "clone":
-		execClone(args[1:])
+		execClone(ctx, args[1:])
 	case "check-depends":
-		execCheckDepends(args[1:])
+		execCheckDepends(ctx, args[1:])
+	case "lint":
+		execLint(ctx, args[1:])
+	case "outdated":
+		execOutdated(ctx, args[1:])
+	case "rdeps":
+		execRdeps(ctx, args[1:])
+	case "itp":
+		execITP(ctx, args[1:])
 	default:
 		// redirect -help to the global usage
-		execMake(args, usage)
+		execMake(ctx, args, usage)
 	}
 }
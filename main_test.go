@@ -0,0 +1,52 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseGlobalFlags(t *testing.T) {
+	for _, tt := range []struct {
+		args        []string
+		wantTimeout time.Duration
+		wantRest    []string
+	}{
+		{
+			args:     []string{"make", "golang.org/x/oauth2"},
+			wantRest: []string{"make", "golang.org/x/oauth2"},
+		},
+		{
+			args:        []string{"-timeout=30m", "make", "golang.org/x/oauth2"},
+			wantTimeout: 30 * time.Minute,
+			wantRest:    []string{"make", "golang.org/x/oauth2"},
+		},
+		{
+			args:        []string{"--timeout", "90s", "estimate", "-timeout=1m"},
+			wantTimeout: 90 * time.Second,
+			wantRest:    []string{"estimate", "-timeout=1m"},
+		},
+		{
+			// Flags of the implicit make command are left alone.
+			args:     []string{"-type=library", "golang.org/x/oauth2"},
+			wantRest: []string{"-type=library", "golang.org/x/oauth2"},
+		},
+	} {
+		timeout, rest, err := parseGlobalFlags(tt.args)
+		if err != nil {
+			t.Errorf("parseGlobalFlags(%q): %v", tt.args, err)
+			continue
+		}
+		if timeout != tt.wantTimeout {
+			t.Errorf("parseGlobalFlags(%q): got timeout %v, want %v", tt.args, timeout, tt.wantTimeout)
+		}
+		if diff := cmp.Diff(tt.wantRest, rest); diff != "" {
+			t.Errorf("parseGlobalFlags(%q): unexpected remaining args (-want +got):\n%s", tt.args, diff)
+		}
+	}
+
+	if _, _, err := parseGlobalFlags([]string{"-timeout=soon", "make"}); err == nil {
+		t.Errorf("parseGlobalFlags with an invalid duration: got no error")
+	}
+}
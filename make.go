@@ -1,706 +1,62 @@
 package main
 
 import (
+	"cmp"
+	"context"
 	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"log"
 	"mime"
-	"net/http"
-	"net/url"
 	"os"
-	"os/exec"
-	"os/user"
 	"path/filepath"
 	"strings"
 
-	"golang.org/x/net/publicsuffix"
+	"github.com/Debian/dh-make-golang/pkg/golangdeb"
 	"golang.org/x/sync/errgroup"
-	"golang.org/x/tools/go/vcs"
 )
 
-type packageType int
-
-const (
-	typeGuess packageType = iota
-	typeLibrary
-	typeProgram
-	typeLibraryProgram
-	typeProgramLibrary
-)
-
-var wrapAndSort string
-
-var errUnsupportedHoster = errors.New("unsupported hoster")
-
-func passthroughEnv() []string {
-	var relevantVariables = []string{
-		"HOME",
-		"PATH",
-		"HTTP_PROXY", "http_proxy",
-		"HTTPS_PROXY", "https_proxy",
-		"ALL_PROXY", "all_proxy",
-		"NO_PROXY", "no_proxy",
-		"GIT_PROXY_COMMAND",
-		"GIT_HTTP_PROXY_AUTHMETHOD",
-	}
-	var result []string
-	for _, variable := range relevantVariables {
-		if value, ok := os.LookupEnv(variable); ok {
-			result = append(result, fmt.Sprintf("%s=%s", variable, value))
-		}
-	}
-	return result
-}
-
-func findVendorDirs(dir string) ([]string, error) {
-	var vendorDirs []string
-	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		if info != nil && !info.IsDir() {
-			return nil // nothing to do for anything but directories
-		}
-		if info.Name() == ".git" ||
-			info.Name() == ".hg" ||
-			info.Name() == ".bzr" {
-			return filepath.SkipDir
-		}
-		if info.Name() == "vendor" {
-			rel, err := filepath.Rel(dir, path)
-			if err != nil {
-				return fmt.Errorf("filepath.Rel: %w", err)
-			}
-			vendorDirs = append(vendorDirs, rel)
-		}
-		return nil
-	})
-	return vendorDirs, err
-}
-
-func downloadFile(filename, url string) error {
-	dst, err := os.Create(filename)
-	if err != nil {
-		return fmt.Errorf("create: %w", err)
-	}
-	defer dst.Close()
-
-	resp, err := http.Get(url)
-	if err != nil {
-		return fmt.Errorf("http get: %w", err)
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode != 200 {
-		return fmt.Errorf("response: %s", resp.Status)
-	}
-
-	_, err = io.Copy(dst, resp.Body)
-	if err != nil {
-		return fmt.Errorf("copy: %w", err)
-	}
-
-	return nil
-}
-
-// upstream describes the upstream repo we are about to package.
-type upstream struct {
-	rr          *vcs.RepoRoot
-	tarPath     string   // path to the downloaded or generated orig tarball tempfile
-	compression string   // compression method, either "gz" or "xz"
-	version     string   // Debian package upstream version number, e.g. 0.0~git20180204.1d24609
-	tag         string   // Latest upstream tag, if any
-	commitIsh   string   // commit-ish corresponding to upstream version to be packaged
-	remote      string   // git remote, set to short hostname if upstream git history is included
-	firstMain   string   // import path of the first main package within repo, if any
-	vendorDirs  []string // all vendor sub directories, relative to the repo directory
-	repoDeps    []string // the repository paths of all dependencies (e.g. github.com/zyedidia/glob)
-	hasGodeps   bool     // whether the Godeps/_workspace directory exists
-	hasRelease  bool     // whether any release tags exist, for debian/watch
-	isRelease   bool     // whether what we end up packaging is a tagged release
-}
-
-func (u *upstream) get(gopath, repo, rev string) error {
-	done := make(chan struct{})
-	defer close(done)
-	go progressSize("go get", filepath.Join(gopath, "src"), done)
-
-	rr, err := vcs.RepoRootForImportPath(repo, false)
-	if err != nil {
-		return fmt.Errorf("get repo root: %w", err)
-	}
-	u.rr = rr
-	dir := filepath.Join(gopath, "src", rr.Root)
-	if rev != "" {
-		// Run "git clone {repo} {dir}" and "git checkout {tag}"
-		return rr.VCS.CreateAtRev(dir, rr.Repo, rev)
-	}
-	// Run "git clone {repo} {dir}" (or the equivalent command for hg, svn, bzr)
-	return rr.VCS.Create(dir, rr.Repo)
-}
-
-func (u *upstream) tarballUrl() (string, error) {
-	repo := strings.TrimSuffix(u.rr.Repo, ".git")
-	repoU, err := url.Parse(repo)
-	if err != nil {
-		return "", fmt.Errorf("parse URL: %w", err)
-	}
-
-	switch repoU.Host {
-	case "github.com":
-		return fmt.Sprintf("%s/archive/%s.tar.%s",
-			repo, u.tag, u.compression), nil
-	case "gitlab.com", "salsa.debian.org":
-		parts := strings.Split(repoU.Path, "/")
-		if len(parts) < 3 {
-			return "", fmt.Errorf("incomplete repo URL: %s", u.rr.Repo)
-		}
-		project := parts[2]
-		return fmt.Sprintf("%s/-/archive/%s/%s-%s.tar.%s",
-			repo, u.tag, project, u.tag, u.compression), nil
-	case "git.sr.ht":
-		return fmt.Sprintf("%s/archive/%s.tar.%s",
-			repo, u.tag, u.compression), nil
-	case "codeberg.org":
-		return fmt.Sprintf("%s/archive/%s.tar.%s",
-			repo, u.tag, u.compression), nil
-	default:
-		return "", errUnsupportedHoster
-	}
-}
-
-func (u *upstream) tarballFromHoster() error {
-	tarURL, err := u.tarballUrl()
-	if err != nil {
-		return err
-	}
-
-	done := make(chan struct{})
-	go progressSize("Download", u.tarPath, done)
-
-	log.Printf("Downloading %s", tarURL)
-	err = downloadFile(u.tarPath, tarURL)
-
-	close(done)
-
-	return err
-}
-
-func (u *upstream) tar(gopath, repo string) error {
-	f, err := os.CreateTemp("", "dh-make-golang")
-	if err != nil {
-		return fmt.Errorf("create temp file: %w", err)
-	}
-	u.tarPath = f.Name()
-	f.Close()
-
-	if u.isRelease {
-		if u.hasGodeps {
-			log.Printf("Godeps/_workspace exists, not downloading tarball from hoster.")
-		} else {
-			u.compression = "gz"
-			if err := u.tarballFromHoster(); err == nil {
-				return nil
-			} else if err == errUnsupportedHoster {
-				log.Printf("INFO: Hoster does not provide release tarball\n")
-			} else {
-				return fmt.Errorf("tarball from hoster: %w", err)
-			}
-		}
-	}
-
-	u.compression = "xz"
-	base := filepath.Base(repo)
-	log.Printf("Generating temp tarball as %q\n", u.tarPath)
-	dir := filepath.Dir(repo)
-	cmd := exec.Command(
-		"tar",
-		"cJf",
-		u.tarPath,
-		"--exclude=.git",
-		"--exclude=Godeps/_workspace",
-		"--exclude="+base+"/debian",
-		base)
-	cmd.Dir = filepath.Join(gopath, "src", dir)
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
-}
-
-// findMains finds main packages within the repo (useful to auto-detect the
-// package type).
-func (u *upstream) findMains(gopath, repo string) error {
-	cmd := exec.Command("go", "list", "-e", "-f", "{{.ImportPath}} {{.Name}}", repo+"/...")
-	cmd.Dir = filepath.Join(gopath, "src", repo)
-	cmd.Env = passthroughEnv()
-	cmd.Stderr = os.Stderr
-	log.Println("findMains: Running", cmd, "in", cmd.Dir)
-	out, err := cmd.Output()
-	if err != nil {
-		log.Println("WARNING: In findMains:", fmt.Errorf("%q: %w", cmd.Args, err))
-		// See https://bugs.debian.org/992610
-		log.Printf("Retrying without appending \"/...\" to repo")
-		cmd = exec.Command("go", "list", "-e", "-f", "{{.ImportPath}} {{.Name}}", repo)
-		cmd.Dir = filepath.Join(gopath, "src", repo)
-		cmd.Env = passthroughEnv()
-		cmd.Stderr = os.Stderr
-		log.Println("findMains: Running", cmd, "in", cmd.Dir)
-		out, err = cmd.Output()
-		if err != nil {
-			log.Println("WARNING: In findMains:", fmt.Errorf("%q: %w", cmd.Args, err))
-		}
-	}
-	for line := range strings.SplitSeq(strings.TrimSpace(string(out)), "\n") {
-		if strings.Contains(line, "/vendor/") ||
-			strings.Contains(line, "/Godeps/") ||
-			strings.Contains(line, "/samples/") ||
-			strings.Contains(line, "/examples/") ||
-			strings.Contains(line, "/example/") {
-			continue
-		}
-		if before, ok := strings.CutSuffix(line, " main"); ok {
-			u.firstMain = before
-			break
-		}
-	}
-	return nil
-}
-
-func (u *upstream) findDependencies(gopath, repo string) error {
-	log.Printf("Determining dependencies\n")
-
-	cmd := exec.Command("go", "list", "-e", "-f", "{{join .Imports \"\\n\"}}\n{{join .TestImports \"\\n\"}}\n{{join .XTestImports \"\\n\"}}", repo+"/...")
-	cmd.Dir = filepath.Join(gopath, "src", repo)
-	cmd.Env = passthroughEnv()
-	cmd.Stderr = os.Stderr
-
-	out, err := cmd.Output()
-	if err != nil {
-		log.Println("WARNING: In findDependencies:", fmt.Errorf("%q: %w", cmd.Args, err))
-		// See https://bugs.debian.org/992610
-		log.Printf("Retrying without appending \"/...\" to repo")
-		cmd = exec.Command("go", "list", "-e", "-f", "{{join .Imports \"\\n\"}}\n{{join .TestImports \"\\n\"}}\n{{join .XTestImports \"\\n\"}}", repo)
-		cmd.Dir = filepath.Join(gopath, "src", repo)
-		cmd.Env = passthroughEnv()
-		cmd.Stderr = os.Stderr
-		out, err = cmd.Output()
-		if err != nil {
-			log.Println("WARNING: In findDependencies:", fmt.Errorf("%q: %w", cmd.Args, err))
-		}
-	}
-
-	godependencies := make(map[string]bool)
-	for p := range strings.SplitSeq(strings.TrimSpace(string(out)), "\n") {
-		if p == "" {
-			continue // skip separators between import types
-		}
-		// Strip packages that are included in the repository we are packaging.
-		if strings.HasPrefix(p, repo+"/") || p == repo {
-			continue
-		}
-		if p == "C" {
-			// TODO: maybe parse the comments to figure out C deps from pkg-config files?
-		} else {
-			godependencies[p] = true
-		}
-	}
-
-	if len(godependencies) == 0 {
-		return nil
-	}
-
-	// Remove all packages which are in the standard lib.
-	cmd = exec.Command("go", "list", "std")
-	cmd.Stderr = os.Stderr
-	cmd.Env = passthroughEnv()
-
-	out, err = cmd.Output()
-	if err != nil {
-		return fmt.Errorf("go list std: (args: %v): %w", cmd.Args, err)
-	}
-
-	for line := range strings.SplitSeq(strings.TrimSpace(string(out)), "\n") {
-		delete(godependencies, line)
-	}
-
-	// Resolve all packages to the root of their repository.
-	roots := make(map[string]bool)
-	for dep := range godependencies {
-		rr, err := vcs.RepoRootForImportPath(dep, false)
-		if err != nil {
-			log.Printf("Could not determine repo path for import path %q: %v\n", dep, err)
-			continue
-		}
-
-		roots[rr.Root] = true
-	}
-
-	u.repoDeps = make([]string, 0, len(godependencies))
-	for root := range roots {
-		u.repoDeps = append(u.repoDeps, root)
-	}
-
-	return nil
-}
-
-func makeUpstreamSourceTarball(repo, revision string, forcePrerelease bool) (*upstream, error) {
-	gopath, err := os.MkdirTemp("", "dh-make-golang")
-	if err != nil {
-		return nil, fmt.Errorf("create tmp dir: %w", err)
-	}
-	defer os.RemoveAll(gopath)
-	repoDir := filepath.Join(gopath, "src", repo)
-
-	var u upstream
-
-	log.Printf("Downloading %q\n", repo+"/...")
-	if err := u.get(gopath, repo, revision); err != nil {
-		return nil, fmt.Errorf("go get: %w", err)
-	}
-
-	// Verify early this repository uses git (we call pkgVersionFromGit later):
-	if _, err := os.Stat(filepath.Join(repoDir, ".git")); os.IsNotExist(err) {
-		return nil, fmt.Errorf("not a git repository; dh-make-golang currently only supports git")
-	}
-
-	if _, err := os.Stat(filepath.Join(repoDir, "debian")); err == nil {
-		log.Printf("WARNING: ignoring debian/ directory that came with the upstream sources\n")
-	}
-
-	u.vendorDirs, err = findVendorDirs(repoDir)
-	if err != nil {
-		return nil, fmt.Errorf("find vendor dirs: %w", err)
-	}
-	if len(u.vendorDirs) > 0 {
-		log.Printf("Deleting upstream vendor/ directories")
-		for _, dir := range u.vendorDirs {
-			if err := os.RemoveAll(filepath.Join(repoDir, dir)); err != nil {
-				return nil, fmt.Errorf("remove all: %w", err)
-			}
-		}
-	}
-
-	if _, err := os.Stat(filepath.Join(repoDir, "Godeps", "_workspace")); !os.IsNotExist(err) {
-		log.Println("Godeps/_workspace detected")
-		u.hasGodeps = true
-	}
-
-	log.Printf("Determining upstream version number\n")
-
-	u.version, err = pkgVersionFromGit(repoDir, &u, revision, forcePrerelease)
-	if err != nil {
-		return nil, fmt.Errorf("get package version from Git: %w", err)
-	}
-
-	log.Printf("Package version is %q\n", u.version)
-
-	if err := u.findMains(gopath, repo); err != nil {
-		return nil, fmt.Errorf("find mains: %w", err)
-	}
-
-	if err := u.findDependencies(gopath, repo); err != nil {
-		return nil, fmt.Errorf("find dependencies: %w", err)
-	}
-
-	if err := u.tar(gopath, repo); err != nil {
-		return nil, fmt.Errorf("tar: %w", err)
-	}
-
-	return &u, nil
-}
-
-func runGitCommandIn(dir string, arg ...string) error {
-	cmd := exec.Command("git", arg...)
-	cmd.Dir = dir
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
-}
-
-func createGitRepository(debsrc, gopkg, orig string, u *upstream,
-	includeUpstreamHistory bool, allowUnknownHoster bool, debianBranch string, pristineTar bool) (string, error) {
-	wd, err := os.Getwd()
-	if err != nil {
-		return "", fmt.Errorf("get cwd: %w", err)
-	}
-	dir := filepath.Join(wd, debsrc)
-	if err := os.Mkdir(dir, 0755); err != nil {
-		return "", fmt.Errorf("mkdir: %w", err)
-	}
-
-	if err := runGitCommandIn(dir, "init", "-b", debianBranch); err != nil {
-		return dir, fmt.Errorf("git init: %w", err)
-	}
-
-	// Set repository options
-
-	if debianName := getDebianName(); debianName != "TODO" {
-		if err := runGitCommandIn(dir, "config", "user.name", debianName); err != nil {
-			return dir, fmt.Errorf("git config user.name: %w", err)
-		}
-	}
-	if debianEmail := getDebianEmail(); debianEmail != "TODO" {
-		if err := runGitCommandIn(dir, "config", "user.email", debianEmail); err != nil {
-			return dir, fmt.Errorf("git config user.email: %w", err)
-		}
-	}
-	if err := runGitCommandIn(dir, "config", "push.default", "matching"); err != nil {
-		return dir, fmt.Errorf("git config push.default: %w", err)
-	}
-
-	// [remote "origin"]
-
-	originURL := "git@salsa.debian.org:go-team/packages/" + debsrc + ".git"
-	log.Printf("Adding remote \"origin\" with URL %q\n", originURL)
-	if err := runGitCommandIn(dir, "remote", "add", "origin", originURL); err != nil {
-		return dir, fmt.Errorf("git remote add origin %s: %w", originURL, err)
-	}
-	if err := runGitCommandIn(dir, "config", "--add", "remote.origin.push", "+refs/heads/*:refs/heads/*"); err != nil {
-		return dir, fmt.Errorf("git config --add remote.origin.push */heads/*: %w", err)
-	}
-	if err := runGitCommandIn(dir, "config", "--add", "remote.origin.push", "+refs/tags/*:refs/tags/*"); err != nil {
-		return dir, fmt.Errorf("git config --add remote.origin.push */tags/*: %w", err)
-	}
-
-	// Preconfigure branches
-
-	branches := []string{debianBranch, "upstream"}
-	if pristineTar {
-		branches = append(branches, "pristine-tar")
-	}
-	for _, branch := range branches {
-		if err := runGitCommandIn(dir, "config", "branch."+branch+".remote", "origin"); err != nil {
-			return dir, fmt.Errorf("git config branch.%s.remote origin: %w", branch, err)
-		}
-		if err := runGitCommandIn(dir, "config", "branch."+branch+".merge", "refs/heads/"+branch); err != nil {
-			return dir, fmt.Errorf("git config branch.%s.merge refs/heads/%s: %w", branch, branch, err)
-		}
-	}
-
-	if includeUpstreamHistory {
-		u.remote, err = shortHostName(gopkg, allowUnknownHoster)
-		if err != nil {
-			return dir, fmt.Errorf("unable to fetch upstream history: %q", err)
-		}
-		if u.remote == "debian" {
-			u.remote = "salsa"
-		}
-		log.Printf("Adding remote %q with URL %q\n", u.remote, u.rr.Repo)
-		if err := runGitCommandIn(dir, "remote", "add", u.remote, u.rr.Repo); err != nil {
-			return dir, fmt.Errorf("git remote add %s %s: %w", u.remote, u.rr.Repo, err)
-		}
-		log.Printf("Running \"git fetch --tags %s\"\n", u.remote)
-		if err := runGitCommandIn(dir, "fetch", "--tags", u.remote); err != nil {
-			return dir, fmt.Errorf("git fetch %s: %w", u.remote, err)
-		}
-	}
-
-	// Import upstream orig tarball
-
-	arg := []string{"import-orig", "--no-interactive", "--debian-branch=" + debianBranch}
-	if pristineTar {
-		arg = append(arg, "--pristine-tar")
-	}
-	if includeUpstreamHistory {
-		arg = append(arg, "--upstream-vcs-tag="+u.commitIsh)
-	}
-	arg = append(arg, filepath.Join(wd, orig))
-	cmd := exec.Command("gbp", arg...)
-	cmd.Dir = dir
-	cmd.Stderr = os.Stderr
-	if err := cmd.Run(); err != nil {
-		return dir, fmt.Errorf("import-orig: %w", err)
-	}
-
-	return dir, nil
-}
-
-// normalize package name into Debian standard[1]
-// https://www.debian.org/doc/debian-policy/ch-controlfields.html#source
-// Package names (both source and binary, see Package, Section 5.6.7) must
-// consist only of lower case letters (a-z), digits (0-9), plus (+) and minus
-// (-) signs, and periods (.). They must be at least two characters long and
-// must start with an alphanumeric character.
-func normalizeDebianPackageName(str string) string {
-	lowerDigitPlusMinusDot := func(r rune) rune {
-		switch {
-		case r >= 'a' && r <= 'z' || '0' <= r && r <= '9':
-			return r
-		case r >= 'A' && r <= 'Z':
-			return r + ('a' - 'A')
-		case r == '.' || r == '+' || r == '-':
-			return r
-		case r == '_':
-			return '-'
-		}
-		return -1
-	}
-
-	safe := strings.Trim(strings.Map(lowerDigitPlusMinusDot, str), "-")
-	if len(safe) < 2 {
-		return "TODO"
-	}
-
-	return safe
-}
-
-func shortHostName(gopkg string, allowUnknownHoster bool) (host string, err error) {
-	knownHosts := map[string]string{
-		// keep the list in alphabetical order
-		"bazil.org":            "bazil",
-		"bitbucket.org":        "bitbucket",
-		"blitiri.com.ar":       "blitiri",
-		"cloud.google.com":     "googlecloud",
-		"code.google.com":      "googlecode",
-		"codeberg.org":         "codeberg",
-		"filippo.io":           "filippo",
-		"fortio.org":           "fortio",
-		"fyne.io":              "fyne",
-		"git.sr.ht":            "sourcehut",
-		"github.com":           "github",
-		"gitlab.com":           "gitlab",
-		"go.bug.st":            "bugst",
-		"go.cypherpunks.ru":    "cypherpunks",
-		"go.mongodb.org":       "mongodb",
-		"go.opentelemetry.io":  "opentelemetry",
-		"go.step.sm":           "step",
-		"go.uber.org":          "uber",
-		"go4.org":              "go4",
-		"gocloud.dev":          "gocloud",
-		"golang.org":           "golang",
-		"google.golang.org":    "google",
-		"gopkg.in":             "gopkg",
-		"honnef.co":            "honnef",
-		"howett.net":           "howett",
-		"k8s.io":               "k8s",
-		"modernc.org":          "modernc",
-		"pault.ag":             "pault",
-		"pgregory.net":         "pgregory",
-		"rsc.io":               "rsc",
-		"salsa.debian.org":     "debian",
-		"sigs.k8s.io":          "k8s-sigs",
-		"software.sslmate.com": "sslmate",
-		"zgo.at":               "zgoat",
-	}
-	fqdn, _, _ := strings.Cut(gopkg, "/")
-	if host, ok := knownHosts[fqdn]; ok {
-		return host, nil
-	}
-	if !allowUnknownHoster {
-		return "", fmt.Errorf("unknown hoster %q", fqdn)
-	}
-	suffix, _ := publicsuffix.PublicSuffix(fqdn)
-	host = fqdn[:len(fqdn)-len(suffix)-len(".")]
-	log.Printf("WARNING: Using %q as canonical hostname for %q. If that is not okay, please file a bug against %s.\n", host, fqdn, os.Args[0])
-	return host, nil
-}
-
-// debianNameFromGopkg maps a Go package repo path to a Debian package name,
-// e.g. "golang.org/x/text" → "golang-golang-x-text".
-// This follows https://fedoraproject.org/wiki/PackagingDrafts/Go#Package_Names
-func debianNameFromGopkg(gopkg string, t packageType, customProgPkgName string, allowUnknownHoster bool) string {
-	parts := strings.Split(gopkg, "/")
-
-	if t == typeProgram || t == typeProgramLibrary {
-		if customProgPkgName != "" {
-			return normalizeDebianPackageName(customProgPkgName)
-		}
-		return normalizeDebianPackageName(parts[len(parts)-1])
-	}
-
-	host, err := shortHostName(gopkg, allowUnknownHoster)
-	if err != nil {
-		log.Fatalf("Cannot derive Debian package name: %v. See -help output for -allow_unknown_hoster\n", err)
-	}
-	parts[0] = host
-
-	return normalizeDebianPackageName("golang-" + strings.Join(parts, "-"))
-}
-
-func getDebianName() string {
-	if name := strings.TrimSpace(os.Getenv("DEBFULLNAME")); name != "" {
-		return name
-	}
-	if name := strings.TrimSpace(os.Getenv("DEBNAME")); name != "" {
-		return name
-	}
-	if u, err := user.Current(); err == nil && u.Name != "" {
-		return u.Name
-	}
-	return "TODO"
-}
-
-func getDebianEmail() string {
-	if email := strings.TrimSpace(os.Getenv("DEBEMAIL")); email != "" {
-		return email
-	}
-	mailname, err := os.ReadFile("/etc/mailname")
-	// By default, /etc/mailname contains "debian" which is not useful; check for ".".
-	if err == nil && strings.Contains(string(mailname), ".") {
-		if u, err := user.Current(); err == nil && u.Username != "" {
-			return u.Username + "@" + strings.TrimSpace(string(mailname))
-		}
-	}
-	return "TODO"
-}
-
-func writeITP(gopkg, debsrc, debversion string) (string, error) {
-	itpname := fmt.Sprintf("itp-%s.txt", debsrc)
+func writeITP(m *golangdeb.Metadata, dir, gopkg, debsrc, debversion string, d *golangdeb.DistroProfile, reasoning itpReasoning) (string, error) {
+	itpname := filepath.Join(dir, fmt.Sprintf("itp-%s.txt", debsrc))
 	f, err := os.Create(itpname)
 	if err != nil {
 		return itpname, fmt.Errorf("create file: %w", err)
 	}
 	defer f.Close()
 
-	// TODO: memoize
-	license, _, err := getLicenseForGopkg(gopkg)
-	if err != nil {
-		log.Printf("Could not determine license for %q: %v\n", gopkg, err)
-		license = "TODO"
-	}
+	license := cmp.Or(m.License, "TODO")
+	author := cmp.Or(m.Author, "TODO")
+	description := cmp.Or(m.Description, "TODO")
 
-	author, _, err := getAuthorAndCopyrightForGopkg(gopkg)
-	if err != nil {
-		log.Printf("Could not determine author for %q: %v\n", gopkg, err)
-		author = "TODO"
-	}
+	subject := mime.QEncoding.Encode("utf-8", fmt.Sprintf("%s%s -- %s", d.ITPSubjectPrefix, debsrc, description))
 
-	description, err := getDescriptionForGopkg(gopkg)
-	if err != nil {
-		log.Printf("Could not determine description for %q: %v\n", gopkg, err)
-		description = "TODO"
-	}
-
-	subject := mime.QEncoding.Encode("utf-8", fmt.Sprintf("ITP: %s -- %s", debsrc, description))
-
-	fmt.Fprintf(f, "From: %q <%s>\n", mime.QEncoding.Encode("utf-8", getDebianName()), getDebianEmail())
-	fmt.Fprintf(f, "To: submit@bugs.debian.org\n")
+	fmt.Fprintf(f, "From: %q <%s>\n", mime.QEncoding.Encode("utf-8", golangdeb.DebianName()), golangdeb.DebianEmail())
+	fmt.Fprintf(f, "To: %s\n", d.ITPTo)
 	fmt.Fprintf(f, "Subject: %s\n", subject)
 	fmt.Fprintf(f, "Content-Type: text/plain; charset=utf-8\n")
 	fmt.Fprintf(f, "Content-Transfer-Encoding: 8bit\n")
-	fmt.Fprintf(f, "X-Debbugs-CC: debian-devel@lists.debian.org, debian-go@lists.debian.org\n")
+	if d.ITPCC != "" {
+		fmt.Fprintf(f, "X-Debbugs-CC: %s\n", d.ITPCC)
+	}
 	fmt.Fprintf(f, "\n")
-	fmt.Fprintf(f, "Package: wnpp\n")
-	fmt.Fprintf(f, "Severity: wishlist\n")
-	fmt.Fprintf(f, "Owner: %s <%s>\n", getDebianName(), getDebianEmail())
+	for _, h := range d.FormatITPPseudoHeaders(fmt.Sprintf("%s <%s>", golangdeb.DebianName(), golangdeb.DebianEmail())) {
+		fmt.Fprintf(f, "%s\n", h)
+	}
 	fmt.Fprintf(f, "\n")
 	fmt.Fprintf(f, "* Package name    : %s\n", debsrc)
 	fmt.Fprintf(f, "  Version         : %s\n", debversion)
 	fmt.Fprintf(f, "  Upstream Author : %s\n", author)
-	fmt.Fprintf(f, "* URL             : %s\n", getHomepageForGopkg(gopkg))
+	fmt.Fprintf(f, "* URL             : %s\n", m.Homepage())
 	fmt.Fprintf(f, "* License         : %s\n", license)
 	fmt.Fprintf(f, "  Programming Lang: Go\n")
 	fmt.Fprintf(f, "  Description     : %s\n", description)
 	fmt.Fprintf(f, "\n")
 
-	longdescription, err := getLongDescriptionForGopkg(gopkg)
-	if err != nil {
-		log.Printf("Could not determine long description for %q: %v\n", gopkg, err)
-		longdescription = "TODO: long description"
-	}
-	fmt.Fprintln(f, longdescription)
+	fmt.Fprintln(f, cmp.Or(m.LongDescription, "TODO: long description"))
 
 	fmt.Fprintf(f, "\n")
-	fmt.Fprintf(f, "TODO: perhaps reasoning\n")
+	reasoning.write(f, debsrc, d)
 	return itpname, nil
 }
 
@@ -721,13 +77,75 @@ func copyFile(src, dest string) error {
 	return output.Close()
 }
 
-func execMake(args []string, usage func()) {
+// movedAside is the directory within the staging directory holding the
+// existing outputs replaced by moveIntoPlace, until all new ones are in place.
+const movedAside = ".old"
+
+// moveIntoPlace renames the entries with the given names in the staging
+// directory to the current directory and removes the staging directory.
+// Existing files (e.g. an orig tarball) are replaced, but existing
+// directories only with force. All conflicts are checked for before moving
+// anything, and if a rename fails, the existing entries are moved back.
+func moveIntoPlace(staging string, names []string, force bool) (err error) {
+	for _, name := range names {
+		if fi, err := os.Stat(name); err == nil && fi.IsDir() && !force {
+			return fmt.Errorf("%q already exists", name)
+		}
+	}
+	old := filepath.Join(staging, movedAside)
+	if err := os.Mkdir(old, 0755); err != nil {
+		return err
+	}
+	var aside, moved []string
+	defer func() {
+		if err == nil {
+			return
+		}
+		// The new entries have to make room for the old ones again.
+		var errs []error
+		for _, name := range moved {
+			errs = append(errs, os.Rename(name, filepath.Join(staging, name)))
+		}
+		for _, name := range aside {
+			errs = append(errs, os.Rename(filepath.Join(old, name), name))
+		}
+		if rerr := errors.Join(errs...); rerr != nil {
+			err = fmt.Errorf("%w; restoring the previous output from %q: %w", err, old, rerr)
+		} else {
+			os.Remove(old)
+		}
+	}()
+	// os.Rename cannot replace a directory, and replacing files could not
+	// be undone, so move existing entries aside first.
+	for _, name := range names {
+		if _, err := os.Lstat(name); os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		if err := os.Rename(name, filepath.Join(old, name)); err != nil {
+			return fmt.Errorf("rename: %w", err)
+		}
+		aside = append(aside, name)
+	}
+	for _, name := range names {
+		if err := os.Rename(filepath.Join(staging, name), name); err != nil {
+			return fmt.Errorf("rename: %w", err)
+		}
+		moved = append(moved, name)
+	}
+	return os.RemoveAll(staging)
+}
+
+func execMake(ctx context.Context, args []string, usage func()) {
 	fs := flag.NewFlagSet("make", flag.ExitOnError)
 	if usage != nil {
 		fs.Usage = usage
 	} else {
 		fs.Usage = func() {
 			fmt.Fprintf(os.Stderr, "Usage: %s [make] [FLAG]... <go-package-importpath>\n", os.Args[0])
+			fmt.Fprintf(os.Stderr, "       %s make -regenerate [FLAG]... [<packaging-dir>]\n", os.Args[0])
+			fmt.Fprintf(os.Stderr, "       %s make -from-dir <dir> [FLAG]... [<go-package-importpath>]\n", os.Args[0])
 			fmt.Fprintf(os.Stderr, "Example: %s make golang.org/x/oauth2\n", os.Args[0])
 			fmt.Fprintf(os.Stderr, "\n")
 			fmt.Fprintf(os.Stderr, "\"%s make\" downloads the specified Go package from the Internet,\nand creates new files and directories in the current working directory.\n", os.Args[0])
@@ -745,6 +163,27 @@ func execMake(args []string, usage func()) {
 			"to check out, defaulting to the default behavior of git clone.\n"+
 			"Useful in case you do not want to package e.g. current HEAD.")
 
+	var source string
+	fs.StringVar(&source,
+		"source",
+		golangdeb.SourceVCS,
+		"Where to get the upstream source from:\n"+
+			` * "vcs": clone the repository`+"\n"+
+			` * "proxy": download the module zip from the Go module proxy (GOPROXY),`+"\n"+
+			`   e.g. if the repository moved or is huge. -git_revision then is a`+"\n"+
+			`   module version (e.g. v1.2.3) or query (e.g. a branch name).`)
+
+	var fromDir string
+	fs.StringVar(&fromDir,
+		"from-dir",
+		"",
+		"Package the existing local git checkout in this directory instead of\n"+
+			"cloning the upstream repository, at -git_revision or its HEAD. The\n"+
+			"import path defaults to the module path in its go.mod, and the\n"+
+			"upstream remote is taken from the remotes of the checkout. The\n"+
+			"Debian archive and the GitHub API (for the upstream metadata) are\n"+
+			"still queried.")
+
 	var allowUnknownHoster bool
 	fs.BoolVar(&allowUnknownHoster,
 		"allow_unknown_hoster",
@@ -761,7 +200,8 @@ func execMake(args []string, usage func()) {
 		"dep14",
 		true,
 		"Follow DEP-14 branch naming and use debian/sid (instead of master)\n"+
-			"as the default debian-branch.")
+			"as the default debian-branch, or e.g. debian/bookworm-backports\n"+
+			"with -suite.")
 
 	var pristineTar bool
 	fs.BoolVar(&pristineTar,
@@ -796,6 +236,32 @@ func execMake(args []string, usage func()) {
 		"Override the program package name, and the source package name too\n"+
 			"when appropriate, e.g. to name github.com/cli/cli as \"gh\"")
 
+	var vendorDeps bool
+	fs.BoolVar(&vendorDeps,
+		"vendor",
+		false,
+		"Vendor the dependencies using \"go mod vendor\" into the orig tarball\n"+
+			"instead of expecting them to be packaged separately. Only for leaf\n"+
+			"programs for which vendoring is acceptable. Generates debian/copyright\n"+
+			"paragraphs for the vendored modules and lists them in\n"+
+			"XS-Vendored-Sources-Go.")
+
+	var vendorComponentDeps bool
+	fs.BoolVar(&vendorComponentDeps,
+		"vendor-component",
+		false,
+		"Like -vendor, but ship vendor/ in a separate orig component tarball\n"+
+			"(orig-vendor.tar.xz), leaving out the modules which are already\n"+
+			"packaged. Those become build dependencies instead.")
+
+	var neededBy string
+	fs.StringVar(&neededBy,
+		"for",
+		"",
+		"Import path of the package which needs the package being created,\n"+
+			"e.g. because it is a missing dependency of it. The ITP then explains\n"+
+			"this and lists the other dependencies which are not packaged yet.")
+
 	var includeUpstreamHistory bool
 	fs.BoolVar(&includeUpstreamHistory,
 		"upstream_git_history",
@@ -803,6 +269,73 @@ func execMake(args []string, usage func()) {
 		"Include upstream git history (Debian pkg-go team new workflow).\n"+
 			"New in dh-make-golang 0.3.0, currently experimental.")
 
+	var shallow bool
+	fs.BoolVar(&shallow,
+		"shallow",
+		false,
+		"Only fetch the files of the tag to package (-git_revision or the latest\n"+
+			"tag of the default branch) from the upstream repository, instead of\n"+
+			"the files of its history. Speeds up packaging large repositories;\n"+
+			"the upstream history is still fetched into the packaging repository\n"+
+			"(see -upstream_git_history).")
+
+	var trial bool
+	fs.BoolVar(&trial,
+		"build",
+		false,
+		"After creating the packaging, run a trial build and the tests of the\n"+
+			"package in a clean GOPATH without network access, and report missing\n"+
+			"Go packages, test failures and remaining TODOs in debian/.")
+
+	var gocodeDir string
+	fs.StringVar(&gocodeDir,
+		"build_gocode",
+		defaultGocodeDir,
+		"Directory containing the Go sources available to the trial build\n"+
+			"(see -build), as installed by the Debian golang-*-dev packages.")
+
+	var force bool
+	fs.BoolVar(&force,
+		"force",
+		false,
+		"Overwrite the output directory if it already exists.")
+
+	var keepFailed bool
+	fs.BoolVar(&keepFailed,
+		"keep-failed",
+		false,
+		"Keep the staging directory (.dh-make-golang-*) with the partially\n"+
+			"created output when failing, for debugging. By default, the output\n"+
+			"is only moved into place when complete.")
+
+	var regenerate bool
+	fs.BoolVar(&regenerate,
+		"regenerate",
+		false,
+		"Instead of creating a new package, regenerate debian/control, copyright,\n"+
+			"watch, upstream/metadata, gbp.conf and salsa-ci.yml of the existing\n"+
+			"packaging repository in the current directory (or the given directory),\n"+
+			"e.g. after the metadata lookup failed, and show the differences.\n"+
+			"Neither the git history nor the orig tarball are touched.")
+
+	var regenerateFiles string
+	fs.StringVar(&regenerateFiles,
+		"regenerate-files",
+		"",
+		"With -regenerate, the comma-separated files in debian/ to regenerate\n"+
+			"(e.g. \"control,copyright\") instead of all of them.")
+
+	var write bool
+	fs.BoolVar(&write,
+		"write",
+		false,
+		"With -regenerate, write the regenerated files instead of only showing\n"+
+			"the differences. Files which are not committed to git unmodified are\n"+
+			"not overwritten.")
+
+	arch := addArchiveFlags(fs)
+
+	var wrapAndSort string
 	fs.StringVar(&wrapAndSort,
 		"wrap-and-sort",
 		"at",
@@ -823,46 +356,95 @@ func execMake(args []string, usage func()) {
 		log.Fatalf("parse args: %v", err)
 	}
 
-	if fs.NArg() < 1 {
+	d, err := arch.distro()
+	if err != nil {
+		log.Fatalf("-distro: %v", err)
+	}
+	suite, err := d.ParseSuite(arch.suite)
+	if err != nil {
+		log.Fatalf("-suite: %v", err)
+	}
+
+	if regenerate {
+		dir := "."
+		if fs.NArg() > 0 {
+			dir = fs.Arg(0)
+		}
+		gen := &golangdeb.Generator{}
+		if gen.WrapAndSort, err = golangdeb.ParseWrapAndSort(wrapAndSort); err != nil {
+			log.Fatalf("%v, aborting.", err)
+		}
+		names, err := parseRegeneratedFiles(regenerateFiles)
+		if err != nil {
+			log.Fatalf("-regenerate-files: %v", err)
+		}
+		if err := regenerateTemplates(ctx, gen, dir, names, write, d, suite); err != nil {
+			log.Fatalf("Could not regenerate debian/: %v\n", err)
+		}
+		return
+	}
+
+	if fs.NArg() < 1 && fromDir == "" {
 		fs.Usage()
 		os.Exit(1)
 	}
 
+	if source != golangdeb.SourceVCS && source != golangdeb.SourceProxy {
+		log.Fatalf("-source=%q not recognized, aborting\n", source)
+	}
+
 	gitRevision = strings.TrimSpace(gitRevision)
 	gopkg := fs.Arg(0)
 
 	// Ensure the specified argument is a Go package import path.
-	rr, err := vcs.RepoRootForImportPath(gopkg, false)
-	if err != nil {
+	var rr *golangdeb.RepoRoot
+	if fromDir != "" {
+		if source != golangdeb.SourceVCS {
+			log.Fatalf("-from-dir cannot be combined with -source=%s, aborting\n", source)
+		}
+		source = golangdeb.SourceDir
+		if rr, err = golangdeb.LocalRepoRoot(ctx, fromDir, gopkg); err != nil {
+			log.Fatalf("-from-dir: %v", err)
+		}
+	} else if rr, err = golangdeb.ResolveRepoRoot(ctx, gopkg); err != nil {
 		log.Fatalf("Verifying arguments: %v — did you specify a Go package import path?", err)
 	}
-	if gopkg != rr.Root {
+	if gopkg == "" {
+		log.Printf("Packaging %q, the module in %s\n", rr.Root, fromDir)
+		gopkg = rr.Root
+	} else if gopkg != rr.Root {
 		log.Printf("Continuing with repository root %q instead of specified import path %q (repositories are the unit of packaging in Debian)", rr.Root, gopkg)
 		gopkg = rr.Root
 	}
 
 	// Set default source and binary package names.
 	// Note that debsrc may change depending on the actual package type.
-	debsrc := debianNameFromGopkg(gopkg, typeLibrary, customProgPkgName, allowUnknownHoster)
+	debsrc, err := golangdeb.DebianNameFromGopkg(gopkg, golangdeb.TypeLibrary, customProgPkgName, allowUnknownHoster)
+	if err != nil {
+		log.Fatalf("%v. See -help output for -allow_unknown_hoster\n", err)
+	}
 	debLib := debsrc + "-dev"
-	debProg := debianNameFromGopkg(gopkg, typeProgram, customProgPkgName, allowUnknownHoster)
+	debProg, err := golangdeb.DebianNameFromGopkg(gopkg, golangdeb.TypeProgram, customProgPkgName, allowUnknownHoster)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	var pkgType packageType
+	var pkgType golangdeb.PackageType
 
 	switch strings.TrimSpace(pkgTypeString) {
 	case "", "guess":
-		pkgType = typeGuess
+		pkgType = golangdeb.TypeGuess
 	case "library", "lib", "l", "dev":
-		pkgType = typeLibrary
+		pkgType = golangdeb.TypeLibrary
 	case "program", "prog", "p":
-		pkgType = typeProgram
+		pkgType = golangdeb.TypeProgram
 	case "library+program", "lib+prog", "l+p", "both":
 		// Example packages: golang-github-alecthomas-chroma,
 		// golang-github-tdewolff-minify, golang-github-spf13-viper
-		pkgType = typeLibraryProgram
+		pkgType = golangdeb.TypeLibraryProgram
 	case "program+library", "prog+lib", "p+l", "combined":
 		// Example package: hugo
-		pkgType = typeProgramLibrary
+		pkgType = golangdeb.TypeProgramLibrary
 	default:
 		log.Fatalf("-type=%q not recognized, aborting\n", pkgTypeString)
 	}
@@ -870,28 +452,28 @@ func execMake(args []string, usage func()) {
 	// Set the debian branch.
 	debBranch := "master"
 	if dep14 {
-		debBranch = "debian/sid"
+		debBranch = suite.Branch
 	}
 
-	switch strings.TrimSpace(wrapAndSort) {
-	case "a":
-		// Current default, also what "cme fix dpkg" generates
-		wrapAndSort = "a"
-	case "at", "ta":
-		// -t, --trailing-comma, preferred by Martina Ferrari
-		// and currently used in quite a few packages
-		wrapAndSort = "at"
-	case "ast", "ats", "sat", "sta", "tas", "tsa":
-		// -s, --short-indent too, proposed by Guillem Jover
-		wrapAndSort = "ast"
-	default:
-		log.Fatalf("%q is not a valid value for -wrap-and-sort, aborting.", wrapAndSort)
+	gen := &golangdeb.Generator{}
+	if gen.WrapAndSort, err = golangdeb.ParseWrapAndSort(wrapAndSort); err != nil {
+		log.Fatalf("%v, aborting.", err)
 	}
 
-	if pkgType != typeGuess {
-		debsrc = debianNameFromGopkg(gopkg, pkgType, customProgPkgName, allowUnknownHoster)
-		if _, err := os.Stat(debsrc); err == nil {
-			log.Fatalf("Output directory %q already exists, aborting\n", debsrc)
+	if vendorComponentDeps {
+		vendorDeps = true
+	}
+	if vendorDeps && pkgType != golangdeb.TypeGuess && pkgType != golangdeb.TypeProgram {
+		log.Fatalf("-vendor is only supported for programs (-type=program), aborting\n")
+	}
+
+	if pkgType != golangdeb.TypeGuess {
+		debsrc, err = golangdeb.DebianNameFromGopkg(gopkg, pkgType, customProgPkgName, allowUnknownHoster)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if _, err := os.Stat(debsrc); err == nil && !force {
+			log.Fatalf("Output directory %q already exists, aborting (use -force to overwrite)\n", debsrc)
 		}
 	}
 	// if pkgType == typeGuess, debsrc (also the output directory) will be
@@ -909,86 +491,198 @@ func execMake(args []string, usage func()) {
 	}
 
 	var (
-		eg             errgroup.Group
-		golangBinaries map[string]debianPackage // map[goImportPath]debianPackage
+		eg               errgroup.Group
+		golangBinaries   map[string]golangdeb.DebianPackage // map[goImportPath]debianPackage
+		unstableBinaries map[string]golangdeb.DebianPackage // only set if backports are needed
+		missingDeps      []string                           // dependencies of -for which are not packaged
 	)
 
 	// TODO: also check whether there already is a git repository on salsa.
+	eg.Go(func() error {
+		// Failed lookups result in TODOs, so only cancellation is fatal,
+		// see below.
+		gen.Metadata, _ = golangdeb.FetchMetadata(ctx, newGitHubClient(), gopkg)
+		return nil
+	})
+	eg.Go(func() error {
+		var err error
+		golangBinaries, err = arch.golangBinaries(ctx)
+		return err
+	})
 	eg.Go(func() error {
 		var err error
-		golangBinaries, err = getGolangBinaries()
+		unstableBinaries, err = arch.unstableBinaries(ctx)
 		return err
 	})
+	if neededBy != "" {
+		eg.Go(func() error {
+			var err error
+			if _, missingDeps, err = estimateDependencies(ctx, neededBy, "", "", arch); err != nil {
+				// Not fatal, the ITP just lacks the list of dependencies.
+				log.Printf("Could not determine the missing dependencies of %s: %v\n", neededBy, err)
+			}
+			return nil
+		})
+	}
 
-	u, err := makeUpstreamSourceTarball(gopkg, gitRevision, forcePrerelease)
+	// Everything is created in a staging directory in the current directory
+	// first, and only moved into place once complete, so that a failure does
+	// not leave a half-initialised repository behind which would make the
+	// next run fail.
+	staging, err := os.MkdirTemp(".", ".dh-make-golang-")
 	if err != nil {
-		log.Fatalf("Could not create a tarball of the upstream source: %v\n", err)
+		log.Fatalf("Could not create staging directory: %v\n", err)
+	}
+	var u *golangdeb.Upstream
+	fatalf := func(format string, v ...any) {
+		if u != nil {
+			u.RemoveTempFiles()
+		}
+		if entries, _ := os.ReadDir(filepath.Join(staging, movedAside)); len(entries) > 0 {
+			log.Printf("Keeping %q, which contains the previous output in %s/\n", staging, movedAside)
+		} else if keepFailed {
+			log.Printf("Keeping the partial output in %q\n", staging)
+		} else if err := os.RemoveAll(staging); err != nil {
+			log.Printf("Could not remove %q: %v\n", staging, err)
+		}
+		log.Fatalf(format, v...)
 	}
 
-	if pkgType == typeGuess {
-		if u.firstMain != "" {
-			log.Printf("Assuming you are packaging a program (because %q defines a main package), use -type to override\n", u.firstMain)
-			pkgType = typeProgram
-			debsrc = debianNameFromGopkg(gopkg, pkgType, customProgPkgName, allowUnknownHoster)
+	// Without the upstream history in the packaging repository, the
+	// upstream clone only needs its history to determine the version.
+	clone := golangdeb.CloneFull
+	if shallow {
+		clone = golangdeb.CloneTag
+	} else if !includeUpstreamHistory {
+		clone = golangdeb.CloneBlobless
+	}
+	u, err = golangdeb.MakeUpstreamSourceTarball(ctx, gopkg, gitRevision, source, fromDir, clone, forcePrerelease, vendorDeps, vendorComponentDeps)
+	if err != nil {
+		fatalf("Could not create a tarball of the upstream source: %v\n", err)
+	}
+	if includeUpstreamHistory && u.RepoRoot.VCS != "git" {
+		log.Printf("Not including the upstream history, %s is not a git repository\n", u.RepoRoot.Root)
+		includeUpstreamHistory = false
+	} else if includeUpstreamHistory && u.RepoRoot.Repo == "" {
+		log.Printf("Not including the upstream history, the checkout %s has no remote to use as upstream\n", fromDir)
+		includeUpstreamHistory = false
+	}
+
+	if pkgType == golangdeb.TypeGuess {
+		if u.FirstMain != "" {
+			log.Printf("Assuming you are packaging a program (because %q defines a main package), use -type to override\n", u.FirstMain)
+			pkgType = golangdeb.TypeProgram
+			debsrc, err = golangdeb.DebianNameFromGopkg(gopkg, pkgType, customProgPkgName, allowUnknownHoster)
+			if err != nil {
+				fatalf("%v", err)
+			}
 		} else {
-			pkgType = typeLibrary
+			pkgType = golangdeb.TypeLibrary
 		}
 	}
+	if vendorDeps && pkgType != golangdeb.TypeProgram {
+		fatalf("-vendor is only supported for programs, but %s looks like a library, aborting\n", gopkg)
+	}
 
-	if _, err := os.Stat(debsrc); err == nil {
-		log.Fatalf("Output directory %q already exists, aborting\n", debsrc)
+	if _, err := os.Stat(debsrc); err == nil && !force {
+		fatalf("Output directory %q already exists, aborting (use -force to overwrite)\n", debsrc)
 	}
 
 	if err := eg.Wait(); err != nil {
 		log.Printf("Could not check for existing Go packages in Debian: %v", err)
 	}
+	if err := ctx.Err(); err != nil {
+		fatalf("%v\n", err)
+	}
 
 	if debpkg, ok := golangBinaries[gopkg]; ok {
-		log.Printf("WARNING: A package called %q is already in Debian! See https://tracker.debian.org/pkg/%s\n",
-			debpkg.binary, debpkg.source)
+		log.Printf("WARNING: A package called %q is already in %s! See %s%s\n",
+			debpkg.Binary, d.Name, d.TrackerURL, debpkg.Source)
 	}
 
-	orig := fmt.Sprintf("%s_%s.orig.tar.%s", debsrc, u.version, u.compression)
+	orig := fmt.Sprintf("%s_%s.orig.tar.%s", debsrc, u.Version, u.Compression)
+	outputs := []string{orig}
 	log.Printf("Moving tempfile to %q\n", orig)
 	// We need to copy the file, merely renaming is not enough since the file
 	// might be on a different filesystem (/tmp often is a tmpfs).
-	if err := copyFile(u.tarPath, orig); err != nil {
-		log.Fatalf("Could not rename orig tarball from %q to %q: %v\n", u.tarPath, orig, err)
+	if err := copyFile(u.TarPath, filepath.Join(staging, orig)); err != nil {
+		fatalf("Could not rename orig tarball from %q to %q: %v\n", u.TarPath, orig, err)
 	}
-	if err := os.Remove(u.tarPath); err != nil {
-		log.Printf("Could not remove tempfile %q: %v\n", u.tarPath, err)
+	if err := os.Remove(u.TarPath); err != nil {
+		log.Printf("Could not remove tempfile %q: %v\n", u.TarPath, err)
 	}
 
-	debversion := u.version + "-1"
+	var packagedDeps []string
+	if u.VendorComponent {
+		packagedDeps, err = u.TarVendorComponent(ctx, golangBinaries)
+		if err != nil {
+			fatalf("Could not create the %s component tarball: %v\n", golangdeb.VendorComponent, err)
+		}
+	}
+	if u.ComponentPath != "" {
+		component := fmt.Sprintf("%s_%s.orig-%s.tar.xz", debsrc, u.Version, golangdeb.VendorComponent)
+		outputs = append(outputs, component)
+		log.Printf("Moving tempfile to %q\n", component)
+		if err := copyFile(u.ComponentPath, filepath.Join(staging, component)); err != nil {
+			fatalf("Could not rename component tarball from %q to %q: %v\n", u.ComponentPath, component, err)
+		}
+		if err := os.Remove(u.ComponentPath); err != nil {
+			log.Printf("Could not remove tempfile %q: %v\n", u.ComponentPath, err)
+		}
+	}
 
-	dir, err := createGitRepository(debsrc, gopkg, orig, u, includeUpstreamHistory, allowUnknownHoster, debBranch, pristineTar)
+	debversion := u.Version + "-" + d.Revision + suite.VersionSuffix
+
+	outputs = append(outputs, debsrc)
+	stagingDir, err := golangdeb.CreateGitRepository(ctx, filepath.Join(staging, debsrc), gopkg, filepath.Join(staging, orig), u,
+		includeUpstreamHistory, allowUnknownHoster, debBranch, pristineTar, d.VcsPushURL+debsrc+".git")
 	if err != nil {
-		log.Fatalf("Could not create git repository: %v\n", err)
+		fatalf("Could not create git repository: %v\n", err)
 	}
 
-	debdependencies := make([]string, 0, len(u.repoDeps))
-	for _, dep := range u.repoDeps {
-		if len(golangBinaries) == 0 {
-			// fall back to heuristic
-			debdependencies = append(debdependencies, debianNameFromGopkg(dep, typeLibrary, "", allowUnknownHoster)+"-dev")
-			continue
-		}
-		pkg, ok := golangBinaries[dep]
-		if !ok {
-			log.Printf("Build-Dependency %q is not yet available in Debian, or has not yet been converted to use XS-Go-Import-Path in debian/control", dep)
-			continue
-		}
-		debdependencies = append(debdependencies, pkg.binary)
+	repoDeps := u.RepoDeps
+	if u.Vendored {
+		// All other dependencies are in vendor/
+		repoDeps = nil
 	}
+	debdependencies, backports, err := golangdeb.ResolveDependencies(repoDeps, golangBinaries, unstableBinaries, suite, allowUnknownHoster)
+	if err != nil {
+		fatalf("Could not resolve build dependencies: %v\n", err)
+	}
+	debdependencies = append(debdependencies, packagedDeps...)
 
-	if err := writeTemplates(dir, gopkg, debsrc, debLib, debProg, debversion,
-		pkgType, debdependencies, u, dep14, pristineTar); err != nil {
-		log.Fatalf("Could not create debian/ from templates: %v\n", err)
+	if err := gen.WriteTemplates(stagingDir, gopkg, debsrc, debLib, debProg, debversion,
+		pkgType, debdependencies, u, d, suite, dep14, pristineTar); err != nil {
+		fatalf("Could not create debian/ from templates: %v\n", err)
 	}
 
-	itpname, err := writeITP(gopkg, debsrc, debversion)
+	itpname, err := writeITP(gen.Metadata, staging, gopkg, debsrc, debversion, d, itpReasoning{
+		neededBy:   neededBy,
+		missing:    otherMissingDependencies(missingDeps, neededBy, gopkg, u.RepoRoot.Root),
+		lastCommit: u.LastCommit,
+		latestTag:  u.Tag,
+		tagDate:    u.TagDate,
+	})
 	if err != nil {
-		log.Fatalf("Could not write ITP email: %v\n", err)
+		fatalf("Could not write ITP email: %v\n", err)
+	}
+	itpname = filepath.Base(itpname)
+	outputs = append(outputs, itpname)
+
+	if err := moveIntoPlace(staging, outputs, force); err != nil {
+		fatalf("Could not move the packaging into place: %v\n", err)
+	}
+	dir, err := filepath.Abs(debsrc)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var report *buildReport
+	if trial {
+		report, err = trialBuild(ctx, dir, gopkg, gocodeDir)
+		if err != nil {
+			log.Printf("Could not run trial build: %v\n", err)
+		}
 	}
 
 	log.Println("Done!")
@@ -997,20 +691,32 @@ func execMake(args []string, usage func()) {
 	fmt.Printf("Packaging successfully created in %s\n", dir)
 	fmt.Printf("    Source: %s\n", debsrc)
 	switch pkgType {
-	case typeLibrary:
+	case golangdeb.TypeLibrary:
 		fmt.Printf("    Binary: %s\n", debLib)
-	case typeProgram:
+	case golangdeb.TypeProgram:
 		fmt.Printf("    Binary: %s\n", debProg)
-	case typeLibraryProgram:
+	case golangdeb.TypeLibraryProgram:
 		fmt.Printf("    Binary: %s\n", debLib)
 		fmt.Printf("    Binary: %s\n", debProg)
-	case typeProgramLibrary:
+	case golangdeb.TypeProgramLibrary:
 		fmt.Printf("    Binary: %s\n", debProg)
 		fmt.Printf("    Binary: %s\n", debLib)
 	}
 	fmt.Printf("\n")
+	if report != nil {
+		printBuildReport(report)
+	}
+	if len(backports) > 0 {
+		fmt.Printf("The following build dependencies need to be backported to %s first:\n", suite.Name)
+		for _, source := range backports {
+			fmt.Printf("    %s\n", source)
+		}
+		fmt.Printf("\n")
+	}
 	fmt.Printf("Resolve all TODOs in %s, then email it out:\n", itpname)
 	fmt.Printf("    /usr/sbin/sendmail -t < %s\n", itpname)
+	fmt.Printf("or check WNPP, send it and record the bug number in debian/changelog using:\n")
+	fmt.Printf("    %s itp -send=sendmail %s\n", os.Args[0], dir)
 	fmt.Printf("\n")
 	fmt.Printf("Resolve all the TODOs in debian/, find them using:\n")
 	fmt.Printf("    grep -r TODO debian\n")
@@ -1019,18 +725,20 @@ func execMake(args []string, usage func()) {
 	fmt.Printf("    git add debian && git commit -S -m 'Initial packaging'\n")
 	fmt.Printf("    gbp buildpackage --git-pbuilder\n")
 	fmt.Printf("\n")
-	fmt.Printf("To create the packaging git repository on salsa, use:\n")
-	fmt.Printf("    dh-make-golang create-salsa-project %s\n", debsrc)
-	fmt.Printf("\n")
-	fmt.Printf("Once you are happy with your packaging, push it to salsa using:\n")
+	if d.VcsPushURL == golangdeb.DebianDistro.VcsPushURL {
+		fmt.Printf("To create the packaging git repository on salsa, use:\n")
+		fmt.Printf("    dh-make-golang create-salsa-project %s\n", debsrc)
+		fmt.Printf("\n")
+	}
+	fmt.Printf("Once you are happy with your packaging, push it to %s using:\n", d.VcsPushURL+debsrc+".git")
 	fmt.Printf("    git push origin %s\n", debBranch)
 	fmt.Printf("    gbp push\n")
 	fmt.Printf("\n")
 
 	if includeUpstreamHistory {
-		fmt.Printf("The upstream git history is being tracked with the remote named %q.\n", u.remote)
+		fmt.Printf("The upstream git history is being tracked with the remote named %q.\n", u.Remote)
 		fmt.Printf("To upgrade to the latest upstream version, you may use something like:\n")
-		fmt.Printf("    git fetch %-15v # note the latest tag or commit-ish\n", u.remote)
+		fmt.Printf("    git fetch %-15v # note the latest tag or commit-ish\n", u.Remote)
 		fmt.Printf("    uscan --report-status     # check we get the same tag or commit-ish\n")
 		fmt.Printf("    gbp import-orig --sign-tags --uscan --upstream-vcs-tag=<commit-ish>\n")
 		fmt.Printf("\n")
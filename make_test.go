@@ -1,98 +1,91 @@
 package main
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
-
-	"golang.org/x/tools/go/vcs"
 )
 
-var shortName = []struct {
-	in  string
-	out string
-}{
-	{"", "TODO"},
-	{"d", "TODO"},
-	{"d--", "TODO"},
-}
+func TestMoveIntoPlace(t *testing.T) {
+	t.Chdir(t.TempDir())
 
-func TestAcceptInput(t *testing.T) {
-	for _, tt := range shortName {
-		in := normalizeDebianPackageName(tt.in)
-		if in != tt.out {
-			t.Errorf("userInput(%q) => %q, want %q", tt.in, in, tt.out)
+	stage := func() string {
+		t.Helper()
+		staging, err := os.MkdirTemp(".", ".dh-make-golang-")
+		if err != nil {
+			t.Fatal(err)
 		}
-	}
-}
-
-var miscName = []struct {
-	in  string
-	out string
-}{
-	{"dh-make-golang", "dh-make-golang"},
-	{"DH-make-golang", "dh-make-golang"},
-	{"dh_make_golang", "dh-make-golang"},
-	{"dh_make*go&3*@@", "dh-makego3"},
-	{"7h_make*go&3*@@", "7h-makego3"},
-	{"7h_make*go&3*.@", "7h-makego3."},
-	{"7h_make*go+3*.@", "7h-makego+3."},
-}
-
-func TestNormalizeDebianPackageName(t *testing.T) {
-	for _, tt := range miscName {
-		s := normalizeDebianPackageName(tt.in)
-		if s != tt.out {
-			t.Errorf("normalizeDebianPackageName(%q) => %q, want %q", tt.in, s, tt.out)
+		if err := os.MkdirAll(filepath.Join(staging, "golang-foo", "debian"), 0755); err != nil {
+			t.Fatal(err)
 		}
+		for _, name := range []string{"golang-foo_1.0.orig.tar.gz", "itp-golang-foo.txt"} {
+			if err := os.WriteFile(filepath.Join(staging, name), []byte(staging), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return staging
 	}
-}
-
-var nameFromGoPkg = []struct {
-	in     string
-	t      packageType
-	custom string
-	out    string
-}{
-	{"github.com/Debian/dh-make-golang", typeProgram, "", "dh-make-golang"},
-	{"github.com/Debian/DH-make-golang", typeGuess, "", "golang-github-debian-dh-make-golang"},
-	{"github.com/Debian/dh_make_golang", typeGuess, "", "golang-github-debian-dh-make-golang"},
-	{"github.com/sean-/seed", typeGuess, "", "golang-github-sean--seed"},
-	{"git.sr.ht/~sircmpwn/getopt", typeGuess, "", "golang-sourcehut-sircmpwn-getopt"},
-	{"golang.org/x/term", typeLibrary, "", "golang-golang-x-term"},
-	{"github.com/cli/cli", typeProgram, "gh", "gh"},
-}
+	outputs := []string{"golang-foo_1.0.orig.tar.gz", "golang-foo", "itp-golang-foo.txt"}
 
-func TestDebianNameFromGopkg(t *testing.T) {
-	for _, tt := range nameFromGoPkg {
-		s := debianNameFromGopkg(tt.in, tt.t, tt.custom, false)
-		if s != tt.out {
-			t.Errorf("debianNameFromGopkg(%q) => %q, want %q", tt.in, s, tt.out)
+	staging := stage()
+	if err := moveIntoPlace(staging, outputs, false); err != nil {
+		t.Fatalf("moveIntoPlace: %v", err)
+	}
+	for _, name := range append(outputs, filepath.Join("golang-foo", "debian")) {
+		if _, err := os.Stat(name); err != nil {
+			t.Errorf("moveIntoPlace did not create %s: %v", name, err)
 		}
 	}
-}
+	if _, err := os.Stat(staging); !os.IsNotExist(err) {
+		t.Errorf("moveIntoPlace did not remove the staging directory %s", staging)
+	}
 
-var tarballUrl = []struct {
-	repoRoot    string
-	tag         string
-	compression string
-	url         string
-}{
-	{"https://github.com/Debian/dh-make-golang", "0.6.0", "gz", "https://github.com/Debian/dh-make-golang/archive/0.6.0.tar.gz"},
-	{"https://github.com/Debian/dh-make-golang.git", "0.6.0", "gz", "https://github.com/Debian/dh-make-golang/archive/0.6.0.tar.gz"},
-	{"https://gitlab.com/gitlab-org/labkit", "1.3.0", "gz", "https://gitlab.com/gitlab-org/labkit/-/archive/1.3.0/labkit-1.3.0.tar.gz"},
-	{"https://git.sr.ht/~sircmpwn/getopt", "v1.0.0", "gz", "https://git.sr.ht/~sircmpwn/getopt/archive/v1.0.0.tar.gz"},
-}
+	// Without force, the existing output directory is left alone.
+	staging = stage()
+	if err := moveIntoPlace(staging, outputs, false); err == nil {
+		t.Fatalf("moveIntoPlace onto an existing directory: got no error")
+	}
+	if b, _ := os.ReadFile("itp-golang-foo.txt"); string(b) == staging {
+		t.Errorf("moveIntoPlace replaced files despite failing")
+	}
 
-func TestUpstreamTarmballUrl(t *testing.T) {
-	for _, tt := range tarballUrl {
-		u := upstream{
-			rr:          &vcs.RepoRoot{Repo: tt.repoRoot},
-			compression: tt.compression,
-			tag:         tt.tag,
-		}
+	// With force, it is replaced.
+	if err := os.WriteFile(filepath.Join("golang-foo", "stale"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := moveIntoPlace(staging, outputs, true); err != nil {
+		t.Fatalf("moveIntoPlace with force: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join("golang-foo", "stale")); !os.IsNotExist(err) {
+		t.Errorf("moveIntoPlace with force kept the old output directory")
+	}
+	if b, _ := os.ReadFile("itp-golang-foo.txt"); string(b) != staging {
+		t.Errorf("moveIntoPlace with force: itp-golang-foo.txt not replaced")
+	}
+	if _, err := os.Stat(staging); !os.IsNotExist(err) {
+		t.Errorf("moveIntoPlace did not remove the staging directory %s", staging)
+	}
 
-		url, _ := u.tarballUrl()
-		if url != tt.url {
-			t.Errorf("TestUpstreamTarmballUrl(%q) => %q, want %q", tt.repoRoot, url, tt.url)
+	// If a rename fails, the previous output is moved back.
+	staging = stage()
+	if err := os.Remove(filepath.Join(staging, "itp-golang-foo.txt")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join("golang-foo", "previous"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := moveIntoPlace(staging, outputs, true); err == nil {
+		t.Fatalf("moveIntoPlace with a missing output: got no error")
+	}
+	if _, err := os.Stat(filepath.Join("golang-foo", "previous")); err != nil {
+		t.Errorf("moveIntoPlace did not restore the previous output directory: %v", err)
+	}
+	for _, name := range []string{"golang-foo_1.0.orig.tar.gz", "itp-golang-foo.txt"} {
+		if b, _ := os.ReadFile(name); string(b) == staging {
+			t.Errorf("moveIntoPlace did not restore the previous %s", name)
 		}
 	}
+	if _, err := os.Stat(filepath.Join(staging, movedAside)); !os.IsNotExist(err) {
+		t.Errorf("moveIntoPlace left %s behind in the staging directory", movedAside)
+	}
 }
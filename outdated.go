@@ -0,0 +1,307 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"regexp"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/Debian/dh-make-golang/pkg/golangdeb"
+	"golang.org/x/sync/errgroup"
+	"pault.ag/go/debian/version"
+)
+
+// repackSuffixRegexp matches the suffix added to the upstream version of
+// repacked orig tarballs, e.g. “+ds1” or “+dfsg”.
+var repackSuffixRegexp = regexp.MustCompile(`[+~](ds|dfsg)\d*$`)
+
+const (
+	statusOutdated = "outdated"
+	statusUpToDate = "up-to-date"
+	statusNoTags   = "no-tags"
+	statusError    = "error"
+)
+
+// outdatedResult describes how a Debian source package relates to the
+// latest upstream release.
+type outdatedResult struct {
+	Source     string `json:"source"`
+	ImportPath string `json:"import_path"`
+	Debian     string `json:"debian_version,omitempty"`
+	Tag        string `json:"upstream_tag,omitempty"`
+	Upstream   string `json:"upstream_version,omitempty"`
+	Status     string `json:"status"`
+	Error      string `json:"error,omitempty"`
+}
+
+// getDebianVersion returns the version of the given source package in
+// the given suite of d, according to its ftp-master API.
+func getDebianVersion(ctx context.Context, d *golangdeb.DistroProfile, suite, source string) (string, error) {
+	if d.DscInSuiteURL == "" {
+		return "", fmt.Errorf("%s has no dsc_in_suite API, use e.g. -archive=apt or -archive=mirror", d.Name)
+	}
+	url := d.DscInSuiteURL + suite + "/" + source
+	resp, err := golangdeb.HTTPGet(ctx, url)
+	if err != nil {
+		return "", fmt.Errorf("getting %q: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if got, want := resp.StatusCode, http.StatusOK; got != want {
+		return "", fmt.Errorf("unexpected HTTP status code: got %d, want %d", got, want)
+	}
+	var dscs []struct {
+		Version string `json:"version"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&dscs); err != nil {
+		return "", fmt.Errorf("decode: %w", err)
+	}
+	var latest string
+	for _, dsc := range dscs {
+		if latest == "" || golangdeb.CompareVersions(dsc.Version, latest) > 0 {
+			latest = dsc.Version
+		}
+	}
+	if latest == "" {
+		return "", fmt.Errorf("%s not found in %s", source, suite)
+	}
+	return latest, nil
+}
+
+// debianUpstreamVersion returns the upstream part of a Debian version,
+// without epoch, Debian revision and repack suffix.
+func debianUpstreamVersion(debversion string) string {
+	v, err := version.Parse(debversion)
+	if err != nil {
+		return debversion
+	}
+	return repackSuffixRegexp.ReplaceAllString(v.Version, "")
+}
+
+// latestUpstreamTag determines the latest version tag of the git
+// repository at repoURL, with the same semantics as pkgVersionFromGit.
+// Only the commits and tags are fetched, using a treeless bare clone.
+func latestUpstreamTag(ctx context.Context, repoURL string) (string, error) {
+	gitdir, err := os.MkdirTemp("", "dh-make-golang")
+	if err != nil {
+		return "", fmt.Errorf("create temp dir: %w", err)
+	}
+	defer os.RemoveAll(gitdir)
+
+	cmd := exec.CommandContext(ctx, "git", "clone", "--quiet", "--bare", "--filter=tree:0", repoURL, gitdir)
+	cmd.Env = append([]string{"GIT_TERMINAL_PROMPT=0"}, golangdeb.PassthroughEnv()...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return "", fmt.Errorf("git clone: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	tag, err := golangdeb.LatestGitTag(ctx, gitdir)
+	if err != nil {
+		// No tags at all is not an error.
+		return "", nil
+	}
+	return tag, nil
+}
+
+// checkOutdated compares the version debversion of source with the latest
+// upstream release of importPath. If debversion is empty (the ftp-master API
+// does not list versions), it is looked up in the given suite of d.
+func checkOutdated(ctx context.Context, d *golangdeb.DistroProfile, suite, source, importPath, debversion string) outdatedResult {
+	res := outdatedResult{Source: source, ImportPath: importPath}
+	fail := func(err error) outdatedResult {
+		res.Status = statusError
+		res.Error = err.Error()
+		return res
+	}
+
+	if debversion == "" {
+		var err error
+		if debversion, err = getDebianVersion(ctx, d, suite, source); err != nil {
+			return fail(fmt.Errorf("get Debian version: %w", err))
+		}
+	}
+	res.Debian = debversion
+
+	rr, err := golangdeb.ResolveRepoRoot(ctx, importPath)
+	if err != nil {
+		return fail(fmt.Errorf("get repo root: %w", err))
+	}
+	if rr.VCS != "git" {
+		return fail(fmt.Errorf("unsupported VCS %q", rr.VCS))
+	}
+
+	res.Tag, err = latestUpstreamTag(ctx, rr.Repo)
+	if err != nil {
+		return fail(err)
+	}
+	if res.Tag == "" {
+		res.Status = statusNoTags
+		return res
+	}
+	res.Upstream = golangdeb.UpstreamVersionFromTag(res.Tag)
+
+	if golangdeb.CompareVersions(res.Upstream, debianUpstreamVersion(debversion)) > 0 {
+		res.Status = statusOutdated
+	} else {
+		res.Status = statusUpToDate
+	}
+	return res
+}
+
+// sourceImportPaths maps source package names to the shortest import path
+// of their -dev packages (i.e. the repository root for most packages).
+func sourceImportPaths(golangBinaries map[string]golangdeb.DebianPackage) map[string]string {
+	paths := make(map[string]string)
+	for importPath, pkg := range golangBinaries {
+		prev, ok := paths[pkg.Source]
+		if !ok || len(importPath) < len(prev) || (len(importPath) == len(prev) && importPath < prev) {
+			paths[pkg.Source] = importPath
+		}
+	}
+	return paths
+}
+
+func printOutdatedTable(results []outdatedResult, all bool) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(w, "SOURCE\tDEBIAN\tUPSTREAM\tTAG\tSTATUS\n")
+	for _, res := range results {
+		if !all && res.Status != statusOutdated && res.Status != statusError {
+			continue
+		}
+		status := res.Status
+		if res.Error != "" {
+			status += ": " + res.Error
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", res.Source, res.Debian, res.Upstream, res.Tag, status)
+	}
+	w.Flush()
+}
+
+func execOutdated(ctx context.Context, args []string) {
+	fs := flag.NewFlagSet("outdated", flag.ExitOnError)
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s outdated [flags] [<source-package>...]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Checks which of the given Go source packages (default: all packages\n"+
+			"with XS-Go-Import-Path in Debian) have newer upstream releases.\n")
+		fmt.Fprintf(os.Stderr, "Example: %s outdated -filter 'github.com/spf13/'\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "\n")
+		fmt.Fprintf(os.Stderr, "Flags:\n")
+		fs.PrintDefaults()
+	}
+
+	var filter string
+	fs.StringVar(&filter,
+		"filter",
+		"",
+		"Only check packages whose import path matches this regexp\n"+
+			"(Go regexp syntax, as used by the search command).")
+
+	var parallel int
+	fs.IntVar(&parallel,
+		"parallel",
+		8,
+		"Number of packages to check concurrently.")
+
+	var jsonOutput bool
+	fs.BoolVar(&jsonOutput,
+		"json",
+		false,
+		"Print the results as a JSON array instead of a table.")
+
+	var all bool
+	fs.BoolVar(&all,
+		"all",
+		false,
+		"Also list packages which are up to date or have no release tags.")
+
+	arch := addArchiveFlags(fs)
+
+	if err := fs.Parse(args); err != nil {
+		log.Fatalf("parse args: %s", err)
+	}
+	if parallel < 1 {
+		log.Fatalf("-parallel must be at least 1")
+	}
+
+	var pattern *regexp.Regexp
+	if filter != "" {
+		var err error
+		pattern, err = regexp.Compile(filter)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	d, err := arch.distro()
+	if err != nil {
+		log.Fatalf("-distro: %s", err)
+	}
+	suite, err := d.ParseSuite(arch.suite)
+	if err != nil {
+		log.Fatalf("-suite: %s", err)
+	}
+
+	golangBinaries, err := arch.golangBinaries(ctx)
+	if err != nil {
+		log.Fatalf("get golang debian packages: %s", err)
+	}
+	paths := sourceImportPaths(golangBinaries)
+	versions := make(map[string]string)
+	for _, pkg := range golangBinaries {
+		versions[pkg.Source] = pkg.Version
+	}
+
+	sources := fs.Args()
+	if len(sources) == 0 {
+		for source := range paths {
+			sources = append(sources, source)
+		}
+	}
+	slices.Sort(sources)
+
+	var todo []outdatedResult
+	for _, source := range sources {
+		importPath, ok := paths[source]
+		if !ok {
+			log.Printf("%s has no XS-Go-Import-Path in Debian, skipping", source)
+			continue
+		}
+		if pattern != nil && !pattern.MatchString(importPath) {
+			continue
+		}
+		todo = append(todo, outdatedResult{Source: source, ImportPath: importPath})
+	}
+	log.Printf("Checking %d packages for newer upstream releases", len(todo))
+
+	results := make([]outdatedResult, len(todo))
+	var eg errgroup.Group
+	eg.SetLimit(parallel)
+	for i, pkg := range todo {
+		eg.Go(func() error {
+			results[i] = checkOutdated(ctx, d, suite.Name, pkg.Source, pkg.ImportPath, versions[pkg.Source])
+			return nil
+		})
+	}
+	eg.Wait()
+
+	if jsonOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if !all {
+			results = slices.DeleteFunc(results, func(res outdatedResult) bool {
+				return res.Status != statusOutdated && res.Status != statusError
+			})
+		}
+		if err := enc.Encode(results); err != nil {
+			log.Fatalf("encode: %s", err)
+		}
+		return
+	}
+	printOutdatedTable(results, all)
+}
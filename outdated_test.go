@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/Debian/dh-make-golang/pkg/golangdeb"
+)
+
+var debianUpstreamVersions = []struct {
+	in   string
+	want string
+}{
+	{"1.2.3-1", "1.2.3"},
+	{"1:1.2.3-2", "1.2.3"},
+	{"1.2.3+ds1-1", "1.2.3"},
+	{"1.2.3+dfsg-1~bpo12+1", "1.2.3"},
+	{"0.0~git20200101.abcdef0-1", "0.0~git20200101.abcdef0"},
+}
+
+func TestDebianUpstreamVersion(t *testing.T) {
+	for _, tt := range debianUpstreamVersions {
+		if got := debianUpstreamVersion(tt.in); got != tt.want {
+			t.Errorf("debianUpstreamVersion(%q) => %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSourceImportPaths(t *testing.T) {
+	got := sourceImportPaths(map[string]golangdeb.DebianPackage{
+		"github.com/example/foo":     {Binary: "golang-github-example-foo-dev", Source: "golang-github-example-foo"},
+		"github.com/example/foo/v2":  {Binary: "golang-github-example-foo-dev", Source: "golang-github-example-foo"},
+		"github.com/example/foo/bar": {Binary: "golang-github-example-foo-dev", Source: "golang-github-example-foo"},
+		"example.org/baz":            {Binary: "golang-example-baz-dev", Source: "golang-example-baz"},
+	})
+	want := map[string]string{
+		"golang-github-example-foo": "github.com/example/foo",
+		"golang-example-baz":        "example.org/baz",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("sourceImportPaths() => %v, want %v", got, want)
+	}
+}
+
+func TestLatestUpstreamTag(t *testing.T) {
+	tempdir := t.TempDir()
+	gitCmdOrFatal(t, tempdir, "init", "--initial-branch=master")
+	gitCmdOrFatal(t, tempdir, "config", "user.email", "unittest@example.com")
+	gitCmdOrFatal(t, tempdir, "config", "user.name", "Unit Test")
+	gitCmdOrFatal(t, tempdir, "commit", "--allow-empty", "-m", "initial commit")
+
+	got, err := latestUpstreamTag(t.Context(), tempdir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "" {
+		t.Errorf("latestUpstreamTag() without tags => %q, want \"\"", got)
+	}
+
+	gitCmdOrFatal(t, tempdir, "tag", "v1.0.0")
+	gitCmdOrFatal(t, tempdir, "commit", "--allow-empty", "-m", "second commit")
+	gitCmdOrFatal(t, tempdir, "tag", "-a", "v1.1.0", "-m", "release v1.1.0")
+	gitCmdOrFatal(t, tempdir, "tag", "submodule/v2.0.0")
+	gitCmdOrFatal(t, tempdir, "commit", "--allow-empty", "-m", "third commit")
+
+	got, err = latestUpstreamTag(t.Context(), tempdir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "v1.1.0"; got != want {
+		t.Errorf("latestUpstreamTag() => %q, want %q", got, want)
+	}
+}
+
+func TestGetDebianVersion(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/dsc_in_suite/sid/golang-foo" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, `[{"version": "1.0.0-1"}, {"version": "1.2.0-1"}, {"version": "1.1.0-2"}]`)
+	}))
+	defer ts.Close()
+
+	d := golangdeb.DebianDistro
+	d.DscInSuiteURL = ts.URL + "/dsc_in_suite/"
+	if got, err := getDebianVersion(t.Context(), &d, "sid", "golang-foo"); err != nil || got != "1.2.0-1" {
+		t.Errorf("getDebianVersion() = %q, %v, want 1.2.0-1", got, err)
+	}
+
+	// Distributions without dsc_in_suite API need an index with versions.
+	res := checkOutdated(t.Context(), &golangdeb.UbuntuDistro, "devel", "golang-foo", "example.invalid/foo", "")
+	if res.Status != statusError || res.Debian != "" {
+		t.Errorf("checkOutdated() without version => %+v, want an error", res)
+	}
+}
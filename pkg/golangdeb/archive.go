@@ -0,0 +1,325 @@
+package golangdeb
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"pault.ag/go/debian/control"
+)
+
+// Archive is a source of information about the Go packages available in
+// Debian (or a subset of it, like a suite).
+type Archive interface {
+	// GolangBinaries returns the -dev packages indexed by Go import path.
+	GolangBinaries(ctx context.Context) (map[string]DebianPackage, error)
+}
+
+// ftpMasterArchive queries the ftp-master API, which covers all suites.
+type ftpMasterArchive struct {
+	url string
+}
+
+func (a ftpMasterArchive) GolangBinaries(ctx context.Context) (map[string]DebianPackage, error) {
+	return GetGolangBinaries(ctx, WithGolangBinariesURL(a.url))
+}
+
+// indexArchive reads APT Packages and Sources indices, e.g. from
+// /var/lib/apt/lists/ or from a mirror.
+type indexArchive struct {
+	locations []string // local glob patterns or http(s) URLs, plain or .gz
+}
+
+// AptListsDir is where APT stores the downloaded indices.
+const AptListsDir = "/var/lib/apt/lists"
+
+// NewArchive returns the archive of the distribution d selected by the
+// -archive and -suite flags. source is one of:
+//   - "ftp-master": the ftp-master API (all suites)
+//   - "apt": the indices in /var/lib/apt/lists/
+//   - "mirror": the Sources indices on the mirror of the distribution
+//   - an http(s) URL of a mirror, or of a Packages or Sources index
+//   - the path (or glob pattern) of local Packages or Sources indices
+//
+// An empty source selects the default of the distribution, or "mirror"
+// if a suite is given. For suites like bookworm-backports, the indices of
+// all suites which can satisfy dependencies (bookworm and
+// bookworm-backports) are used.
+func NewArchive(d *DistroProfile, source, suite string) (Archive, error) {
+	var suites []string
+	if suite != "" {
+		ts, err := d.ParseSuite(suite)
+		if err != nil {
+			return nil, err
+		}
+		suites = ts.LookupSuites
+	}
+	if source == "" {
+		source = d.Archive
+		if suite != "" {
+			source = "mirror"
+		}
+	}
+	switch {
+	case source == "ftp-master":
+		if d.MetadataURL == "" {
+			return nil, fmt.Errorf("%s has no ftp-master API, use e.g. -archive=apt or -archive=mirror", d.Name)
+		}
+		if suite != "" {
+			return nil, fmt.Errorf("the ftp-master API cannot be restricted to a suite, use e.g. -archive=apt or -archive=mirror")
+		}
+		return ftpMasterArchive{url: d.MetadataURL}, nil
+	case source == "apt":
+		if suite == "" {
+			suites = []string{"*"}
+		}
+		var patterns []string
+		for _, s := range suites {
+			for _, name := range d.suiteNames(s) {
+				patterns = append(patterns,
+					filepath.Join(AptListsDir, "*_dists_"+name+"_*_Packages"),
+					filepath.Join(AptListsDir, "*_dists_"+name+"_*_Sources"))
+			}
+		}
+		return indexArchive{locations: patterns}, nil
+	case source == "mirror" || strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://"):
+		if source == "mirror" {
+			source = d.Mirror
+		}
+		base := filepath.Base(source)
+		if strings.HasPrefix(base, "Packages") || strings.HasPrefix(base, "Sources") {
+			return indexArchive{locations: []string{source}}, nil
+		}
+		if suite == "" {
+			suites = []string{d.DevelSuite}
+		}
+		var urls []string
+		for _, s := range suites {
+			for _, component := range d.Components {
+				urls = append(urls, strings.TrimSuffix(source, "/")+"/dists/"+s+"/"+component+"/source/Sources.gz")
+			}
+		}
+		return indexArchive{locations: urls}, nil
+	default:
+		return indexArchive{locations: []string{source}}, nil
+	}
+}
+
+// OpenIndex opens the index at the given location, decompressing it if
+// necessary.
+func OpenIndex(ctx context.Context, location string) (io.ReadCloser, error) {
+	var rc io.ReadCloser
+	if strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://") {
+		resp, err := HTTPGet(ctx, location)
+		if err != nil {
+			return nil, fmt.Errorf("getting %q: %w", location, err)
+		}
+		if got, want := resp.StatusCode, http.StatusOK; got != want {
+			resp.Body.Close()
+			return nil, fmt.Errorf("getting %q: unexpected HTTP status code: got %d, want %d", location, got, want)
+		}
+		rc = resp.Body
+	} else {
+		f, err := os.Open(location)
+		if err != nil {
+			return nil, err
+		}
+		rc = f
+	}
+	if !strings.HasSuffix(location, ".gz") {
+		return rc, nil
+	}
+	defer rc.Close()
+	gz, err := gzip.NewReader(rc)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", location, err)
+	}
+	// Read everything so that the underlying file or connection can be closed.
+	b, err := io.ReadAll(gz)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", location, err)
+	}
+	return io.NopCloser(bytes.NewReader(b)), nil
+}
+
+// goImportPaths returns the import paths of a Packages or Sources index
+// paragraph.
+func goImportPaths(p *control.Paragraph) []string {
+	value := p.Values["Go-Import-Path"]
+	if value == "" {
+		value = p.Values["XS-Go-Import-Path"]
+	}
+	return SplitList(value)
+}
+
+// parseGolangBinaries adds the Go packages of a Packages or Sources index
+// to golangBinaries.
+func parseGolangBinaries(r io.Reader, golangBinaries map[string]DebianPackage) error {
+	pr, err := control.NewParagraphReader(r, nil)
+	if err != nil {
+		return err
+	}
+	for {
+		p, err := pr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		importPaths := goImportPaths(p)
+		if len(importPaths) == 0 {
+			continue
+		}
+		var pkg DebianPackage
+		if binaries, ok := p.Values["Binary"]; ok {
+			// Sources index: use the first -dev binary package.
+			pkg.Source = strings.TrimSpace(p.Values["Package"])
+			pkg.Version = strings.TrimSpace(p.Values["Version"])
+			for _, bin := range SplitList(binaries) {
+				if strings.HasSuffix(bin, "-dev") {
+					pkg.Binary = bin
+					break
+				}
+			}
+		} else {
+			// Packages index
+			idx := control.BinaryIndex{
+				Package: strings.TrimSpace(p.Values["Package"]),
+				Source:  strings.TrimSpace(p.Values["Source"]),
+			}
+			pkg.Binary = idx.Package
+			pkg.Source = idx.SourcePackage()
+			// The source version differs from the binary one in
+			// binNMUs, and is then given in the Source field.
+			pkg.Version = strings.TrimSpace(p.Values["Version"])
+			if _, v, ok := strings.Cut(idx.Source, " ("); ok {
+				pkg.Version = strings.TrimSuffix(v, ")")
+			}
+		}
+		if !strings.HasSuffix(pkg.Binary, "-dev") {
+			continue // skip programs, -dbgsym packages etc.
+		}
+		for _, importPath := range importPaths {
+			golangBinaries[importPath] = pkg
+		}
+	}
+}
+
+func (a indexArchive) GolangBinaries(ctx context.Context) (map[string]DebianPackage, error) {
+	var locations []string
+	for _, location := range a.locations {
+		if strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://") {
+			locations = append(locations, location)
+			continue
+		}
+		matches, err := filepath.Glob(location)
+		if err != nil {
+			return nil, err
+		}
+		locations = append(locations, matches...)
+	}
+	if len(locations) == 0 {
+		return nil, fmt.Errorf("no Packages or Sources index found at %q", a.locations)
+	}
+
+	golangBinaries := make(map[string]DebianPackage)
+	for _, location := range locations {
+		rc, err := OpenIndex(ctx, location)
+		if err != nil {
+			return nil, err
+		}
+		err = parseGolangBinaries(rc, golangBinaries)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", location, err)
+		}
+	}
+	return golangBinaries, nil
+}
+
+const (
+	golangBinariesURL = "https://api.ftp-master.debian.org/binary/by_metadata/Go-Import-Path"
+)
+
+// DebianPackage names the binary and source package providing a Go package.
+type DebianPackage struct {
+	Binary  string
+	Source  string
+	Version string // version of the source package, empty if unknown (the ftp-master API does not provide it)
+}
+
+type ftpMasterApiResult struct {
+	Binary        string `json:"binary"`
+	MetadataValue string `json:"metadata_value"`
+	Source        string `json:"source"`
+}
+
+type getGolangBinariesConfig struct {
+	url string
+}
+
+// GolangBinariesOption configures GetGolangBinaries.
+type GolangBinariesOption func(cfg *getGolangBinariesConfig)
+
+// WithGolangBinariesURL queries the ftp-master API at url instead of the
+// default.
+func WithGolangBinariesURL(url string) GolangBinariesOption {
+	return func(cfg *getGolangBinariesConfig) {
+		cfg.url = url
+	}
+}
+
+// GetGolangBinaries returns the Go packages in Debian, indexed by Go import
+// path, according to the ftp-master API.
+func GetGolangBinaries(ctx context.Context, opts ...GolangBinariesOption) (map[string]DebianPackage, error) {
+	cfg := &getGolangBinariesConfig{url: golangBinariesURL}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	golangBinaries := make(map[string]DebianPackage)
+
+	resp, err := HTTPGet(ctx, cfg.url)
+	if err != nil {
+		return nil, fmt.Errorf("getting %q: %w", cfg.url, err)
+	}
+	defer resp.Body.Close()
+	if got, want := resp.StatusCode, http.StatusOK; got != want {
+		return nil, fmt.Errorf("unexpected HTTP status code: got %d, want %d", got, want)
+	}
+	var pkgs []ftpMasterApiResult
+	if err := json.NewDecoder(resp.Body).Decode(&pkgs); err != nil {
+		return nil, fmt.Errorf("decode: %w", err)
+	}
+	for _, pkg := range pkgs {
+		if !strings.HasSuffix(pkg.Binary, "-dev") {
+			continue // skip -dbgsym packages etc.
+		}
+		for importPath := range strings.SplitSeq(pkg.MetadataValue, ",") {
+			// XS-Go-Import-Path can be comma-separated and contain spaces.
+			golangBinaries[strings.TrimSpace(importPath)] = DebianPackage{
+				Binary: pkg.Binary,
+				Source: pkg.Source,
+			}
+		}
+	}
+	return golangBinaries, nil
+}
+
+// SplitList splits a comma-separated control field value.
+func SplitList(value string) []string {
+	var list []string
+	for item := range strings.SplitSeq(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
@@ -0,0 +1,268 @@
+package golangdeb
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestGetGolangBinaries(t *testing.T) {
+	t.Parallel()
+	for _, tc := range []struct {
+		desc    string
+		results []ftpMasterApiResult
+		want    map[string]DebianPackage
+	}{
+		{
+			desc:    "no results",
+			results: nil,
+			want:    map[string]DebianPackage{},
+		},
+		{
+			desc: "leading whitespace",
+			results: []ftpMasterApiResult{{
+				Binary:        "golang-example-foo-dev",
+				MetadataValue: " \t\n\rexample.com/foo",
+				Source:        "golang-example-foo",
+			}},
+			want: map[string]DebianPackage{
+				"example.com/foo": {
+					Binary: "golang-example-foo-dev",
+					Source: "golang-example-foo",
+				},
+			},
+		},
+		{
+			desc: "trailing whitespace",
+			results: []ftpMasterApiResult{{
+				Binary:        "golang-example-foo-dev",
+				MetadataValue: "example.com/foo \t\n\r",
+				Source:        "golang-example-foo",
+			}},
+			want: map[string]DebianPackage{
+				"example.com/foo": {
+					Binary: "golang-example-foo-dev",
+					Source: "golang-example-foo",
+				},
+			},
+		},
+		{
+			desc: "comma separation",
+			results: []ftpMasterApiResult{{
+				Binary:        "golang-example-foo-dev",
+				MetadataValue: "example.com/foo,example.com/bar",
+				Source:        "golang-example-foo",
+			}},
+			want: map[string]DebianPackage{
+				"example.com/foo": {
+					Binary: "golang-example-foo-dev",
+					Source: "golang-example-foo",
+				},
+				"example.com/bar": {
+					Binary: "golang-example-foo-dev",
+					Source: "golang-example-foo",
+				},
+			},
+		},
+		{
+			desc: "space around comma",
+			results: []ftpMasterApiResult{{
+				Binary:        "golang-example-foo-dev",
+				MetadataValue: "example.com/foo ,\n\texample.com/bar",
+				Source:        "golang-example-foo",
+			}},
+			want: map[string]DebianPackage{
+				"example.com/foo": {
+					Binary: "golang-example-foo-dev",
+					Source: "golang-example-foo",
+				},
+				"example.com/bar": {
+					Binary: "golang-example-foo-dev",
+					Source: "golang-example-foo",
+				},
+			},
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if err := json.NewEncoder(w).Encode(tc.results); err != nil {
+					t.Fatal(err)
+				}
+			}))
+			defer ts.Close()
+			got, err := GetGolangBinaries(t.Context(), WithGolangBinariesURL(ts.URL))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.want, got, cmpopts.EquateComparable(DebianPackage{})); diff != "" {
+				t.Fatalf("unexpected result (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+const testPackagesIndex = `Package: golang-github-foo-bar-dev
+Source: golang-github-foo-bar
+Version: 1.0-1
+Architecture: all
+Go-Import-Path: github.com/foo/bar
+
+Package: golang-github-foo-baz-dev
+Source: golang-github-foo-baz (1.2-1)
+Version: 1.2-1+b1
+Go-Import-Path: github.com/foo/baz, github.com/foo/baz/v2
+
+Package: foo-tool
+Source: foo-tool
+Version: 0.1-1
+Go-Import-Path: github.com/foo/tool
+
+Package: libc6
+Version: 2.36-9
+`
+
+const testSourcesIndex2 = `Package: golang-github-foo-qux
+Binary: qux-tool, golang-github-foo-qux-dev
+Version: 0.3-1
+XS-Go-Import-Path: github.com/foo/qux
+
+Package: golang-github-foo-cli
+Binary: foo-cli
+Version: 0.1-1
+XS-Go-Import-Path: github.com/foo/cli
+`
+
+func TestParseGolangBinaries(t *testing.T) {
+	got := make(map[string]DebianPackage)
+	for _, index := range []string{testPackagesIndex, testSourcesIndex2} {
+		if err := parseGolangBinaries(strings.NewReader(index), got); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := map[string]DebianPackage{
+		"github.com/foo/bar":    {Binary: "golang-github-foo-bar-dev", Source: "golang-github-foo-bar", Version: "1.0-1"},
+		"github.com/foo/baz":    {Binary: "golang-github-foo-baz-dev", Source: "golang-github-foo-baz", Version: "1.2-1"},
+		"github.com/foo/baz/v2": {Binary: "golang-github-foo-baz-dev", Source: "golang-github-foo-baz", Version: "1.2-1"},
+		"github.com/foo/qux":    {Binary: "golang-github-foo-qux-dev", Source: "golang-github-foo-qux", Version: "0.3-1"},
+	}
+	if diff := cmp.Diff(want, got, cmp.AllowUnexported(DebianPackage{})); diff != "" {
+		t.Errorf("parseGolangBinaries: diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestNewArchive(t *testing.T) {
+	for _, tt := range []struct {
+		source string
+		suite  string
+		want   Archive
+	}{
+		{"ftp-master", "", ftpMasterArchive{url: golangBinariesURL}},
+		{"", "", ftpMasterArchive{url: golangBinariesURL}},
+		{"apt", "bookworm-backports", indexArchive{locations: []string{
+			"/var/lib/apt/lists/*_dists_bookworm_*_Packages",
+			"/var/lib/apt/lists/*_dists_bookworm_*_Sources",
+			"/var/lib/apt/lists/*_dists_bookworm-backports_*_Packages",
+			"/var/lib/apt/lists/*_dists_bookworm-backports_*_Sources",
+		}}},
+		{"apt", "sid", indexArchive{locations: []string{
+			"/var/lib/apt/lists/*_dists_sid_*_Packages",
+			"/var/lib/apt/lists/*_dists_sid_*_Sources",
+			"/var/lib/apt/lists/*_dists_unstable_*_Packages",
+			"/var/lib/apt/lists/*_dists_unstable_*_Sources",
+		}}},
+		{"mirror", "trixie", indexArchive{locations: []string{
+			"https://deb.debian.org/debian/dists/trixie/main/source/Sources.gz",
+		}}},
+		{"", "bookworm-backports", indexArchive{locations: []string{
+			"https://deb.debian.org/debian/dists/bookworm/main/source/Sources.gz",
+			"https://deb.debian.org/debian/dists/bookworm-backports/main/source/Sources.gz",
+		}}},
+		{"http://mirror.example/debian/", "", indexArchive{locations: []string{
+			"http://mirror.example/debian/dists/sid/main/source/Sources.gz",
+		}}},
+		{"https://mirror.example/debian/dists/sid/main/binary-amd64/Packages.gz", "", indexArchive{locations: []string{
+			"https://mirror.example/debian/dists/sid/main/binary-amd64/Packages.gz",
+		}}},
+		{"/tmp/*_Packages", "", indexArchive{locations: []string{"/tmp/*_Packages"}}},
+	} {
+		got, err := NewArchive(&DebianDistro, tt.source, tt.suite)
+		if err != nil {
+			t.Errorf("newArchive(%q, %q): %v", tt.source, tt.suite, err)
+			continue
+		}
+		if diff := cmp.Diff(tt.want, got, cmp.AllowUnexported(ftpMasterArchive{}, indexArchive{})); diff != "" {
+			t.Errorf("newArchive(%q, %q): diff (-want +got):\n%s", tt.source, tt.suite, diff)
+		}
+	}
+
+	ubuntu, err := NewArchive(&UbuntuDistro, "", "noble")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := indexArchive{locations: []string{
+		"https://archive.ubuntu.com/ubuntu/dists/noble/main/source/Sources.gz",
+		"https://archive.ubuntu.com/ubuntu/dists/noble/universe/source/Sources.gz",
+	}}
+	if diff := cmp.Diff(want, ubuntu, cmp.AllowUnexported(indexArchive{})); diff != "" {
+		t.Errorf("newArchive(ubuntu, %q, %q): diff (-want +got):\n%s", "", "noble", diff)
+	}
+	if _, err := NewArchive(&UbuntuDistro, "ftp-master", ""); err == nil {
+		t.Errorf("newArchive(ubuntu, %q, %q) unexpectedly succeeded", "ftp-master", "")
+	}
+
+	for _, tt := range []struct {
+		source string
+		suite  string
+	}{
+		{"ftp-master", "trixie"},
+		{"apt", "stable"},
+	} {
+		if _, err := NewArchive(&DebianDistro, tt.source, tt.suite); err == nil {
+			t.Errorf("newArchive(%q, %q) unexpectedly succeeded", tt.source, tt.suite)
+		}
+	}
+}
+
+func TestIndexArchive(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "test_Packages"), []byte(testPackagesIndex), 0644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Create(filepath.Join(dir, "test_Sources.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gz := gzip.NewWriter(f)
+	if _, err := gz.Write([]byte(testSourcesIndex2)); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := indexArchive{locations: []string{filepath.Join(dir, "*_Packages"), filepath.Join(dir, "*_Sources.gz")}}.GolangBinaries(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, importPath := range []string{"github.com/foo/bar", "github.com/foo/qux"} {
+		if _, ok := got[importPath]; !ok {
+			t.Errorf("golangBinaries() does not contain %q", importPath)
+		}
+	}
+
+	if _, err := (indexArchive{locations: []string{filepath.Join(dir, "nonexistent_*")}}).GolangBinaries(t.Context()); err == nil {
+		t.Errorf("golangBinaries() of a non-matching pattern unexpectedly succeeded")
+	}
+}
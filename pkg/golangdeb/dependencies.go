@@ -0,0 +1,41 @@
+package golangdeb
+
+import (
+	"log"
+	"slices"
+)
+
+// ResolveDependencies maps the Go import paths in deps to the -dev packages
+// providing them in golangBinaries. Dependencies which are only available in
+// unstableBinaries need to be backported to suite, their source packages are
+// returned in backports. Without golangBinaries, the package names are
+// derived from the import paths.
+func ResolveDependencies(deps []string, golangBinaries, unstableBinaries map[string]DebianPackage, suite TargetSuite, allowUnknownHoster bool) (binaries, backports []string, _ error) {
+	binaries = make([]string, 0, len(deps))
+	for _, dep := range deps {
+		if len(golangBinaries) == 0 {
+			// fall back to heuristic
+			name, err := DebianNameFromGopkg(dep, TypeLibrary, "", allowUnknownHoster)
+			if err != nil {
+				return nil, nil, err
+			}
+			binaries = append(binaries, name+"-dev")
+			continue
+		}
+		pkg, ok := golangBinaries[dep]
+		if !ok {
+			if pkg, ok := unstableBinaries[dep]; ok {
+				log.Printf("Build-Dependency %q (%s) is not available in %s, it needs to be backported too", dep, pkg.Binary, suite.Name)
+				binaries = append(binaries, pkg.Binary)
+				if !slices.Contains(backports, pkg.Source) {
+					backports = append(backports, pkg.Source)
+				}
+				continue
+			}
+			log.Printf("Build-Dependency %q is not yet available in Debian, or has not yet been converted to use XS-Go-Import-Path in debian/control", dep)
+			continue
+		}
+		binaries = append(binaries, pkg.Binary)
+	}
+	return binaries, backports, nil
+}
@@ -0,0 +1,42 @@
+package golangdeb
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestResolveDependencies(t *testing.T) {
+	golangBinaries := map[string]DebianPackage{
+		"github.com/foo/bar": {Binary: "golang-github-foo-bar-dev", Source: "golang-github-foo-bar"},
+	}
+	unstableBinaries := map[string]DebianPackage{
+		"github.com/foo/bar": {Binary: "golang-github-foo-bar-dev", Source: "golang-github-foo-bar"},
+		"github.com/foo/baz": {Binary: "golang-github-foo-baz-dev", Source: "golang-github-foo-baz"},
+		"github.com/foo/qux": {Binary: "golang-github-foo-qux-dev", Source: "golang-github-foo-baz"},
+	}
+	deps := []string{"github.com/foo/bar", "github.com/foo/baz", "github.com/foo/qux", "github.com/foo/unknown"}
+
+	binaries, backports, err := ResolveDependencies(deps, golangBinaries, unstableBinaries, TargetSuite{Name: "trixie-backports"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"golang-github-foo-bar-dev", "golang-github-foo-baz-dev", "golang-github-foo-qux-dev"}, binaries); diff != "" {
+		t.Errorf("binaries: diff (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"golang-github-foo-baz"}, backports); diff != "" {
+		t.Errorf("backports: diff (-want +got):\n%s", diff)
+	}
+
+	// Without archive information, the names are derived from import paths.
+	binaries, _, err = ResolveDependencies([]string{"golang.org/x/text"}, nil, nil, TargetSuite{}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"golang-golang-x-text-dev"}, binaries); diff != "" {
+		t.Errorf("binaries: diff (-want +got):\n%s", diff)
+	}
+	if _, _, err := ResolveDependencies([]string{"example.invalid/foo"}, nil, nil, TargetSuite{}, false); err == nil {
+		t.Errorf("ResolveDependencies of an unknown hoster unexpectedly succeeded")
+	}
+}
@@ -1,4 +1,4 @@
-package main
+package golangdeb
 
 import (
 	"context"
@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"github.com/charmbracelet/glamour"
+	"github.com/google/go-github/v60/github"
 )
 
 //go:embed description.json
@@ -63,15 +64,10 @@ func markdownToLongDescription(markdown string) (string, error) {
 	return reformatForControl(out), nil
 }
 
-// getDescriptionForGopkg reads from README.md (or equivalent) from GitHub,
-// intended for extended description in debian/control.
-func getLongDescriptionForGopkg(gopkg string) (string, error) {
-	owner, repo, err := findGitHubRepo(gopkg)
-	if err != nil {
-		return "", fmt.Errorf("find github repo: %w", err)
-	}
-
-	rr, _, err := gitHub.Repositories.GetReadme(context.TODO(), owner, repo, nil)
+// fetchLongDescription reads README.md (or equivalent) of the GitHub
+// repository, intended for extended description in debian/control.
+func fetchLongDescription(ctx context.Context, client *github.Client, owner, repo string) (string, error) {
+	rr, _, err := client.Repositories.GetReadme(ctx, owner, repo, nil)
 	if err != nil {
 		return "", fmt.Errorf("get readme: %w", err)
 	}
@@ -0,0 +1,267 @@
+package golangdeb
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// DistroProfile describes a distribution dh-make-golang creates packages
+// for: where to look up existing packages, where packages are tracked and
+// hosted, where to file ITPs and which version conventions to follow.
+//
+// Custom profiles are JSON files with the same field names, see
+// LoadDistroProfile.
+type DistroProfile struct {
+	Name string `json:"name"`
+
+	// Archive is the default of the -archive flag (see NewArchive).
+	Archive string `json:"archive"`
+	// MetadataURL is the ftp-master style API listing the binary packages
+	// by Go-Import-Path, empty if the distribution has none.
+	MetadataURL string `json:"metadata_url,omitempty"`
+	// SourcesInNewURL is the ftp-master style API listing the source
+	// packages waiting in the NEW queue, empty if there is none.
+	SourcesInNewURL string `json:"sources_in_new_url,omitempty"`
+	// DscInSuiteURL is the prefix of the ftp-master style API listing the
+	// versions of a source package in a suite, to which "<suite>/<source>"
+	// is appended, empty if there is none.
+	DscInSuiteURL string `json:"dsc_in_suite_url,omitempty"`
+	// Mirror is the base URL of an archive mirror.
+	Mirror string `json:"mirror"`
+	// Components are the archive components to read Sources indices of.
+	Components []string `json:"components"`
+
+	// DevelSuite is the suite new packages are uploaded to, and
+	// DevelAliases are other names it is known by.
+	DevelSuite   string   `json:"devel_suite"`
+	DevelAliases []string `json:"devel_aliases,omitempty"`
+	// ExperimentalSuite is a suite on top of DevelSuite, if any.
+	ExperimentalSuite string `json:"experimental_suite,omitempty"`
+	// Releases maps the codenames of stable releases to their version,
+	// as used in the version suffixes below.
+	Releases map[string]string `json:"releases"`
+
+	// BranchPrefix is the DEP-14 vendor prefix of packaging branches.
+	BranchPrefix string `json:"branch_prefix"`
+	// Revision is the Debian revision of the first upload of a package.
+	Revision string `json:"revision"`
+	// BackportSuffix and StableUpdateSuffix are format strings for the
+	// suffix appended to the version when targeting a stable release,
+	// with the release version as argument.
+	BackportSuffix     string `json:"backport_suffix"`
+	StableUpdateSuffix string `json:"stable_update_suffix"`
+
+	// TrackerURL and NewQueueURL are prefixes of URLs to which a source
+	// package name is appended.
+	TrackerURL  string `json:"tracker_url"`
+	NewQueueURL string `json:"new_queue_url,omitempty"`
+
+	// Maintainer is the Maintainer field of new packages.
+	Maintainer string `json:"maintainer"`
+	// VcsURL is the prefix of the Vcs-Browser and Vcs-Git fields of new
+	// packages, and VcsPushURL the one of the git remote to push to.
+	VcsURL     string `json:"vcs_url"`
+	VcsPushURL string `json:"vcs_push_url"`
+
+	// ITPTo, ITPCC, ITPSubjectPrefix and ITPPseudoHeaders define the ITP
+	// email. "{owner}" in ITPPseudoHeaders is replaced with the name and
+	// email address of the packager.
+	ITPTo            string   `json:"itp_to"`
+	ITPCC            string   `json:"itp_cc,omitempty"`
+	ITPSubjectPrefix string   `json:"itp_subject_prefix"`
+	ITPPseudoHeaders []string `json:"itp_pseudo_headers"`
+}
+
+// DebianDistro is the built-in profile for Debian.
+var DebianDistro = DistroProfile{
+	Name:              "debian",
+	Archive:           "ftp-master",
+	MetadataURL:       golangBinariesURL,
+	SourcesInNewURL:   sourcesInNewURL,
+	DscInSuiteURL:     dscInSuiteURL,
+	Mirror:            "https://deb.debian.org/debian",
+	Components:        []string{"main"},
+	DevelSuite:        "sid",
+	DevelAliases:      []string{"unstable"},
+	ExperimentalSuite: "experimental",
+	Releases: map[string]string{
+		"buster":   "10",
+		"bullseye": "11",
+		"bookworm": "12",
+		"trixie":   "13",
+		"forky":    "14",
+		"duke":     "15",
+	},
+	BranchPrefix: "debian/",
+	Revision:     "1",
+	// https://backports.debian.org/Contribute/#index6h3
+	BackportSuffix: "~bpo%s+1",
+	// https://www.debian.org/doc/manuals/developers-reference/pkgs.html#special-case-uploads-to-the-stable-and-oldstable-distributions
+	StableUpdateSuffix: "+deb%su1",
+	TrackerURL:         "https://tracker.debian.org/pkg/",
+	NewQueueURL:        "https://dfsg-new-queue.debian.org/reviews/",
+	Maintainer:         GoTeamMaintainer,
+	VcsURL:             GoTeamSalsaURL,
+	VcsPushURL:         "git@salsa.debian.org:go-team/packages/",
+	ITPTo:              "submit@bugs.debian.org",
+	ITPCC:              "debian-devel@lists.debian.org, debian-go@lists.debian.org",
+	ITPSubjectPrefix:   "ITP: ",
+	ITPPseudoHeaders: []string{
+		"Package: wnpp",
+		"Severity: wishlist",
+		"Owner: {owner}",
+	},
+}
+
+// UbuntuDistro is the built-in profile for Ubuntu.
+var UbuntuDistro = DistroProfile{
+	Name:       "ubuntu",
+	Archive:    "mirror",
+	Mirror:     "https://archive.ubuntu.com/ubuntu",
+	Components: []string{"main", "universe"},
+	DevelSuite: "devel",
+	Releases: map[string]string{
+		"focal":    "20.04",
+		"jammy":    "22.04",
+		"noble":    "24.04",
+		"plucky":   "25.04",
+		"questing": "25.10",
+	},
+	BranchPrefix: "ubuntu/",
+	// Packages which are not in Debian get a 0 Debian revision, see
+	// https://wiki.ubuntu.com/UbuntuDevelopment/PackageVersionFormat
+	Revision:           "0ubuntu1",
+	BackportSuffix:     "~bpo%s.1",
+	StableUpdateSuffix: "~%s.1",
+	TrackerURL:         "https://launchpad.net/ubuntu/+source/",
+	Maintainer:         "Ubuntu Developers <ubuntu-devel-discuss@lists.ubuntu.com>",
+	VcsURL:             "https://git.launchpad.net/ubuntu/+source/",
+	VcsPushURL:         "git+ssh://git.launchpad.net/ubuntu/+source/",
+	// https://help.launchpad.net/Bugs/EmailInterface
+	ITPTo:            "new@bugs.launchpad.net",
+	ITPSubjectPrefix: "[needs-packaging] ",
+	ITPPseudoHeaders: []string{
+		" affects ubuntu",
+		" tag needs-packaging",
+	},
+}
+
+// distroProfiles are the built-in profiles.
+var distroProfiles = map[string]*DistroProfile{
+	DebianDistro.Name: &DebianDistro,
+	UbuntuDistro.Name: &UbuntuDistro,
+}
+
+// distroProfilesDir returns the directory in which custom profiles are
+// looked up by name.
+func distroProfilesDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "dh-make-golang", "distros"), nil
+}
+
+// LoadDistroProfile returns the profile with the given name, which is
+// either a built-in profile, the path of a JSON file (ending in .json), or
+// the name of a JSON file in ~/.config/dh-make-golang/distros/.
+//
+// Custom profiles start out as a copy of the profile named in their "base"
+// field (default: debian), so they only need to set the fields which
+// differ, e.g.:
+//
+//	{"name": "acme", "mirror": "https://deb.acme.example/debian", ...}
+func LoadDistroProfile(name string) (*DistroProfile, error) {
+	if d, ok := distroProfiles[name]; ok {
+		return d, nil
+	}
+	path := name
+	if !strings.HasSuffix(name, ".json") {
+		dir, err := distroProfilesDir()
+		if err != nil {
+			return nil, fmt.Errorf("unknown distribution %q: %w", name, err)
+		}
+		path = filepath.Join(dir, name+".json")
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("unknown distribution %q, want one of %s or a profile in %s", name, strings.Join(slices.Sorted(maps.Keys(distroProfiles)), ", "), path)
+		}
+		return nil, err
+	}
+	return parseDistroProfile(b)
+}
+
+// parseDistroProfile parses a custom profile in JSON format.
+func parseDistroProfile(b []byte) (*DistroProfile, error) {
+	var header struct {
+		Base string `json:"base"`
+	}
+	if err := json.Unmarshal(b, &header); err != nil {
+		return nil, fmt.Errorf("parse profile: %w", err)
+	}
+	if header.Base == "" {
+		header.Base = DebianDistro.Name
+	}
+	base, ok := distroProfiles[header.Base]
+	if !ok {
+		return nil, fmt.Errorf("parse profile: unknown base %q", header.Base)
+	}
+	// Copy the base profile, including its maps and slices, which
+	// json.Unmarshal would otherwise modify in place.
+	d := *base
+	d.Components = slices.Clone(base.Components)
+	d.DevelAliases = slices.Clone(base.DevelAliases)
+	d.ITPPseudoHeaders = slices.Clone(base.ITPPseudoHeaders)
+	d.Releases = maps.Clone(base.Releases)
+	if err := json.Unmarshal(b, &d); err != nil {
+		return nil, fmt.Errorf("parse profile: %w", err)
+	}
+	if d.Name == "" || d.Name == base.Name {
+		return nil, fmt.Errorf("parse profile: missing name")
+	}
+	return &d, nil
+}
+
+// isDevelSuite returns whether suite refers to the development suite.
+func (d *DistroProfile) isDevelSuite(suite string) bool {
+	return suite == "" || suite == d.DevelSuite || slices.Contains(d.DevelAliases, suite)
+}
+
+// suiteNames returns suite and the other names it is known by.
+func (d *DistroProfile) suiteNames(suite string) []string {
+	if suite != "*" && d.isDevelSuite(suite) {
+		return append([]string{d.DevelSuite}, d.DevelAliases...)
+	}
+	return []string{suite}
+}
+
+// FormatITPPseudoHeaders returns the pseudo-headers of the ITP email body.
+func (d *DistroProfile) FormatITPPseudoHeaders(owner string) []string {
+	headers := make([]string, len(d.ITPPseudoHeaders))
+	for i, h := range d.ITPPseudoHeaders {
+		headers[i] = strings.ReplaceAll(h, "{owner}", owner)
+	}
+	return headers
+}
+
+// releaseCodenames returns the known release codenames, sorted by version.
+func (d *DistroProfile) releaseCodenames() []string {
+	codenames := slices.Collect(maps.Keys(d.Releases))
+	sort.Slice(codenames, func(i, j int) bool {
+		return CompareVersions(d.Releases[codenames[i]], d.Releases[codenames[j]]) < 0
+	})
+	return codenames
+}
+
+const (
+	sourcesInNewURL = "https://api.ftp-master.debian.org/sources_in_suite/new"
+	dscInSuiteURL   = "https://api.ftp-master.debian.org/dsc_in_suite/"
+)
@@ -0,0 +1,78 @@
+package golangdeb
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseDistroProfile(t *testing.T) {
+	d, err := parseDistroProfile([]byte(`{
+	"name": "acme",
+	"base": "ubuntu",
+	"mirror": "https://apt.acme.example/ubuntu",
+	"releases": {"acme1": "1"},
+	"maintainer": "ACME Go Team <go@acme.example>"
+}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := d.Name, "acme"; got != want {
+		t.Errorf("Name: got %q, want %q", got, want)
+	}
+	if got, want := d.Mirror, "https://apt.acme.example/ubuntu"; got != want {
+		t.Errorf("Mirror: got %q, want %q", got, want)
+	}
+	if got, want := d.Revision, UbuntuDistro.Revision; got != want {
+		t.Errorf("Revision (inherited from the base): got %q, want %q", got, want)
+	}
+	if got, want := d.Releases["acme1"], "1"; got != want {
+		t.Errorf(`Releases["acme1"]: got %q, want %q`, got, want)
+	}
+	if got, want := d.Releases["noble"], "24.04"; got != want {
+		t.Errorf(`Releases["noble"] (inherited from the base): got %q, want %q`, got, want)
+	}
+	if _, ok := UbuntuDistro.Releases["acme1"]; ok {
+		t.Errorf("parseDistroProfile modified the base profile")
+	}
+
+	for _, profile := range []string{
+		`{"base": "debian"}`,
+		`{"name": "acme", "base": "gentoo"}`,
+		`{"name": "acme"`,
+	} {
+		if _, err := parseDistroProfile([]byte(profile)); err == nil {
+			t.Errorf("parseDistroProfile(%s) unexpectedly succeeded", profile)
+		}
+	}
+}
+
+func TestLoadDistroProfile(t *testing.T) {
+	if d, err := LoadDistroProfile("ubuntu"); err != nil || d != &UbuntuDistro {
+		t.Errorf("loadDistroProfile(%q) = %v, %v, want the built-in profile", "ubuntu", d, err)
+	}
+
+	config := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", config)
+	dir := filepath.Join(config, "dh-make-golang", "distros")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "acme.json")
+	if err := os.WriteFile(path, []byte(`{"name": "acme"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"acme", path} {
+		d, err := LoadDistroProfile(name)
+		if err != nil {
+			t.Errorf("loadDistroProfile(%q): %v", name, err)
+			continue
+		}
+		if got, want := d.Name, "acme"; got != want {
+			t.Errorf("loadDistroProfile(%q).Name = %q, want %q", name, got, want)
+		}
+	}
+	if _, err := LoadDistroProfile("nonexistent"); err == nil {
+		t.Errorf("loadDistroProfile(%q) unexpectedly succeeded", "nonexistent")
+	}
+}
@@ -0,0 +1,11 @@
+// Package golangdeb implements the packaging pipeline of dh-make-golang:
+// resolving and downloading upstream sources (MakeUpstreamSourceTarball),
+// deriving Debian package names (DebianNameFromGopkg), looking up and
+// resolving dependencies in the archive (NewArchive, ResolveDependencies),
+// fetching upstream metadata from GitHub (FetchMetadata), and generating
+// the packaging git repository and debian/ directory (CreateGitRepository,
+// Generator).
+//
+// Functions report failures as errors; the dh-make-golang command is a thin
+// wrapper around them.
+package golangdeb
@@ -1,4 +1,4 @@
-package main
+package golangdeb
 
 import (
 	"io/fs"
@@ -6,9 +6,9 @@ import (
 	"path/filepath"
 )
 
-// forceRemoveAll is a more robust alternative to [os.RemoveAll] that tries
+// ForceRemoveAll is a more robust alternative to [os.RemoveAll] that tries
 // harder to remove all the files and directories.
-func forceRemoveAll(path string) error {
+func ForceRemoveAll(path string) error {
 	// first pass to make sure all the directories are writable
 	err := filepath.Walk(path, func(path string, info fs.FileInfo, err error) error {
 		if info.IsDir() {
@@ -0,0 +1,154 @@
+package golangdeb
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// RunGitCommandIn runs git with the given arguments in dir.
+func RunGitCommandIn(ctx context.Context, dir string, arg ...string) error {
+	cmd := exec.CommandContext(ctx, "git", arg...)
+	cmd.Dir = dir
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// ConfigureGitRepository sets the identity of the packager (if known) and
+// the push behaviour the team workflow relies on in the git repository in
+// dir.
+func ConfigureGitRepository(ctx context.Context, dir string) error {
+	if debianName := DebianName(); debianName != "TODO" {
+		if err := RunGitCommandIn(ctx, dir, "config", "user.name", debianName); err != nil {
+			return fmt.Errorf("git config user.name: %w", err)
+		}
+	}
+	if debianEmail := DebianEmail(); debianEmail != "TODO" {
+		if err := RunGitCommandIn(ctx, dir, "config", "user.email", debianEmail); err != nil {
+			return fmt.Errorf("git config user.email: %w", err)
+		}
+	}
+	if err := RunGitCommandIn(ctx, dir, "config", "push.default", "matching"); err != nil {
+		return fmt.Errorf("git config push.default: %w", err)
+	}
+	return nil
+}
+
+// ConfigureOriginPush makes "git push" push all branches and tags to the
+// origin remote of the git repository in dir.
+func ConfigureOriginPush(ctx context.Context, dir string) error {
+	if err := RunGitCommandIn(ctx, dir, "config", "--add", "remote.origin.push", "+refs/heads/*:refs/heads/*"); err != nil {
+		return fmt.Errorf("git config --add remote.origin.push */heads/*: %w", err)
+	}
+	if err := RunGitCommandIn(ctx, dir, "config", "--add", "remote.origin.push", "+refs/tags/*:refs/tags/*"); err != nil {
+		return fmt.Errorf("git config --add remote.origin.push */tags/*: %w", err)
+	}
+	return nil
+}
+
+// CreateGitRepository creates the packaging git repository debsrc for
+// gopkg, importing the orig tarball, and returns its absolute directory.
+// debsrc and orig are relative to the current directory, and may point
+// into a staging directory.
+func CreateGitRepository(ctx context.Context, debsrc, gopkg, orig string, u *Upstream,
+	includeUpstreamHistory bool, allowUnknownHoster bool, debianBranch string, pristineTar bool,
+	originURL string) (string, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("get cwd: %w", err)
+	}
+	dir := filepath.Join(wd, debsrc)
+	if err := os.Mkdir(dir, 0755); err != nil {
+		return "", fmt.Errorf("mkdir: %w", err)
+	}
+
+	if err := RunGitCommandIn(ctx, dir, "init", "-b", debianBranch); err != nil {
+		return dir, fmt.Errorf("git init: %w", err)
+	}
+
+	// Set repository options
+
+	if err := ConfigureGitRepository(ctx, dir); err != nil {
+		return dir, err
+	}
+
+	// [remote "origin"]
+
+	log.Printf("Adding remote \"origin\" with URL %q\n", originURL)
+	if err := RunGitCommandIn(ctx, dir, "remote", "add", "origin", originURL); err != nil {
+		return dir, fmt.Errorf("git remote add origin %s: %w", originURL, err)
+	}
+	if err := ConfigureOriginPush(ctx, dir); err != nil {
+		return dir, err
+	}
+
+	// Preconfigure branches
+
+	branches := []string{debianBranch, "upstream"}
+	if pristineTar {
+		branches = append(branches, "pristine-tar")
+	}
+	for _, branch := range branches {
+		if err := RunGitCommandIn(ctx, dir, "config", "branch."+branch+".remote", "origin"); err != nil {
+			return dir, fmt.Errorf("git config branch.%s.remote origin: %w", branch, err)
+		}
+		if err := RunGitCommandIn(ctx, dir, "config", "branch."+branch+".merge", "refs/heads/"+branch); err != nil {
+			return dir, fmt.Errorf("git config branch.%s.merge refs/heads/%s: %w", branch, branch, err)
+		}
+	}
+
+	if includeUpstreamHistory {
+		u.Remote, err = UpstreamRemoteName(gopkg, allowUnknownHoster)
+		if err != nil {
+			return dir, fmt.Errorf("unable to fetch upstream history: %q", err)
+		}
+		if u.FromDir != "" {
+			err = addUpstreamRemoteFromDir(ctx, dir, u.Remote, u.RepoRoot.Repo, u.FromDir)
+		} else {
+			err = AddUpstreamRemote(ctx, dir, u.Remote, u.RepoRoot.Repo)
+		}
+		if err != nil {
+			return dir, err
+		}
+	}
+
+	// Import upstream orig tarball
+
+	arg := []string{"import-orig", "--no-interactive", "--debian-branch=" + debianBranch}
+	if pristineTar {
+		arg = append(arg, "--pristine-tar")
+	}
+	if includeUpstreamHistory {
+		arg = append(arg, "--upstream-vcs-tag="+u.CommitIsh)
+	}
+	if u.VendorComponent {
+		// gbp finds the component tarball next to the orig tarball.
+		arg = append(arg, "--component="+VendorComponent)
+	}
+	arg = append(arg, filepath.Join(wd, orig))
+	cmd := exec.CommandContext(ctx, "gbp", arg...)
+	cmd.Dir = dir
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return dir, fmt.Errorf("import-orig: %w", err)
+	}
+
+	return dir, nil
+}
+
+// AddUpstreamRemote adds the upstream repository repo as remote to the git
+// repository in dir and fetches its tags.
+func AddUpstreamRemote(ctx context.Context, dir, remote, repo string) error {
+	log.Printf("Adding remote %q with URL %q\n", remote, repo)
+	if err := RunGitCommandIn(ctx, dir, "remote", "add", remote, repo); err != nil {
+		return fmt.Errorf("git remote add %s %s: %w", remote, repo, err)
+	}
+	log.Printf("Running \"git fetch --tags %s\"\n", remote)
+	if err := RunGitCommandIn(ctx, dir, "fetch", "--tags", remote); err != nil {
+		return fmt.Errorf("git fetch %s: %w", remote, err)
+	}
+	return nil
+}
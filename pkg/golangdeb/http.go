@@ -0,0 +1,40 @@
+package golangdeb
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"time"
+)
+
+// APITimeout bounds requests to web APIs (ftp-master, GitHub, go-get
+// discovery), whose responses are small.
+const APITimeout = 1 * time.Minute
+
+// HTTPClient is used for all HTTP requests. Its timeouts bound connecting
+// and waiting for the response headers, but not reading the response body,
+// as downloading an upstream tarball can take arbitrarily long. Use the
+// context for an overall deadline.
+var HTTPClient = &http.Client{
+	Transport: &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		TLSHandshakeTimeout:   30 * time.Second,
+		ResponseHeaderTimeout: APITimeout,
+		IdleConnTimeout:       90 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	},
+}
+
+// HTTPGet issues a GET request for url with HTTPClient.
+func HTTPGet(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return HTTPClient.Do(req)
+}
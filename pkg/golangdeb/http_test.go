@@ -0,0 +1,37 @@
+package golangdeb
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHTTPGetHonorsContext(t *testing.T) {
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer ts.Close()
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	resp, err := HTTPGet(ctx, ts.URL)
+	if err == nil {
+		resp.Body.Close()
+		t.Fatalf("HTTPGet on a hanging server: got no error")
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("HTTPGet on a hanging server: got %v, want %v", err, context.DeadlineExceeded)
+	}
+	if d := time.Since(start); d > 10*time.Second {
+		t.Errorf("HTTPGet returned after %v, want shortly after the deadline", d)
+	}
+}
@@ -0,0 +1,175 @@
+package golangdeb
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+)
+
+// ReadModulePath returns the module path declared in dir/go.mod.
+func ReadModulePath(dir string) (string, error) {
+	b, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		return "", err
+	}
+	mod := modfile.ModulePath(b)
+	if mod == "" {
+		return "", fmt.Errorf("%s: no module declaration", filepath.Join(dir, "go.mod"))
+	}
+	return mod, nil
+}
+
+// LocalRepoRoot describes the local git checkout dir of the Go repository
+// importPath. If importPath is empty, it is determined from the go.mod file
+// at the top of the checkout. The upstream repository URL is taken from the
+// remote of the checkout which is most likely upstream (see
+// upstreamRemoteURL), and is empty if there is none.
+func LocalRepoRoot(ctx context.Context, dir, importPath string) (*RepoRoot, error) {
+	top, err := gitOutput(ctx, dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a git checkout: %w", dir, err)
+	}
+	rr := &RepoRoot{Root: importPath, VCS: "git"}
+	if mod, err := ReadModulePath(top); err == nil {
+		rr.Module = mod
+		if rr.Root == "" {
+			rr.Root = repoRootFromModule(mod, "")
+		}
+	} else if rr.Root == "" {
+		return nil, fmt.Errorf("determine import path: %w", err)
+	}
+	if rr.Repo, err = upstreamRemoteURL(ctx, top, rr.Root); err != nil {
+		return nil, err
+	}
+	return rr, nil
+}
+
+// gitOutput runs git with the given arguments in dir and returns its output
+// without the trailing newline.
+func gitOutput(ctx context.Context, dir string, arg ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", arg...)
+	cmd.Dir = dir
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	return strings.TrimSpace(string(out)), err
+}
+
+// upstreamRemoteURL returns the URL of the remote of the git checkout dir
+// which is most likely the upstream repository of importPath: the one
+// whose URL matches importPath, else the one named "upstream" (as commonly
+// used in checkouts of forks), else "origin", else any.
+func upstreamRemoteURL(ctx context.Context, dir, importPath string) (string, error) {
+	// Exits with status 1 if there are no remotes.
+	out, _ := gitOutput(ctx, dir, "config", "--get-regexp", `^remote\..*\.url$`)
+	remotes := make(map[string]string)
+	var first string
+	for line := range strings.SplitSeq(out, "\n") {
+		key, u, ok := strings.Cut(line, " ")
+		if !ok {
+			continue
+		}
+		name := strings.TrimSuffix(strings.TrimPrefix(key, "remote."), ".url")
+		u = publicRepoURL(u)
+		if importPathOfURL(u) == strings.ToLower(importPath) {
+			return u, nil
+		}
+		remotes[name] = u
+		if first == "" {
+			first = u
+		}
+	}
+	for _, name := range []string{"upstream", "origin"} {
+		if u, ok := remotes[name]; ok {
+			return u, nil
+		}
+	}
+	if first == "" {
+		log.Printf("The git checkout %s has no remotes\n", dir)
+	}
+	return first, nil
+}
+
+// publicRepoURL turns SSH URLs of repositories, which need an account, into
+// the corresponding HTTPS URLs, e.g. “git@github.com:foo/bar.git” →
+// “https://github.com/foo/bar.git”.
+func publicRepoURL(repo string) string {
+	if u, err := url.Parse(repo); err == nil && strings.Contains(repo, "://") {
+		if u.Scheme == "ssh" || u.Scheme == "git+ssh" {
+			return "https://" + u.Hostname() + u.Path
+		}
+		return repo
+	}
+	// scp-like syntax: [user@]host:path
+	if host, path, ok := strings.Cut(repo, ":"); ok && !strings.Contains(host, "/") {
+		host = host[strings.LastIndex(host, "@")+1:]
+		return "https://" + host + "/" + strings.TrimPrefix(path, "/")
+	}
+	return repo
+}
+
+// importPathOfURL returns the lower-cased host and path of a repository URL,
+// which for many hosters is the import path of the repository.
+func importPathOfURL(repo string) string {
+	u, err := url.Parse(repo)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Hostname() + strings.TrimSuffix(strings.TrimSuffix(u.Path, "/"), ".git"))
+}
+
+// getFromDir clones the local git checkout dir of repo at rev (by default
+// its HEAD) into gopath, so that the checkout itself is not modified.
+// Uncommitted changes are not packaged.
+func (u *Upstream) getFromDir(ctx context.Context, gopath, repo, dir, rev string) error {
+	rr, err := LocalRepoRoot(ctx, dir, repo)
+	if err != nil {
+		return err
+	}
+	top, err := gitOutput(ctx, dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return fmt.Errorf("git rev-parse: %w", err)
+	}
+	commit, err := gitOutput(ctx, top, "rev-parse", "--verify", "--quiet", cmp.Or(rev, "HEAD")+"^{commit}")
+	if err != nil {
+		return fmt.Errorf("revision %q not found in %s", cmp.Or(rev, "HEAD"), top)
+	}
+	if rev == "" {
+		if status, _ := gitOutput(ctx, top, "status", "--porcelain", "--untracked-files=no"); status != "" {
+			log.Printf("WARNING: %s has uncommitted changes, which are not packaged\n", top)
+		}
+	}
+	u.RepoRoot = rr
+	u.FromDir = top
+
+	dst := filepath.Join(gopath, "src", repo)
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	return gitClone(ctx, dst, top, commit, CloneFull)
+}
+
+// addUpstreamRemoteFromDir adds the upstream repository repo as remote to
+// the git repository in dir like AddUpstreamRemote, but fetches the
+// branches and tags of remote from the local checkout instead, without
+// network access. "git fetch remote" later updates them from upstream.
+func addUpstreamRemoteFromDir(ctx context.Context, dir, remote, repo, checkout string) error {
+	log.Printf("Adding remote %q with URL %q\n", remote, repo)
+	if err := RunGitCommandIn(ctx, dir, "remote", "add", remote, repo); err != nil {
+		return fmt.Errorf("git remote add %s %s: %w", remote, repo, err)
+	}
+	log.Printf("Fetching the history of %q from %q\n", remote, checkout)
+	// HEAD, in case the packaged commit is on no branch.
+	refspec := "+refs/heads/*:refs/remotes/" + remote + "/*"
+	if err := RunGitCommandIn(ctx, dir, "fetch", "--tags", checkout, refspec, "HEAD"); err != nil {
+		return fmt.Errorf("git fetch %s: %w", checkout, err)
+	}
+	return nil
+}
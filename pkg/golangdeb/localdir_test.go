@@ -0,0 +1,154 @@
+package golangdeb
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestPublicRepoURL(t *testing.T) {
+	for _, tt := range []struct {
+		in, want string
+	}{
+		{"https://github.com/foo/bar.git", "https://github.com/foo/bar.git"},
+		{"git@github.com:foo/bar.git", "https://github.com/foo/bar.git"},
+		{"salsa.debian.org:go-team/bar", "https://salsa.debian.org/go-team/bar"},
+		{"ssh://git@gitlab.com:2222/group/bar", "https://gitlab.com/group/bar"},
+		{"/srv/git/bar", "/srv/git/bar"},
+	} {
+		if got := publicRepoURL(tt.in); got != tt.want {
+			t.Errorf("publicRepoURL(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+// newLocalCheckout creates a git checkout of the module example.org/hello
+// with the release tag v1.0.0 and one more commit, and the given remotes
+// (name → URL).
+func newLocalCheckout(t *testing.T, remotes map[string]string) string {
+	dir := t.TempDir()
+	gitCmdOrFatal(t, dir, "init", "--quiet", "--initial-branch=main")
+	gitCmdOrFatal(t, dir, "config", "user.email", "unittest@example.com")
+	gitCmdOrFatal(t, dir, "config", "user.name", "Unit Test")
+	for name, content := range map[string]string{
+		"go.mod":  "module example.org/hello\n\ngo 1.21\n",
+		"main.go": "package main\n\nfunc main() {}\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	gitCmdOrFatal(t, dir, "add", ".")
+	gitCmdOrFatal(t, dir, "commit", "--quiet", "-m", "Initial commit")
+	gitCmdOrFatal(t, dir, "tag", "-a", "-m", "Release v1.0.0", "v1.0.0")
+	if err := os.WriteFile(filepath.Join(dir, "README"), []byte("hello\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	gitCmdOrFatal(t, dir, "add", ".")
+	gitCmdOrFatal(t, dir, "commit", "--quiet", "-m", "Add README")
+	for name, url := range remotes {
+		gitCmdOrFatal(t, dir, "remote", "add", name, url)
+	}
+	return dir
+}
+
+func TestLocalRepoRoot(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		remotes map[string]string
+		want    string
+	}{
+		{"matching import path", map[string]string{"origin": "git@git.example.org:fork/hello.git", "mine": "git@example.org:hello.git"}, "https://example.org/hello.git"},
+		{"upstream", map[string]string{"origin": "git@git.example.org:fork/hello.git", "upstream": "https://git.example.org/hello"}, "https://git.example.org/hello"},
+		{"origin", map[string]string{"origin": "https://git.example.org/hello"}, "https://git.example.org/hello"},
+		{"no remotes", nil, ""},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := newLocalCheckout(t, tt.remotes)
+			got, err := LocalRepoRoot(t.Context(), dir, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := (RepoRoot{Root: "example.org/hello", Repo: tt.want, VCS: "git", Module: "example.org/hello"}); *got != want {
+				t.Errorf("LocalRepoRoot: got %+v, want %+v", *got, want)
+			}
+		})
+	}
+}
+
+func TestMakeUpstreamSourceTarballFromDir(t *testing.T) {
+	dir := newLocalCheckout(t, map[string]string{"upstream": "https://git.example.org/hello"})
+	setGoModuleEnv(t, "off", "")
+
+	for _, tt := range []struct {
+		revision   string
+		prerelease bool
+		want       string
+		files      []string
+	}{
+		{"v1.0.0", false, "1.0.0", []string{"hello/", "hello/go.mod", "hello/main.go"}},
+		{"", true, "1.0.0+git", []string{"hello/", "hello/README", "hello/go.mod", "hello/main.go"}},
+	} {
+		u, err := MakeUpstreamSourceTarball(t.Context(), "example.org/hello", tt.revision, SourceDir, dir, CloneFull, tt.prerelease, false, false)
+		if err != nil {
+			t.Fatalf("MakeUpstreamSourceTarball(%q): %v", tt.revision, err)
+		}
+		defer u.RemoveTempFiles()
+		if !strings.HasPrefix(u.Version, tt.want) || u.Tag != "v1.0.0" || u.Compression != "xz" {
+			t.Errorf("MakeUpstreamSourceTarball(%q): got version %q (tag %q, compression %q), want %q",
+				tt.revision, u.Version, u.Tag, u.Compression, tt.want)
+		}
+		if u.FromDir != dir || u.RepoRoot.Repo != "https://git.example.org/hello" {
+			t.Errorf("MakeUpstreamSourceTarball(%q): got checkout %q and upstream %q, want %q and https://git.example.org/hello",
+				tt.revision, u.FromDir, u.RepoRoot.Repo, dir)
+		}
+		out, err := exec.Command("tar", "tJf", u.TarPath).Output()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := strings.Fields(string(out))
+		slices.Sort(got)
+		if !slices.Equal(got, tt.files) {
+			t.Errorf("MakeUpstreamSourceTarball(%q): got tarball contents %q, want %q", tt.revision, got, tt.files)
+		}
+	}
+
+	// The checkout itself is left alone.
+	if out, err := exec.Command("git", "-C", dir, "status", "--porcelain").Output(); err != nil || len(out) > 0 {
+		t.Errorf("checkout modified: %q, %v", out, err)
+	}
+}
+
+func TestMakeUpstreamSourceTarballFromDirOffline(t *testing.T) {
+	dir := newLocalCheckout(t, nil)
+	for name, content := range map[string]string{
+		"go.mod":  "module example.org/hello\n\ngo 1.21\n\nrequire example.org/vanity/lib v1.2.0\n",
+		"main.go": "package main\n\nimport (\n\t_ \"example.net/unknown\"\n\t_ \"example.org/vanity/lib/sub\"\n\t_ \"github.com/foo/bar/baz\"\n)\n\nfunc main() {}\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	gitCmdOrFatal(t, dir, "commit", "--quiet", "-a", "-m", "Add dependencies")
+	// No module proxy and no go-get discovery, as without network access.
+	setGoModuleEnv(t, "off", "")
+	oldResolver := DefaultRepoRootResolver
+	DefaultRepoRootResolver = &RepoRootResolver{}
+	t.Cleanup(func() { DefaultRepoRootResolver = oldResolver })
+
+	u, err := MakeUpstreamSourceTarball(t.Context(), "example.org/hello", "", SourceDir, dir, CloneFull, true, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer u.RemoveTempFiles()
+	slices.Sort(u.RepoDeps)
+	if want := []string{"example.org/vanity/lib", "github.com/foo/bar"}; !slices.Equal(u.RepoDeps, want) {
+		t.Errorf("got repo deps %q, want %q", u.RepoDeps, want)
+	}
+	if want := []string{"example.net/unknown"}; !slices.Equal(u.UnresolvedDeps, want) {
+		t.Errorf("got unresolved deps %q, want %q", u.UnresolvedDeps, want)
+	}
+}
@@ -1,15 +1,80 @@
-package main
+package golangdeb
 
 import (
 	"context"
 	"fmt"
-	"net/http"
+	"log"
 	"regexp"
 	"strings"
 
+	"github.com/google/go-github/v60/github"
 	"golang.org/x/net/html"
+	"golang.org/x/sync/errgroup"
 )
 
+// Metadata is the upstream metadata of a Go package hosted on GitHub, as
+// determined by FetchMetadata. Fields which could not be determined are
+// empty, and the writers fall back to TODO markers.
+type Metadata struct {
+	Owner           string // GitHub repository owner, empty if not on GitHub
+	Repo            string // GitHub repository name
+	License         string // Debian license short name, e.g. Expat
+	LicenseText     string // license text for debian/copyright
+	Author          string // upstream author, for the ITP
+	Copyright       string // copyright line, e.g. “2015 Jane Doe”
+	Description     string // short description
+	LongDescription string // long description, formatted for debian/control
+}
+
+// Homepage returns the GitHub URL of the package, or TODO if it is not on
+// GitHub.
+func (m *Metadata) Homepage() string {
+	if m.Repo == "" {
+		return "TODO"
+	}
+	return "https://github.com/" + m.Owner + "/" + m.Repo
+}
+
+// FetchMetadata determines the repository of gopkg on GitHub (following
+// go-get redirects of vanity import paths) and fetches its metadata with
+// client, issuing the API requests concurrently. Failed lookups are logged
+// and leave the corresponding fields empty; an error is only returned when
+// ctx is done.
+func FetchMetadata(ctx context.Context, client *github.Client, gopkg string) (*Metadata, error) {
+	var m Metadata
+	owner, repo, err := findGitHubRepo(ctx, gopkg)
+	if err != nil {
+		log.Printf("Could not find %q on GitHub: %v\n", gopkg, err)
+		return &m, ctx.Err()
+	}
+	m.Owner, m.Repo = owner, repo
+
+	var eg errgroup.Group
+	eg.Go(func() error {
+		var err error
+		if m.License, m.LicenseText, err = fetchLicense(ctx, client, owner, repo); err != nil {
+			log.Printf("Could not determine license for %q: %v\n", gopkg, err)
+		}
+		return nil
+	})
+	eg.Go(func() error {
+		var err error
+		if m.Author, m.Copyright, m.Description, err = fetchRepository(ctx, client, owner, repo); err != nil {
+			log.Printf("Could not determine author and description for %q: %v\n", gopkg, err)
+		}
+		return nil
+	})
+	eg.Go(func() error {
+		var err error
+		if m.LongDescription, err = fetchLongDescription(ctx, client, owner, repo); err != nil {
+			log.Printf("Could not determine long description for %q: %v\n", gopkg, err)
+		}
+		return nil
+	})
+	eg.Wait()
+	return &m, ctx.Err()
+}
+
 // To update, use:
 // curl -s https://api.github.com/licenses | jq '.[].key'
 // then compare with https://www.debian.org/doc/packaging-manuals/copyright-format/1.0/#license-specification
@@ -75,11 +140,13 @@ Comment:
 
 var githubRegexp = regexp.MustCompile(`github\.com/([^/]+/[^/]+)`)
 
-func findGitHubOwnerRepo(gopkg string) (string, error) {
+func findGitHubOwnerRepo(ctx context.Context, gopkg string) (string, error) {
 	if after, ok := strings.CutPrefix(gopkg, "github.com/"); ok {
 		return after, nil
 	}
-	resp, err := http.Get("https://" + gopkg + "?go-get=1")
+	ctx, cancel := context.WithTimeout(ctx, APITimeout)
+	defer cancel()
+	resp, err := HTTPGet(ctx, "https://"+gopkg+"?go-get=1")
 	if err != nil {
 		return "", fmt.Errorf("HTTP get: %w", err)
 	}
@@ -126,8 +193,8 @@ func findGitHubOwnerRepo(gopkg string) (string, error) {
 	}
 }
 
-func findGitHubRepo(gopkg string) (owner string, repo string, _ error) {
-	ownerrepo, err := findGitHubOwnerRepo(gopkg)
+func findGitHubRepo(ctx context.Context, gopkg string) (owner string, repo string, _ error) {
+	ownerrepo, err := findGitHubOwnerRepo(ctx, gopkg)
 	if err != nil {
 		return "", "", fmt.Errorf("find GitHub owner repo: %w", err)
 	}
@@ -138,13 +205,10 @@ func findGitHubRepo(gopkg string) (owner string, repo string, _ error) {
 	return parts[0], parts[1], nil
 }
 
-func getLicenseForGopkg(gopkg string) (string, string, error) {
-	owner, repo, err := findGitHubRepo(gopkg)
-	if err != nil {
-		return "", "", fmt.Errorf("find GitHub repo: %w", err)
-	}
-
-	rl, _, err := gitHub.Repositories.License(context.TODO(), owner, repo)
+// fetchLicense returns the Debian license short name and full text of the
+// GitHub repository.
+func fetchLicense(ctx context.Context, client *github.Client, owner, repo string) (string, string, error) {
+	rl, _, err := client.Repositories.License(ctx, owner, repo)
 	if err != nil {
 		return "", "", fmt.Errorf("get license for Go package: %w", err)
 	}
@@ -160,56 +224,30 @@ func getLicenseForGopkg(gopkg string) (string, string, error) {
 	return "TODO", " TODO", nil
 }
 
-func getAuthorAndCopyrightForGopkg(gopkg string) (string, string, error) {
-	owner, repo, err := findGitHubRepo(gopkg)
+// fetchRepository returns the upstream author, the copyright line and the
+// description of the GitHub repository.
+func fetchRepository(ctx context.Context, client *github.Client, owner, repo string) (author, copyright, description string, _ error) {
+	rr, _, err := client.Repositories.Get(ctx, owner, repo)
 	if err != nil {
-		return "", "", fmt.Errorf("find GitHub repo: %w", err)
-	}
-
-	rr, _, err := gitHub.Repositories.Get(context.TODO(), owner, repo)
-	if err != nil {
-		return "", "", fmt.Errorf("get repo: %w", err)
+		return "", "", "", fmt.Errorf("get repo: %w", err)
 	}
+	description = strings.TrimSpace(rr.GetDescription())
 
 	if strings.TrimSpace(rr.GetOwner().GetURL()) == "" {
-		return "", "", fmt.Errorf("repository owner URL not present in API response")
+		return "", "", description, fmt.Errorf("repository owner URL not present in API response")
 	}
 
-	ur, _, err := gitHub.Users.Get(context.TODO(), rr.GetOwner().GetLogin())
+	ur, _, err := client.Users.Get(ctx, rr.GetOwner().GetLogin())
 	if err != nil {
-		return "", "", fmt.Errorf("get user: %w", err)
+		return "", "", description, fmt.Errorf("get user: %w", err)
 	}
 
-	copyright := rr.CreatedAt.Format("2006") + " " + ur.GetName()
-	if strings.HasPrefix(repo, "google/") {
+	copyright = rr.CreatedAt.Format("2006") + " " + ur.GetName()
+	if owner == "google" {
 		// As per https://opensource.google.com/docs/creating/, Google retains
 		// the copyright for repositories underneath github.com/google/.
 		copyright = rr.CreatedAt.Format("2006") + " Google Inc."
 	}
 
-	return ur.GetName(), copyright, nil
-}
-
-// getDescriptionForGopkg gets the package description from GitHub,
-// intended for the synopsis or the short description in debian/control.
-func getDescriptionForGopkg(gopkg string) (string, error) {
-	owner, repo, err := findGitHubRepo(gopkg)
-	if err != nil {
-		return "", fmt.Errorf("find GitHub repo: %w", err)
-	}
-
-	rr, _, err := gitHub.Repositories.Get(context.TODO(), owner, repo)
-	if err != nil {
-		return "", err
-	}
-
-	return strings.TrimSpace(rr.GetDescription()), nil
-}
-
-func getHomepageForGopkg(gopkg string) string {
-	owner, repo, err := findGitHubRepo(gopkg)
-	if err != nil {
-		return "TODO"
-	}
-	return "https://github.com/" + owner + "/" + repo
+	return ur.GetName(), copyright, description, nil
 }
@@ -0,0 +1,117 @@
+package golangdeb
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-github/v60/github"
+)
+
+// newGitHubStub returns a TLS server which serves the GitHub API for the
+// repository foo/bar and go-get discovery for the vanity import path
+// <host>/bar, as well as a GitHub client talking to it. The number of
+// requests per path is recorded in requests.
+func newGitHubStub(t *testing.T) (ts *httptest.Server, client *github.Client, requests map[string]int) {
+	var mu sync.Mutex
+	requests = make(map[string]int)
+	readme := base64.StdEncoding.EncodeToString([]byte("Bar does things.\n\nMany things.\n"))
+	ts = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.URL.Path]++
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/bar":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprintf(w, `<html><head><meta name="go-import" content="%s/bar git https://github.com/foo/bar.git"></head></html>`, r.Host)
+		case "/repos/foo/bar":
+			fmt.Fprint(w, `{"description": " Bar does things. ", "created_at": "2015-01-02T03:04:05Z",
+				"owner": {"login": "foo", "url": "https://api.github.com/users/foo"}}`)
+		case "/repos/foo/bar/license":
+			fmt.Fprint(w, `{"license": {"key": "mit"}}`)
+		case "/repos/foo/bar/readme":
+			fmt.Fprintf(w, `{"name": "README.txt", "encoding": "base64", "content": %q}`, readme)
+		case "/users/foo":
+			fmt.Fprint(w, `{"login": "foo", "name": "Jane Doe"}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(ts.Close)
+
+	client = github.NewClient(ts.Client())
+	client.BaseURL, _ = url.Parse(ts.URL + "/")
+
+	// Discover vanity import paths on the stub, too.
+	oldClient := HTTPClient
+	HTTPClient = ts.Client()
+	t.Cleanup(func() { HTTPClient = oldClient })
+
+	return ts, client, requests
+}
+
+func TestFetchMetadata(t *testing.T) {
+	ts, client, requests := newGitHubStub(t)
+
+	want := &Metadata{
+		Owner:           "foo",
+		Repo:            "bar",
+		License:         "Expat",
+		LicenseText:     debianLicenseText["Expat"],
+		Author:          "Jane Doe",
+		Copyright:       "2015 Jane Doe",
+		Description:     "Bar does things.",
+		LongDescription: reformatForControl("Bar does things.\n\nMany things.\n"),
+	}
+	for _, gopkg := range []string{"github.com/foo/bar", strings.TrimPrefix(ts.URL, "https://") + "/bar"} {
+		clear(requests)
+		got, err := FetchMetadata(t.Context(), client, gopkg)
+		if err != nil {
+			t.Fatalf("FetchMetadata(%q): %v", gopkg, err)
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("FetchMetadata(%q): unexpected metadata (-want +got):\n%s", gopkg, diff)
+		}
+		if got, want := got.Homepage(), "https://github.com/foo/bar"; got != want {
+			t.Errorf("FetchMetadata(%q).Homepage() = %q, want %q", gopkg, got, want)
+		}
+		for path, n := range requests {
+			if n != 1 {
+				t.Errorf("FetchMetadata(%q): %d requests for %s, want 1", gopkg, n, path)
+			}
+		}
+	}
+}
+
+func TestFetchMetadataFailures(t *testing.T) {
+	_, client, _ := newGitHubStub(t)
+
+	// Failed lookups leave the fields empty.
+	got, err := FetchMetadata(t.Context(), client, "github.com/foo/missing")
+	if err != nil {
+		t.Fatalf("FetchMetadata: %v", err)
+	}
+	want := &Metadata{Owner: "foo", Repo: "missing"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("FetchMetadata: unexpected metadata (-want +got):\n%s", diff)
+	}
+
+	if got, want := (&Metadata{}).Homepage(), "TODO"; got != want {
+		t.Errorf("Homepage() without a repository = %q, want %q", got, want)
+	}
+
+	// Cancellation is an error.
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	if _, err := FetchMetadata(ctx, client, "github.com/foo/bar"); err == nil {
+		t.Errorf("FetchMetadata with a cancelled context: got no error")
+	}
+}
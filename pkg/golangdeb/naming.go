@@ -0,0 +1,179 @@
+package golangdeb
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/user"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// PackageType is the kind of binary packages built from a Go package.
+type PackageType int
+
+const (
+	TypeGuess PackageType = iota
+	TypeLibrary
+	TypeProgram
+	TypeLibraryProgram
+	TypeProgramLibrary
+)
+
+// normalize package name into Debian standard[1]
+// https://www.debian.org/doc/debian-policy/ch-controlfields.html#source
+// Package names (both source and binary, see Package, Section 5.6.7) must
+// consist only of lower case letters (a-z), digits (0-9), plus (+) and minus
+// (-) signs, and periods (.). They must be at least two characters long and
+// must start with an alphanumeric character.
+func NormalizeDebianPackageName(str string) string {
+	lowerDigitPlusMinusDot := func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z' || '0' <= r && r <= '9':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r + ('a' - 'A')
+		case r == '.' || r == '+' || r == '-':
+			return r
+		case r == '_':
+			return '-'
+		}
+		return -1
+	}
+
+	safe := strings.Trim(strings.Map(lowerDigitPlusMinusDot, str), "-")
+	if len(safe) < 2 {
+		return "TODO"
+	}
+
+	return safe
+}
+
+// knownHost describes a hoster of Go packages.
+type knownHost struct {
+	short string // short name used in Debian package names
+	// rootDepth is the number of path elements of repository roots, e.g. 3
+	// for github.com/foo/bar, or 0 if it varies (e.g. GitLab subgroups).
+	rootDepth int
+}
+
+var knownHosts = map[string]knownHost{
+	// keep the list in alphabetical order
+	"bazil.org":            {"bazil", 2},
+	"bitbucket.org":        {"bitbucket", 3},
+	"blitiri.com.ar":       {"blitiri", 3},
+	"cloud.google.com":     {"googlecloud", 2},
+	"code.google.com":      {"googlecode", 3},
+	"codeberg.org":         {"codeberg", 3},
+	"filippo.io":           {"filippo", 2},
+	"fortio.org":           {"fortio", 2},
+	"fyne.io":              {"fyne", 2},
+	"git.sr.ht":            {"sourcehut", 3},
+	"github.com":           {"github", 3},
+	"gitlab.com":           {"gitlab", 0},
+	"go.bug.st":            {"bugst", 2},
+	"go.cypherpunks.ru":    {"cypherpunks", 0},
+	"go.mongodb.org":       {"mongodb", 2},
+	"go.opentelemetry.io":  {"opentelemetry", 0},
+	"go.step.sm":           {"step", 2},
+	"go.uber.org":          {"uber", 2},
+	"go4.org":              {"go4", 0},
+	"gocloud.dev":          {"gocloud", 1},
+	"golang.org":           {"golang", 3},
+	"google.golang.org":    {"google", 2},
+	"gopkg.in":             {"gopkg", 0},
+	"honnef.co":            {"honnef", 3},
+	"howett.net":           {"howett", 2},
+	"k8s.io":               {"k8s", 2},
+	"modernc.org":          {"modernc", 2},
+	"pault.ag":             {"pault", 3},
+	"pgregory.net":         {"pgregory", 2},
+	"rsc.io":               {"rsc", 2},
+	"salsa.debian.org":     {"debian", 0},
+	"sigs.k8s.io":          {"k8s-sigs", 2},
+	"software.sslmate.com": {"sslmate", 3},
+	"zgo.at":               {"zgoat", 2},
+}
+
+// ShortHostName returns the short name of the hoster of gopkg used in
+// Debian package names, e.g. "github" for github.com.
+func ShortHostName(gopkg string, allowUnknownHoster bool) (host string, err error) {
+	fqdn, _, _ := strings.Cut(gopkg, "/")
+	if host, ok := knownHosts[fqdn]; ok {
+		return host.short, nil
+	}
+	if !allowUnknownHoster {
+		return "", fmt.Errorf("unknown hoster %q", fqdn)
+	}
+	suffix, _ := publicsuffix.PublicSuffix(fqdn)
+	host = fqdn[:len(fqdn)-len(suffix)-len(".")]
+	log.Printf("WARNING: Using %q as canonical hostname for %q. If that is not okay, please file a bug against %s.\n", host, fqdn, os.Args[0])
+	return host, nil
+}
+
+// UpstreamRemoteName returns the name of the git remote for the upstream
+// repository of gopkg, e.g. "github" for github.com/foo/bar. Upstreams on
+// salsa.debian.org use "salsa", as "debian" is confusing next to origin.
+func UpstreamRemoteName(gopkg string, allowUnknownHoster bool) (string, error) {
+	remote, err := ShortHostName(gopkg, allowUnknownHoster)
+	if err != nil {
+		return "", err
+	}
+	if remote == "debian" {
+		remote = "salsa"
+	}
+	return remote, nil
+}
+
+// DebianNameFromGopkg maps a Go package repo path to a Debian package name,
+// e.g. "golang.org/x/text" → "golang-golang-x-text".
+// This follows https://fedoraproject.org/wiki/PackagingDrafts/Go#Package_Names
+func DebianNameFromGopkg(gopkg string, t PackageType, customProgPkgName string, allowUnknownHoster bool) (string, error) {
+	parts := strings.Split(gopkg, "/")
+
+	if t == TypeProgram || t == TypeProgramLibrary {
+		if customProgPkgName != "" {
+			return NormalizeDebianPackageName(customProgPkgName), nil
+		}
+		return NormalizeDebianPackageName(parts[len(parts)-1]), nil
+	}
+
+	host, err := ShortHostName(gopkg, allowUnknownHoster)
+	if err != nil {
+		return "", fmt.Errorf("cannot derive Debian package name: %w", err)
+	}
+	parts[0] = host
+
+	return NormalizeDebianPackageName("golang-" + strings.Join(parts, "-")), nil
+}
+
+// DebianName returns the name of the packager, or TODO if unknown.
+func DebianName() string {
+	if name := strings.TrimSpace(os.Getenv("DEBFULLNAME")); name != "" {
+		return name
+	}
+	if name := strings.TrimSpace(os.Getenv("DEBNAME")); name != "" {
+		return name
+	}
+	if u, err := user.Current(); err == nil && u.Name != "" {
+		return u.Name
+	}
+	return "TODO"
+}
+
+// DebianEmail returns the email address of the packager, or TODO if
+// unknown.
+func DebianEmail() string {
+	if email := strings.TrimSpace(os.Getenv("DEBEMAIL")); email != "" {
+		return email
+	}
+	mailname, err := os.ReadFile("/etc/mailname")
+	// By default, /etc/mailname contains "debian" which is not useful; check for ".".
+	if err == nil && strings.Contains(string(mailname), ".") {
+		if u, err := user.Current(); err == nil && u.Username != "" {
+			return u.Username + "@" + strings.TrimSpace(string(mailname))
+		}
+	}
+	return "TODO"
+}
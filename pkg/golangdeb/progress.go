@@ -1,35 +1,37 @@
-package main
+package golangdeb
 
 import (
 	"fmt"
-	"github.com/mattn/go-isatty"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
+
+	"github.com/mattn/go-isatty"
 )
 
 const (
 	_ = 1 << (10 * iota)
-	Kibi
-	Mebi
-	Gibi
-	Tebi
+	kibi
+	mebi
+	gibi
+	tebi
 )
 
 func humanizeBytes(b int64) string {
-	if b > Tebi {
-		return fmt.Sprintf("%.2f TiB", float64(b)/float64(Tebi))
-	} else if b > Gibi {
-		return fmt.Sprintf("%.2f GiB", float64(b)/float64(Gibi))
-	} else if b > Mebi {
-		return fmt.Sprintf("%.2f MiB", float64(b)/float64(Mebi))
+	if b > tebi {
+		return fmt.Sprintf("%.2f TiB", float64(b)/float64(tebi))
+	} else if b > gibi {
+		return fmt.Sprintf("%.2f GiB", float64(b)/float64(gibi))
+	} else if b > mebi {
+		return fmt.Sprintf("%.2f MiB", float64(b)/float64(mebi))
 	} else {
-		return fmt.Sprintf("%.2f KiB", float64(b)/float64(Kibi))
+		return fmt.Sprintf("%.2f KiB", float64(b)/float64(kibi))
 	}
 }
 
-func progressSize(prefix, path string, done chan struct{}) {
+// ProgressSize periodically prints the size of path until done is closed.
+func ProgressSize(prefix, path string, done chan struct{}) {
 	// previous holds how many bytes the previous line contained
 	// so that we can clear it in its entirety.
 	var previous int
@@ -0,0 +1,230 @@
+package golangdeb
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// repoRootCacheTTL is how long repository roots resolved over the network
+// are cached.
+const repoRootCacheTTL = 30 * 24 * time.Hour
+
+// RepoRootResolver resolves Go import paths to the root of their repository,
+// e.g. “golang.org/x/net/html” → “golang.org/x/net”. Import paths on the
+// known hosters with a fixed layout (see ShortHostName) are resolved without
+// network access. The others are resolved using ResolveRepoRoot,
+// concurrently and only once per import path, and cached on disk.
+type RepoRootResolver struct {
+	// CachePath is the JSON file in which resolved repository roots are
+	// kept across runs. Empty disables the cache.
+	CachePath string
+
+	// Concurrency limits the concurrent lookups of RepoRoots, 8 by default.
+	Concurrency int
+
+	// lookup resolves import paths over the network, for tests.
+	lookup func(ctx context.Context, importPath string) (string, error)
+
+	loadOnce sync.Once
+	mu       sync.Mutex
+	cache    map[string]repoRootCacheEntry
+	calls    map[string]*repoRootCall
+	dirty    bool
+}
+
+type repoRootCacheEntry struct {
+	Root     string    `json:"root"`
+	Resolved time.Time `json:"resolved"`
+}
+
+// repoRootCall is an in-flight lookup, which concurrent callers wait for.
+type repoRootCall struct {
+	done chan struct{}
+	root string
+	err  error
+}
+
+// DefaultRepoRootResolver is used by MakeUpstreamSourceTarball, and caches
+// in the user's cache directory, e.g. ~/.cache/dh-make-golang.
+var DefaultRepoRootResolver = &RepoRootResolver{CachePath: defaultRepoRootCachePath()}
+
+func defaultRepoRootCachePath() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "dh-make-golang", "repo-roots.json")
+}
+
+// knownRepoRoot returns the repository root of importPath if it is on a
+// known hoster with a fixed layout.
+func knownRepoRoot(importPath string) (string, bool) {
+	parts := strings.Split(importPath, "/")
+	depth := knownHosts[parts[0]].rootDepth
+	if parts[0] == "gopkg.in" && len(parts) > 1 {
+		// gopkg.in/yaml.v3 or gopkg.in/user/pkg.v1
+		depth = 3
+		if strings.Contains(parts[1], ".v") {
+			depth = 2
+		}
+	}
+	if depth == 0 || len(parts) < depth {
+		return "", false
+	}
+	return strings.Join(parts[:depth], "/"), true
+}
+
+func (r *RepoRootResolver) load() {
+	r.cache = make(map[string]repoRootCacheEntry)
+	r.calls = make(map[string]*repoRootCall)
+	if r.CachePath == "" {
+		return
+	}
+	b, err := os.ReadFile(r.CachePath)
+	if err != nil {
+		return // no cache yet
+	}
+	if err := json.Unmarshal(b, &r.cache); err != nil {
+		log.Printf("Ignoring invalid repository root cache %s: %v\n", r.CachePath, err)
+		r.cache = make(map[string]repoRootCacheEntry)
+	}
+}
+
+// RepoRoot returns the repository root of importPath.
+func (r *RepoRootResolver) RepoRoot(ctx context.Context, importPath string) (string, error) {
+	root, err := r.repoRoot(ctx, importPath)
+	r.save()
+	return root, err
+}
+
+// RepoRoots resolves the given import paths concurrently and returns their
+// repository roots. Import paths which cannot be resolved are logged and
+// left out; an error is only returned when ctx is done.
+func (r *RepoRootResolver) RepoRoots(ctx context.Context, importPaths []string) (map[string]string, error) {
+	var (
+		mu    sync.Mutex
+		roots = make(map[string]string)
+		eg    errgroup.Group
+	)
+	limit := r.Concurrency
+	if limit <= 0 {
+		limit = 8
+	}
+	eg.SetLimit(limit)
+	for _, importPath := range importPaths {
+		eg.Go(func() error {
+			root, err := r.repoRoot(ctx, importPath)
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Printf("Could not determine repo path for import path %q: %v\n", importPath, err)
+				}
+				return nil
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			roots[importPath] = root
+			return nil
+		})
+	}
+	eg.Wait()
+	r.save()
+	return roots, ctx.Err()
+}
+
+func (r *RepoRootResolver) repoRoot(ctx context.Context, importPath string) (string, error) {
+	if root, ok := knownRepoRoot(importPath); ok {
+		return root, nil
+	}
+
+	r.loadOnce.Do(r.load)
+	r.mu.Lock()
+	if entry, ok := r.cache[importPath]; ok && time.Since(entry.Resolved) < repoRootCacheTTL {
+		r.mu.Unlock()
+		return entry.Root, nil
+	}
+	c, ok := r.calls[importPath]
+	if !ok {
+		c = &repoRootCall{done: make(chan struct{})}
+		r.calls[importPath] = c
+		// Other callers might wait for the lookup, so it is not
+		// cancelled with ctx; its result is cached for the next run.
+		go r.resolve(context.WithoutCancel(ctx), importPath, c)
+	}
+	r.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return "", ctx.Err()
+	case <-c.done:
+		return c.root, c.err
+	}
+}
+
+func (r *RepoRootResolver) resolve(ctx context.Context, importPath string, c *repoRootCall) {
+	lookup := r.lookup
+	if lookup == nil {
+		lookup = func(ctx context.Context, importPath string) (string, error) {
+			rr, err := ResolveRepoRoot(ctx, importPath)
+			if err != nil {
+				return "", err
+			}
+			return rr.Root, nil
+		}
+	}
+	c.root, c.err = lookup(ctx, importPath)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.calls, importPath)
+	if c.err == nil {
+		r.cache[importPath] = repoRootCacheEntry{Root: c.root, Resolved: time.Now()}
+		r.dirty = true
+	}
+	close(c.done)
+}
+
+// save writes the cache if it changed.
+func (r *RepoRootResolver) save() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.dirty || r.CachePath == "" {
+		return
+	}
+	if err := writeRepoRootCache(r.CachePath, r.cache); err != nil {
+		log.Printf("Could not write repository root cache: %v\n", err)
+		return
+	}
+	r.dirty = false
+}
+
+func writeRepoRootCache(path string, cache map[string]repoRootCacheEntry) error {
+	b, err := json.Marshal(cache)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	// Write atomically, concurrent runs might read the cache.
+	f, err := os.CreateTemp(filepath.Dir(path), ".repo-roots-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
@@ -0,0 +1,138 @@
+package golangdeb
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestKnownRepoRoot(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		want string
+	}{
+		{"github.com/Debian/dh-make-golang", "github.com/Debian/dh-make-golang"},
+		{"github.com/google/go-github/v60/github", "github.com/google/go-github"},
+		{"golang.org/x/net/html", "golang.org/x/net"},
+		{"k8s.io/client-go/kubernetes", "k8s.io/client-go"},
+		{"gopkg.in/yaml.v3", "gopkg.in/yaml.v3"},
+		{"gopkg.in/check.v1/sub", "gopkg.in/check.v1"},
+		{"gopkg.in/src-d/go-git.v4/plumbing", "gopkg.in/src-d/go-git.v4"},
+		{"github.com/Debian", ""},
+		{"gitlab.com/group/subgroup/project", ""},
+		{"example.org/pkg", ""},
+	} {
+		got, ok := knownRepoRoot(tt.in)
+		if got != tt.want || ok != (tt.want != "") {
+			t.Errorf("knownRepoRoot(%q) = %q, %v, want %q", tt.in, got, ok, tt.want)
+		}
+	}
+}
+
+// countingLookup returns a lookup which resolves import paths to their
+// first two elements, and counts its calls.
+func countingLookup(calls *atomic.Int32) func(context.Context, string) (string, error) {
+	return func(ctx context.Context, importPath string) (string, error) {
+		calls.Add(1)
+		if strings.HasPrefix(importPath, "invalid.example") {
+			return "", errors.New("no go-import meta tag")
+		}
+		parts := strings.SplitN(importPath, "/", 3)
+		return strings.Join(parts[:min(2, len(parts))], "/"), nil
+	}
+}
+
+func TestRepoRoots(t *testing.T) {
+	var calls atomic.Int32
+	r := &RepoRootResolver{
+		CachePath: filepath.Join(t.TempDir(), "repo-roots.json"),
+		lookup:    countingLookup(&calls),
+	}
+	paths := []string{
+		"github.com/Debian/dh-make-golang",
+		"example.org/zap",
+		"example.org/zap",
+		"example.org/zap/zapcore",
+		"invalid.example/pkg",
+	}
+	got, err := r.RepoRoots(t.Context(), paths)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"github.com/Debian/dh-make-golang": "github.com/Debian/dh-make-golang",
+		"example.org/zap":                  "example.org/zap",
+		"example.org/zap/zapcore":          "example.org/zap",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("RepoRoots: unexpected result (-want +got):\n%s", diff)
+	}
+	// github.com is resolved without lookup, example.org/zap only once.
+	if n := calls.Load(); n != 3 {
+		t.Errorf("RepoRoots: got %d lookups, want 3", n)
+	}
+
+	// A new resolver reads the cache instead of looking up again, except
+	// for the import path which could not be resolved.
+	calls.Store(0)
+	r = &RepoRootResolver{CachePath: r.CachePath, lookup: countingLookup(&calls)}
+	got, err = r.RepoRoots(t.Context(), paths)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("RepoRoots (cached): unexpected result (-want +got):\n%s", diff)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("RepoRoots (cached): got %d lookups, want 1", n)
+	}
+}
+
+func TestRepoRootConcurrentLookups(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	r := &RepoRootResolver{
+		lookup: func(ctx context.Context, importPath string) (string, error) {
+			calls.Add(1)
+			<-release
+			return "example.org/repo", nil
+		},
+	}
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if root, err := r.RepoRoot(t.Context(), "example.org/repo/pkg"); err != nil || root != "example.org/repo" {
+				t.Errorf("RepoRoot: got %q, %v", root, err)
+			}
+		}()
+	}
+	close(release)
+	wg.Wait()
+	if n := calls.Load(); n != 1 {
+		t.Errorf("RepoRoot: got %d lookups, want 1", n)
+	}
+}
+
+func TestRepoRootsCancel(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+	r := &RepoRootResolver{
+		lookup: func(ctx context.Context, importPath string) (string, error) {
+			<-block
+			return importPath, nil
+		},
+	}
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	if _, err := r.RepoRoots(ctx, []string{"example.org/a", "example.org/b"}); !errors.Is(err, context.Canceled) {
+		t.Errorf("RepoRoots: got error %v, want %v", err, context.Canceled)
+	}
+}
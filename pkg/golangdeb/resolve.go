@@ -0,0 +1,412 @@
+package golangdeb
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+	"golang.org/x/net/html"
+)
+
+// RepoRoot describes the repository providing a Go import path.
+type RepoRoot struct {
+	Root   string // import path of the repository root, e.g. golang.org/x/net
+	Repo   string // repository URL, or module proxy URL if VCS is "mod"
+	VCS    string // "git", "hg", "svn", "bzr", or "mod" if only available from a module proxy
+	Module string // module path providing the import path, if known
+}
+
+// errNotFound is returned by module proxies which do not know a module.
+var errNotFound = errors.New("not found")
+
+// goModuleEnv is the part of the go environment used for module lookups.
+type goModuleEnv struct {
+	GOPROXY    string
+	GONOPROXY  string // defaults to GOPRIVATE
+	GOINSECURE string
+}
+
+// goModuleEnvironment returns the module lookup settings, which are only
+// read once per process (see readGoModuleEnv). Tests replace it to read
+// them again.
+var goModuleEnvironment = sync.OnceValues(readGoModuleEnv)
+
+// readGoModuleEnv reads the module lookup settings with "go env", which
+// honours GOFLAGS and the go env file, just like "go get".
+func readGoModuleEnv() (goModuleEnv, error) {
+	var env goModuleEnv
+	cmd := exec.Command("go", "env", "-json", "GOPROXY", "GONOPROXY", "GOINSECURE")
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return env, fmt.Errorf("go env: %w", err)
+	}
+	if err := json.Unmarshal(out, &env); err != nil {
+		return env, fmt.Errorf("go env: %w", err)
+	}
+	return env, nil
+}
+
+// goProxy is an entry of GOPROXY: a proxy URL, "direct" or "off".
+type goProxy struct {
+	url string
+	// fallBackOnError is set for entries followed by "|", after which the
+	// next entry is tried on any error, not just if the module is not found.
+	fallBackOnError bool
+}
+
+func parseGoProxy(value string) []goProxy {
+	var proxies []goProxy
+	for value != "" {
+		var p goProxy
+		i := strings.IndexAny(value, ",|")
+		if i < 0 {
+			p.url, value = value, ""
+		} else {
+			p.url, p.fallBackOnError, value = value[:i], value[i] == '|', value[i+1:]
+		}
+		if p.url = strings.TrimSpace(p.url); p.url != "" {
+			proxies = append(proxies, p)
+		}
+	}
+	return proxies
+}
+
+// ResolveRepoRoot determines the repository providing importPath the way
+// "go get" does: it asks the module proxies in GOPROXY for the origin of the
+// module, and falls back to go-get discovery for "direct" and for modules
+// matching GONOPROXY or GOPRIVATE.
+func ResolveRepoRoot(ctx context.Context, importPath string) (*RepoRoot, error) {
+	// github.com needs no lookup, and is by far the most common hoster.
+	if root, ok := knownRepoRoot(importPath); ok && strings.HasPrefix(root, "github.com/") {
+		return &RepoRoot{Root: root, Repo: "https://" + root, VCS: "git"}, nil
+	}
+
+	env, err := goModuleEnvironment()
+	if err != nil {
+		return nil, err
+	}
+	insecure := module.MatchPrefixPatterns(env.GOINSECURE, importPath)
+	proxies := parseGoProxy(env.GOPROXY)
+	if module.MatchPrefixPatterns(env.GONOPROXY, importPath) {
+		proxies = []goProxy{{url: "direct"}}
+	}
+	var errs []error
+	for _, p := range proxies {
+		var rr *RepoRoot
+		switch p.url {
+		case "off":
+			err = errors.New("module lookup disabled by GOPROXY=off")
+		case "direct":
+			rr, err = discoverRepoRoot(ctx, importPath, insecure)
+		default:
+			rr, err = proxyRepoRoot(ctx, p.url, importPath, insecure)
+		}
+		if err == nil {
+			return rr, nil
+		}
+		errs = append(errs, err)
+		if !p.fallBackOnError && !errors.Is(err, errNotFound) {
+			break
+		}
+	}
+	if len(errs) == 0 {
+		return nil, errors.New("GOPROXY is empty")
+	}
+	return nil, errors.Join(errs...)
+}
+
+// moduleInfo is the JSON returned by the .info and @latest module proxy
+// endpoints.
+type moduleInfo struct {
+	Version string
+	Time    time.Time
+	Origin  *struct {
+		VCS    string
+		URL    string
+		Subdir string
+	}
+}
+
+// repoRoot returns the repository of modPath recorded in info, if any.
+func (info *moduleInfo) repoRoot(modPath string) (*RepoRoot, bool) {
+	o := info.Origin
+	if o == nil || o.VCS == "" || o.URL == "" {
+		return nil, false
+	}
+	return &RepoRoot{
+		Root:   repoRootFromModule(modPath, o.Subdir),
+		Repo:   o.URL,
+		VCS:    o.VCS,
+		Module: modPath,
+	}, true
+}
+
+// proxyRepoRoot finds the module providing importPath on the module proxy
+// at proxyURL, by trying importPath and its parents like "go get".
+func proxyRepoRoot(ctx context.Context, proxyURL, importPath string, insecure bool) (*RepoRoot, error) {
+	for modPath := importPath; ; {
+		info, err := proxyLatest(ctx, proxyURL, modPath)
+		if err == nil {
+			return repoRootFromModuleInfo(ctx, proxyURL, modPath, info, insecure), nil
+		}
+		if !errors.Is(err, errNotFound) {
+			return nil, err
+		}
+		i := strings.LastIndex(modPath, "/")
+		if i < 0 {
+			return nil, fmt.Errorf("%s: no module providing %s: %w", proxyURL, importPath, err)
+		}
+		modPath = modPath[:i]
+	}
+}
+
+// proxyLatest returns the latest version of modPath on the module proxy at
+// proxyURL: the latest release, else the latest pre-release, else what the
+// proxy reports as latest (usually a pseudo-version).
+func proxyLatest(ctx context.Context, proxyURL, modPath string) (*moduleInfo, error) {
+	escaped, err := module.EscapePath(modPath)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errNotFound, err)
+	}
+	list, err := proxyFetch(ctx, proxyURL, escaped+"/@v/list")
+	if err != nil {
+		return nil, err
+	}
+	var release, prerelease string
+	for _, v := range strings.Fields(string(list)) {
+		switch {
+		case !semver.IsValid(v) || module.IsPseudoVersion(v):
+		case semver.Prerelease(v) == "":
+			if semver.Compare(v, release) > 0 {
+				release = v
+			}
+		default:
+			if semver.Compare(v, prerelease) > 0 {
+				prerelease = v
+			}
+		}
+	}
+	latest := cmp.Or(release, prerelease)
+	endpoint := escaped + "/@latest"
+	if latest != "" {
+		v, err := module.EscapeVersion(latest)
+		if err != nil {
+			return nil, err
+		}
+		endpoint = escaped + "/@v/" + v + ".info"
+	}
+	b, err := proxyFetch(ctx, proxyURL, endpoint)
+	if err != nil {
+		return nil, err
+	}
+	var info moduleInfo
+	if err := json.Unmarshal(b, &info); err != nil {
+		return nil, fmt.Errorf("%s/%s: %w", proxyURL, endpoint, err)
+	}
+	return &info, nil
+}
+
+// proxyFetch returns the contents of endpoint on the module proxy at
+// proxyURL, which can be an http(s) or a file URL.
+func proxyFetch(ctx context.Context, proxyURL, endpoint string) ([]byte, error) {
+	u, err := url.Parse(proxyURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid GOPROXY URL %q: %w", proxyURL, err)
+	}
+	if u.Scheme == "file" {
+		b, err := os.ReadFile(filepath.Join(filepath.FromSlash(u.Path), filepath.FromSlash(endpoint)))
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("%w: %v", errNotFound, err)
+		}
+		return b, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, APITimeout)
+	defer cancel()
+	resp, err := HTTPGet(ctx, strings.TrimSuffix(proxyURL, "/")+"/"+endpoint)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return io.ReadAll(resp.Body)
+	case http.StatusNotFound, http.StatusGone:
+		return nil, fmt.Errorf("%s/%s: %w", proxyURL, endpoint, errNotFound)
+	default:
+		return nil, fmt.Errorf("%s/%s: %s", proxyURL, endpoint, resp.Status)
+	}
+}
+
+// repoRootFromModuleInfo returns the repository of modPath recorded by the
+// module proxy. Not all proxies record the origin of modules, in which case
+// go-get discovery is tried, and the proxy itself returned if that fails.
+func repoRootFromModuleInfo(ctx context.Context, proxyURL, modPath string, info *moduleInfo, insecure bool) *RepoRoot {
+	if rr, ok := info.repoRoot(modPath); ok {
+		return rr
+	}
+	if rr, err := discoverRepoRoot(ctx, modPath, insecure); err == nil && rr.VCS != "mod" {
+		rr.Module = modPath
+		return rr
+	}
+	return &RepoRoot{
+		Root:   repoRootFromModule(modPath, ""),
+		Repo:   proxyURL,
+		VCS:    "mod",
+		Module: modPath,
+	}
+}
+
+// repoRootFromModule returns the import path of the repository root of the
+// module modPath, which lives in the directory subdir of the repository.
+// The major version suffix of modPath is not part of the repository root,
+// e.g. github.com/google/go-github/v60 is in github.com/google/go-github.
+func repoRootFromModule(modPath, subdir string) string {
+	if subdir != "" {
+		if root, ok := strings.CutSuffix(modPath, "/"+subdir); ok {
+			return root
+		}
+	}
+	root := modPath
+	if prefix, major, ok := module.SplitPathVersion(root); ok && strings.HasPrefix(major, "/") {
+		root = prefix
+	}
+	if subdir != "" {
+		root = strings.TrimSuffix(root, "/"+subdir)
+	}
+	return root
+}
+
+// goImport is a <meta name="go-import" content="prefix vcs repo"> tag.
+type goImport struct {
+	prefix, vcs, repo string
+}
+
+func parseGoImports(r io.Reader) []goImport {
+	var imports []goImport
+	z := html.NewTokenizer(r)
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return imports
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := z.Token()
+			if token.Data == "body" {
+				return imports
+			}
+			if token.Data != "meta" {
+				continue
+			}
+			var name, content string
+			for _, attr := range token.Attr {
+				switch attr.Key {
+				case "name":
+					name = attr.Val
+				case "content":
+					content = attr.Val
+				}
+			}
+			if f := strings.Fields(content); name == "go-import" && len(f) == 3 {
+				imports = append(imports, goImport{prefix: f[0], vcs: f[1], repo: f[2]})
+			}
+		}
+	}
+}
+
+// discoverRepoRoot finds the repository of importPath using go-get
+// discovery, i.e. the go-import meta tags served at
+// https://importPath?go-get=1, or http:// if insecure.
+func discoverRepoRoot(ctx context.Context, importPath string, insecure bool) (*RepoRoot, error) {
+	body, err := fetchGoGet(ctx, "https://"+importPath+"?go-get=1")
+	if err != nil && insecure {
+		body, err = fetchGoGet(ctx, "http://"+importPath+"?go-get=1")
+	}
+	if err != nil {
+		return nil, err
+	}
+	var match *goImport
+	for _, imp := range parseGoImports(bytes.NewReader(body)) {
+		if imp.prefix != importPath && !strings.HasPrefix(importPath, imp.prefix+"/") {
+			continue
+		}
+		// Like "go get" in direct mode, prefer the repository to the
+		// module proxy if both are given.
+		if match == nil || (match.vcs == "mod" && imp.vcs != "mod") {
+			match = &imp
+		}
+	}
+	if match == nil {
+		return nil, fmt.Errorf("%s: no go-import meta tag", importPath)
+	}
+	switch match.vcs {
+	case "git", "hg", "svn", "bzr":
+	case "mod":
+		if info, err := proxyLatest(ctx, match.repo, match.prefix); err == nil {
+			if rr, ok := info.repoRoot(match.prefix); ok {
+				return rr, nil
+			}
+		}
+		return &RepoRoot{Root: match.prefix, Repo: match.repo, VCS: "mod", Module: match.prefix}, nil
+	default:
+		return nil, fmt.Errorf("%s: unsupported VCS %q", importPath, match.vcs)
+	}
+	if u, err := url.Parse(match.repo); err != nil || u.Scheme == "" {
+		return nil, fmt.Errorf("%s: invalid repository URL %q", importPath, match.repo)
+	}
+	return &RepoRoot{Root: match.prefix, Repo: match.repo, VCS: match.vcs}, nil
+}
+
+func fetchGoGet(ctx context.Context, url string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, APITimeout)
+	defer cancel()
+	resp, err := HTTPGet(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", url, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// vcsCreateArgs returns the arguments to the rr.VCS command which check out
+// the repository at rev (if not empty) into dir. git is handled by the
+// callers, as they need finer control.
+func vcsCreateArgs(rr *RepoRoot, dir, rev string) ([]string, error) {
+	switch rr.VCS {
+	case "hg":
+		if rev != "" {
+			return []string{"clone", "-u", rev, rr.Repo, dir}, nil
+		}
+		return []string{"clone", rr.Repo, dir}, nil
+	case "bzr":
+		if rev != "" {
+			return []string{"branch", "-r", rev, rr.Repo, dir}, nil
+		}
+		return []string{"branch", rr.Repo, dir}, nil
+	case "svn":
+		if rev != "" {
+			return []string{"checkout", "-r", rev, rr.Repo, dir}, nil
+		}
+		return []string{"checkout", rr.Repo, dir}, nil
+	case "mod":
+		return nil, fmt.Errorf("%s is only available from the module proxy %s", rr.Root, rr.Repo)
+	default:
+		return nil, fmt.Errorf("unsupported VCS %q", rr.VCS)
+	}
+}
@@ -0,0 +1,179 @@
+package golangdeb
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseGoProxy(t *testing.T) {
+	got := parseGoProxy("https://proxy.example.org|file:///srv/proxy, direct,")
+	want := []goProxy{
+		{url: "https://proxy.example.org", fallBackOnError: true},
+		{url: "file:///srv/proxy"},
+		{url: "direct"},
+	}
+	if diff := cmp.Diff(want, got, cmp.AllowUnexported(goProxy{})); diff != "" {
+		t.Errorf("parseGoProxy: unexpected result (-want +got):\n%s", diff)
+	}
+}
+
+func TestRepoRootFromModule(t *testing.T) {
+	for _, tt := range []struct {
+		mod, subdir, want string
+	}{
+		{"golang.org/x/net", "", "golang.org/x/net"},
+		{"github.com/google/go-github/v60", "", "github.com/google/go-github"},
+		{"cloud.google.com/go/storage", "storage", "cloud.google.com/go"},
+		{"example.org/repo/sub/v2", "sub/v2", "example.org/repo"},
+		{"example.org/repo/sub/v2", "sub", "example.org/repo"},
+		{"gopkg.in/yaml.v3", "", "gopkg.in/yaml.v3"},
+	} {
+		if got := repoRootFromModule(tt.mod, tt.subdir); got != tt.want {
+			t.Errorf("repoRootFromModule(%q, %q) = %q, want %q", tt.mod, tt.subdir, got, tt.want)
+		}
+	}
+}
+
+// writeFileProxy writes a module proxy serving the given modules (module
+// path → version → .info contents) to a temporary directory, and returns
+// its file:// URL.
+func writeFileProxy(t *testing.T, modules map[string]map[string]string) string {
+	dir := t.TempDir()
+	for mod, versions := range modules {
+		vdir := filepath.Join(dir, filepath.FromSlash(mod), "@v")
+		if err := os.MkdirAll(vdir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		var list []string
+		for version, info := range versions {
+			list = append(list, version)
+			if err := os.WriteFile(filepath.Join(vdir, version+".info"), []byte(info), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := os.WriteFile(filepath.Join(vdir, "list"), []byte(strings.Join(list, "\n")+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return "file://" + filepath.ToSlash(dir)
+}
+
+// setGoModuleEnv sets the environment read by readGoModuleEnv, and makes
+// goModuleEnvironment read it again, also after the test.
+func setGoModuleEnv(t *testing.T, goproxy, goprivate string) {
+	t.Setenv("GOPROXY", goproxy)
+	t.Setenv("GOPRIVATE", goprivate)
+	t.Setenv("GONOPROXY", "")
+	t.Setenv("GOINSECURE", "")
+	t.Setenv("GOFLAGS", "")
+	goModuleEnvironment = sync.OnceValues(readGoModuleEnv)
+	t.Cleanup(func() { goModuleEnvironment = sync.OnceValues(readGoModuleEnv) })
+}
+
+func TestGoModuleEnvironment(t *testing.T) {
+	setGoModuleEnv(t, "https://proxy.example.org", "")
+	env, err := goModuleEnvironment()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The settings are read once, not by every lookup.
+	t.Setenv("GOPROXY", "off")
+	if again, err := goModuleEnvironment(); err != nil || again != env {
+		t.Errorf("goModuleEnvironment() = %+v, %v, want %+v", again, err, env)
+	}
+	if env.GOPROXY != "https://proxy.example.org" {
+		t.Errorf("got GOPROXY %q, want https://proxy.example.org", env.GOPROXY)
+	}
+}
+
+func TestResolveRepoRootProxy(t *testing.T) {
+	proxy := writeFileProxy(t, map[string]map[string]string{
+		"example.org/foo": {
+			"v1.1.0":      `{"Version": "v1.1.0"}`,
+			"v1.2.0":      `{"Version": "v1.2.0", "Origin": {"VCS": "git", "URL": "https://git.example.org/foo.git", "Ref": "refs/tags/v1.2.0"}}`,
+			"v1.3.0-rc.1": `{"Version": "v1.3.0-rc.1"}`,
+		},
+		"example.org/mono/sub/v2": {
+			"v2.0.0": `{"Version": "v2.0.0", "Origin": {"VCS": "hg", "URL": "https://hg.example.org/mono", "Subdir": "sub"}}`,
+		},
+	})
+	setGoModuleEnv(t, proxy+",off", "")
+
+	for _, tt := range []struct {
+		importPath string
+		want       *RepoRoot
+	}{
+		{"example.org/foo", &RepoRoot{Root: "example.org/foo", Repo: "https://git.example.org/foo.git", VCS: "git", Module: "example.org/foo"}},
+		{"example.org/foo/bar/baz", &RepoRoot{Root: "example.org/foo", Repo: "https://git.example.org/foo.git", VCS: "git", Module: "example.org/foo"}},
+		{"example.org/mono/sub/v2/pkg", &RepoRoot{Root: "example.org/mono", Repo: "https://hg.example.org/mono", VCS: "hg", Module: "example.org/mono/sub/v2"}},
+		{"github.com/foo/bar/baz", &RepoRoot{Root: "github.com/foo/bar", Repo: "https://github.com/foo/bar", VCS: "git"}},
+	} {
+		got, err := ResolveRepoRoot(t.Context(), tt.importPath)
+		if err != nil {
+			t.Errorf("ResolveRepoRoot(%q): %v", tt.importPath, err)
+			continue
+		}
+		if diff := cmp.Diff(tt.want, got); diff != "" {
+			t.Errorf("ResolveRepoRoot(%q): unexpected result (-want +got):\n%s", tt.importPath, diff)
+		}
+	}
+
+	// Not found on the proxy, so "off" is reached.
+	if _, err := ResolveRepoRoot(t.Context(), "example.org/unknown"); err == nil || !strings.Contains(err.Error(), "GOPROXY=off") {
+		t.Errorf("ResolveRepoRoot(unknown module): got error %v, want lookup disabled", err)
+	}
+}
+
+func TestResolveRepoRootDirect(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/vanity", "/vanity/pkg":
+			fmt.Fprintf(w, `<html><head>
+<meta name="go-import" content="%[1]s/vanity mod https://%[1]s/proxy">
+<meta name="go-import" content="%[1]s/vanity git https://git.example.org/vanity">
+<meta name="go-import" content="%[1]s/other git https://git.example.org/other">
+</head><body></body></html>`, r.Host)
+		case "/modonly":
+			fmt.Fprintf(w, `<meta name="go-import" content="%[1]s/modonly mod https://%[1]s/proxy">`, r.Host)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+	oldClient := HTTPClient
+	HTTPClient = ts.Client()
+	t.Cleanup(func() { HTTPClient = oldClient })
+	host := strings.TrimPrefix(ts.URL, "https://")
+
+	// GOPRIVATE modules are not looked up on the proxy.
+	setGoModuleEnv(t, "off", host)
+
+	for _, tt := range []struct {
+		importPath string
+		want       *RepoRoot
+	}{
+		{host + "/vanity/pkg", &RepoRoot{Root: host + "/vanity", Repo: "https://git.example.org/vanity", VCS: "git"}},
+		{host + "/modonly", &RepoRoot{Root: host + "/modonly", Repo: "https://" + host + "/proxy", VCS: "mod", Module: host + "/modonly"}},
+	} {
+		got, err := ResolveRepoRoot(t.Context(), tt.importPath)
+		if err != nil {
+			t.Errorf("ResolveRepoRoot(%q): %v", tt.importPath, err)
+			continue
+		}
+		if diff := cmp.Diff(tt.want, got); diff != "" {
+			t.Errorf("ResolveRepoRoot(%q): unexpected result (-want +got):\n%s", tt.importPath, diff)
+		}
+	}
+
+	if _, err := ResolveRepoRoot(t.Context(), host+"/missing"); err == nil {
+		t.Errorf("ResolveRepoRoot(%q): got nil error", host+"/missing")
+	}
+}
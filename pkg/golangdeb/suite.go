@@ -0,0 +1,74 @@
+package golangdeb
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TargetSuite describes the suite a package is being prepared for.
+type TargetSuite struct {
+	Name          string   // e.g. "sid" or "bookworm-backports"
+	Distribution  string   // for debian/changelog
+	Branch        string   // DEP-14 packaging branch
+	VersionSuffix string   // appended to the Debian version, e.g. "~bpo12+1"
+	LookupSuites  []string // suites which can satisfy build dependencies
+	Release       string   // version of the stable release, empty for the development suite
+}
+
+// ParseSuite returns the TargetSuite for the given suite name, which is
+// one of the development suite ("sid", also known as "unstable", for
+// Debian), the experimental suite, a release codename like "bookworm" for
+// stable updates, or a codename followed by "-backports" or
+// "-backports-sloppy".
+func (d *DistroProfile) ParseSuite(name string) (TargetSuite, error) {
+	switch {
+	case d.isDevelSuite(name):
+		return TargetSuite{
+			Name:         d.DevelSuite,
+			Distribution: "UNRELEASED",
+			Branch:       d.BranchPrefix + d.DevelSuite,
+			LookupSuites: []string{d.DevelSuite},
+		}, nil
+	case d.ExperimentalSuite != "" && name == d.ExperimentalSuite:
+		return TargetSuite{
+			Name:         name,
+			Distribution: name,
+			Branch:       d.BranchPrefix + name,
+			LookupSuites: []string{d.DevelSuite, name},
+		}, nil
+	}
+
+	codename, pocket, _ := strings.Cut(name, "-")
+	release, ok := d.Releases[codename]
+	if !ok {
+		return TargetSuite{}, fmt.Errorf("unknown %s suite %q, want %s or a release codename (%s) with optional -backports suffix",
+			d.Name, name, d.DevelSuite, strings.Join(d.releaseCodenames(), ", "))
+	}
+	ts := TargetSuite{
+		Name:         name,
+		Distribution: name,
+		Branch:       d.BranchPrefix + name,
+		LookupSuites: []string{codename},
+		Release:      release,
+	}
+	switch pocket {
+	case "":
+		ts.VersionSuffix = fmt.Sprintf(d.StableUpdateSuffix, release)
+	case "backports":
+		ts.VersionSuffix = fmt.Sprintf(d.BackportSuffix, release)
+		ts.LookupSuites = append(ts.LookupSuites, name)
+	case "backports-sloppy":
+		ts.VersionSuffix = fmt.Sprintf(d.BackportSuffix, release)
+		ts.LookupSuites = append(ts.LookupSuites, codename+"-backports", name)
+	default:
+		return TargetSuite{}, fmt.Errorf("unknown %s suite %q, want %s, %s-backports or %s-backports-sloppy", d.Name, name, codename, codename, codename)
+	}
+	return ts, nil
+}
+
+// NeedsBackports returns whether build dependencies which are only
+// available in the development suite need to be backported to the suite
+// first.
+func (ts TargetSuite) NeedsBackports() bool {
+	return ts.Release != ""
+}
@@ -0,0 +1,107 @@
+package golangdeb
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseSuite(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		want TargetSuite
+	}{
+		{"", TargetSuite{
+			Name:         "sid",
+			Distribution: "UNRELEASED",
+			Branch:       "debian/sid",
+			LookupSuites: []string{"sid"},
+		}},
+		{"unstable", TargetSuite{
+			Name:         "sid",
+			Distribution: "UNRELEASED",
+			Branch:       "debian/sid",
+			LookupSuites: []string{"sid"},
+		}},
+		{"experimental", TargetSuite{
+			Name:         "experimental",
+			Distribution: "experimental",
+			Branch:       "debian/experimental",
+			LookupSuites: []string{"sid", "experimental"},
+		}},
+		{"bookworm", TargetSuite{
+			Name:          "bookworm",
+			Distribution:  "bookworm",
+			Branch:        "debian/bookworm",
+			VersionSuffix: "+deb12u1",
+			LookupSuites:  []string{"bookworm"},
+			Release:       "12",
+		}},
+		{"bookworm-backports", TargetSuite{
+			Name:          "bookworm-backports",
+			Distribution:  "bookworm-backports",
+			Branch:        "debian/bookworm-backports",
+			VersionSuffix: "~bpo12+1",
+			LookupSuites:  []string{"bookworm", "bookworm-backports"},
+			Release:       "12",
+		}},
+		{"bullseye-backports-sloppy", TargetSuite{
+			Name:          "bullseye-backports-sloppy",
+			Distribution:  "bullseye-backports-sloppy",
+			Branch:        "debian/bullseye-backports-sloppy",
+			VersionSuffix: "~bpo11+1",
+			LookupSuites:  []string{"bullseye", "bullseye-backports", "bullseye-backports-sloppy"},
+			Release:       "11",
+		}},
+	} {
+		got, err := DebianDistro.ParseSuite(tt.name)
+		if err != nil {
+			t.Errorf("parseSuite(%q): %v", tt.name, err)
+			continue
+		}
+		if diff := cmp.Diff(tt.want, got, cmp.AllowUnexported(TargetSuite{})); diff != "" {
+			t.Errorf("parseSuite(%q): diff (-want +got):\n%s", tt.name, diff)
+		}
+	}
+
+	for _, name := range []string{"stable", "hamm", "bookworm-proposed-updates"} {
+		if _, err := DebianDistro.ParseSuite(name); err == nil {
+			t.Errorf("parseSuite(%q) unexpectedly succeeded", name)
+		}
+	}
+}
+
+func TestParseSuiteUbuntu(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		want TargetSuite
+	}{
+		{"", TargetSuite{
+			Name:         "devel",
+			Distribution: "UNRELEASED",
+			Branch:       "ubuntu/devel",
+			LookupSuites: []string{"devel"},
+		}},
+		{"noble-backports", TargetSuite{
+			Name:          "noble-backports",
+			Distribution:  "noble-backports",
+			Branch:        "ubuntu/noble-backports",
+			VersionSuffix: "~bpo24.04.1",
+			LookupSuites:  []string{"noble", "noble-backports"},
+			Release:       "24.04",
+		}},
+	} {
+		got, err := UbuntuDistro.ParseSuite(tt.name)
+		if err != nil {
+			t.Errorf("parseSuite(%q): %v", tt.name, err)
+			continue
+		}
+		if diff := cmp.Diff(tt.want, got, cmp.AllowUnexported(TargetSuite{})); diff != "" {
+			t.Errorf("parseSuite(%q): diff (-want +got):\n%s", tt.name, diff)
+		}
+	}
+
+	if _, err := UbuntuDistro.ParseSuite("experimental"); err == nil {
+		t.Errorf("parseSuite(%q) unexpectedly succeeded", "experimental")
+	}
+}
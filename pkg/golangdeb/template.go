@@ -1,6 +1,7 @@
-package main
+package golangdeb
 
 import (
+	"cmp"
 	"fmt"
 	"log"
 	"os"
@@ -11,10 +12,63 @@ import (
 	"time"
 )
 
-func writeTemplates(dir, gopkg, debsrc, debLib, debProg, debversion string,
-	pkgType packageType, dependencies []string, u *upstream,
-	dep14, pristineTar bool,
+const (
+	// DebhelperCompatLevel is the debhelper compat level used for new packages.
+	DebhelperCompatLevel = 13
+
+	// StandardsVersion is the Debian Policy version new packages comply with.
+	StandardsVersion = "4.7.0"
+
+	// GoTeamMaintainer is the Maintainer of all packages of the Go team.
+	GoTeamMaintainer = "Debian Go Packaging Team <team+pkg-go@tracker.debian.org>"
+
+	// GoTeamSalsaURL is where the Go team hosts its packaging repositories.
+	GoTeamSalsaURL = "https://salsa.debian.org/go-team/packages/"
+)
+
+// Generator writes the debian/ directory of new packages.
+type Generator struct {
+	// Metadata is used to fill in the license, copyright, descriptions and
+	// the upstream URLs, see FetchMetadata. Nil means nothing is known.
+	Metadata *Metadata
+
+	// WrapAndSort is how multi-line fields are formatted, see
+	// ParseWrapAndSort. Empty means "at".
+	WrapAndSort string
+}
+
+// ParseWrapAndSort normalizes the wrap-and-sort(1) options in value (e.g.
+// "ta") to one of the styles "a", "at" and "ast".
+func ParseWrapAndSort(value string) (string, error) {
+	switch strings.TrimSpace(value) {
+	case "a":
+		// Current default, also what "cme fix dpkg" generates
+		return "a", nil
+	case "at", "ta":
+		// -t, --trailing-comma, preferred by Martina Ferrari
+		// and currently used in quite a few packages
+		return "at", nil
+	case "ast", "ats", "sat", "sta", "tas", "tsa":
+		// -s, --short-indent too, proposed by Guillem Jover
+		return "ast", nil
+	}
+	return "", fmt.Errorf("%q is not a valid value for -wrap-and-sort", value)
+}
+
+// WriteTemplates creates the debian/ directory in dir.
+func (g *Generator) WriteTemplates(dir, gopkg, debsrc, debLib, debProg, debversion string,
+	pkgType PackageType, dependencies []string, u *Upstream,
+	d *DistroProfile, suite TargetSuite, dep14, pristineTar bool,
 ) error {
+	switch g.WrapAndSort {
+	case "", "a", "at", "ast":
+	default:
+		return fmt.Errorf("invalid wrap-and-sort style %q", g.WrapAndSort)
+	}
+	m := g.Metadata
+	if m == nil {
+		m = &Metadata{}
+	}
 
 	if err := os.Mkdir(filepath.Join(dir, "debian"), 0755); err != nil {
 		// If upstream debian dir exists, try to move it aside, and then below.
@@ -37,21 +91,36 @@ func writeTemplates(dir, gopkg, debsrc, debLib, debProg, debversion string,
 	if err := writeDebianGitIgnore(dir, debLib, debProg, pkgType); err != nil {
 		return fmt.Errorf("write debian/.gitignore: %w", err)
 	}
-	if err := writeDebianChangelog(dir, debsrc, debversion); err != nil {
+	if err := writeDebianChangelog(dir, debsrc, debversion, suite.Distribution); err != nil {
 		return fmt.Errorf("write changelog: %w", err)
 	}
-	if err := writeDebianControl(dir, gopkg, debsrc, debLib, debProg, pkgType, dependencies); err != nil {
+	// Vendored dependencies need no build dependencies.
+	var unresolvedDeps []string
+	if !u.Vendored {
+		unresolvedDeps = u.UnresolvedDeps
+	}
+	if err := g.writeDebianControl(m, dir, gopkg, debsrc, debLib, debProg, pkgType, dependencies, unresolvedDeps, u.VendorMods, d); err != nil {
 		return fmt.Errorf("write control: %w", err)
 	}
-	if err := writeDebianCopyright(dir, gopkg, u.vendorDirs, u.hasGodeps); err != nil {
+	// Upstream vendor/ directories are replaced, not excluded, when vendoring
+	// into the orig tarball.
+	excludedVendorDirs := u.VendorDirs
+	if u.Vendored && !u.VendorComponent {
+		excludedVendorDirs = nil
+	}
+	if err := g.writeDebianCopyright(m, dir, gopkg, excludedVendorDirs, u.HasGodeps, u.VendorMods); err != nil {
 		return fmt.Errorf("write copyright: %w", err)
 	}
 	if err := writeDebianRules(dir, pkgType); err != nil {
 		return fmt.Errorf("write rules: %w", err)
 	}
 
-	var repack bool = len(u.vendorDirs) > 0 || u.hasGodeps
-	if err := writeDebianWatch(dir, gopkg, debsrc, u.hasRelease, repack); err != nil {
+	var repack bool = len(excludedVendorDirs) > 0 || u.HasGodeps
+	var components []string
+	if u.VendorComponent {
+		components = append(components, VendorComponent)
+	}
+	if err := writeDebianWatch(m, dir, gopkg, debsrc, u.HasRelease, repack, components); err != nil {
 		return fmt.Errorf("write watch: %w", err)
 	}
 
@@ -61,22 +130,22 @@ func writeTemplates(dir, gopkg, debsrc, debLib, debProg, debversion string,
 	if err := writeDebianPackageInstall(dir, debLib, debProg, pkgType); err != nil {
 		return fmt.Errorf("write install: %w", err)
 	}
-	if err := writeDebianUpstreamMetadata(dir, gopkg); err != nil {
+	if err := writeDebianUpstreamMetadata(m, dir, gopkg); err != nil {
 		return fmt.Errorf("write upstream metadata: %w", err)
 	}
 
-	if err := writeDebianGbpConf(dir, dep14, pristineTar); err != nil {
+	if err := writeDebianGbpConf(dir, suite.Branch, dep14, pristineTar, components); err != nil {
 		return fmt.Errorf("write gbp conf: %w", err)
 	}
 
-	if err := writeDebianGitLabCI(dir); err != nil {
+	if err := writeDebianGitLabCI(dir, suite); err != nil {
 		return fmt.Errorf("write GitLab CI: %w", err)
 	}
 
 	return nil
 }
 
-func writeDebianGitIgnore(dir, debLib, debProg string, pkgType packageType) error {
+func writeDebianGitIgnore(dir, debLib, debProg string, pkgType PackageType) error {
 	f, err := os.Create(filepath.Join(dir, "debian", ".gitignore"))
 	if err != nil {
 		return err
@@ -92,75 +161,58 @@ func writeDebianGitIgnore(dir, debLib, debProg string, pkgType packageType) erro
 	fmt.Fprintf(f, "/files\n")
 
 	switch pkgType {
-	case typeLibrary:
+	case TypeLibrary:
 		fmt.Fprintf(f, "/%s/\n", debLib)
-	case typeProgram:
+	case TypeProgram:
 		fmt.Fprintf(f, "/%s/\n", debProg)
-	case typeLibraryProgram:
+	case TypeLibraryProgram:
 		fallthrough
-	case typeProgramLibrary:
+	case TypeProgramLibrary:
 		fmt.Fprintf(f, "/%s/\n", debLib)
 		fmt.Fprintf(f, "/%s/\n", debProg)
 	default:
-		log.Fatalf("Invalid pkgType %d in writeDebianGitIgnore(), aborting", pkgType)
+		return fmt.Errorf("invalid package type %d", pkgType)
 	}
 
 	return nil
 }
 
-func writeDebianChangelog(dir, debsrc, debversion string) error {
+func writeDebianChangelog(dir, debsrc, debversion, distribution string) error {
 	f, err := os.Create(filepath.Join(dir, "debian", "changelog"))
 	if err != nil {
 		return err
 	}
 	defer f.Close()
 
-	fmt.Fprintf(f, "%s (%s) UNRELEASED; urgency=medium\n", debsrc, debversion)
+	fmt.Fprintf(f, "%s (%s) %s; urgency=medium\n", debsrc, debversion, distribution)
 	fmt.Fprintf(f, "\n")
 	fmt.Fprintf(f, "  * Initial release (Closes: TODO)\n")
 	fmt.Fprintf(f, "\n")
 	fmt.Fprintf(f, " -- %s <%s>  %s\n",
-		getDebianName(),
-		getDebianEmail(),
+		DebianName(),
+		DebianEmail(),
 		time.Now().Format("Mon, 02 Jan 2006 15:04:05 -0700"))
 
 	return nil
 }
 
-func fprintfControlField(f *os.File, field string, valueArray []string) {
-	switch wrapAndSort {
+func (g *Generator) fprintfControlField(f *os.File, field string, valueArray []string) {
+	switch g.WrapAndSort {
 	case "a":
-		// Current default, also what "cme fix dpkg" generates
 		fmt.Fprintf(f, "%s: %s\n", field, strings.Join(valueArray, ",\n"+strings.Repeat(" ", len(field)+2)))
-	case "at":
-		// -t, --trailing-comma, preferred by Martina Ferrari
-		// and currently used in quite a few packages
-		fmt.Fprintf(f, "%s: %s,\n", field, strings.Join(valueArray, ",\n"+strings.Repeat(" ", len(field)+2)))
 	case "ast":
-		// -s, --short-indent too, proposed by Guillem Jover
 		fmt.Fprintf(f, "%s:\n %s,\n", field, strings.Join(valueArray, ",\n "))
-	default:
-		log.Fatalf("%q is not a valid value for -wrap-and-sort, aborting.", wrapAndSort)
+	default: // "at"
+		fmt.Fprintf(f, "%s: %s,\n", field, strings.Join(valueArray, ",\n"+strings.Repeat(" ", len(field)+2)))
 	}
 }
 
-func addDescription(f *os.File, gopkg, comment string) {
-	description, err := getDescriptionForGopkg(gopkg)
-	if err != nil {
-		log.Printf("Could not determine description for %q: %v\n", gopkg, err)
-		description = "TODO: short description"
-	}
-	fmt.Fprintf(f, "Description: %s %s\n", description, comment)
-
-	longdescription, err := getLongDescriptionForGopkg(gopkg)
-	if err != nil {
-		log.Printf("Could not determine long description for %q: %v\n", gopkg, err)
-		longdescription = "TODO: long description"
-	}
-	fmt.Fprintln(f, longdescription)
+func addDescription(m *Metadata, f *os.File, comment string) {
+	fmt.Fprintf(f, "Description: %s %s\n", cmp.Or(m.Description, "TODO: short description"), comment)
+	fmt.Fprintln(f, cmp.Or(m.LongDescription, "TODO: long description"))
 }
 
-func addLibraryPackage(f *os.File, gopkg, debLib string, dependencies []string) {
+func (g *Generator) addLibraryPackage(m *Metadata, f *os.File, debLib string, dependencies []string) {
 	fmt.Fprintf(f, "\n")
 	fmt.Fprintf(f, "Package: %s\n", debLib)
 	fmt.Fprintf(f, "Architecture: all\n")
@@ -168,22 +220,22 @@ func addLibraryPackage(f *os.File, gopkg, debLib string, dependencies []string)
 	deps := dependencies
 	sort.Strings(deps)
 	deps = append(deps, "${misc:Depends}")
-	fprintfControlField(f, "Depends", deps)
-	addDescription(f, gopkg, "(library)")
+	g.fprintfControlField(f, "Depends", deps)
+	addDescription(m, f, "(library)")
 }
 
-func addProgramPackage(f *os.File, gopkg, debProg string) {
+func (g *Generator) addProgramPackage(m *Metadata, f *os.File, debProg string) {
 	fmt.Fprintf(f, "\n")
 	fmt.Fprintf(f, "Package: %s\n", debProg)
 	fmt.Fprintf(f, "Section: TODO\n")
 	fmt.Fprintf(f, "Architecture: any\n")
 	deps := []string{"${misc:Depends}", "${shlibs:Depends}"}
-	fprintfControlField(f, "Depends", deps)
+	g.fprintfControlField(f, "Depends", deps)
 	fmt.Fprintf(f, "Static-Built-Using: ${misc:Static-Built-Using}\n")
-	addDescription(f, gopkg, "(program)")
+	addDescription(m, f, "(program)")
 }
 
-func writeDebianControl(dir, gopkg, debsrc, debLib, debProg string, pkgType packageType, dependencies []string) error {
+func (g *Generator) writeDebianControl(m *Metadata, dir, gopkg, debsrc, debLib, debProg string, pkgType PackageType, dependencies, unresolvedDeps []string, vendorMods []VendoredModule, d *DistroProfile) error {
 	f, err := os.Create(filepath.Join(dir, "debian", "control"))
 	if err != nil {
 		return err
@@ -194,52 +246,64 @@ func writeDebianControl(dir, gopkg, debsrc, debLib, debProg string, pkgType pack
 
 	fmt.Fprintf(f, "Source: %s\n", debsrc)
 	fmt.Fprintf(f, "Section: golang\n")
-	fmt.Fprintf(f, "Maintainer: Debian Go Packaging Team <team+pkg-go@tracker.debian.org>\n")
-	fprintfControlField(f, "Uploaders", []string{getDebianName() + " <" + getDebianEmail() + ">"})
+	fmt.Fprintf(f, "Maintainer: %s\n", d.Maintainer)
+	g.fprintfControlField(f, "Uploaders", []string{DebianName() + " <" + DebianEmail() + ">"})
 
 	builddeps := append([]string{
-		"debhelper-compat (= 13)",
+		fmt.Sprintf("debhelper-compat (= %d)", DebhelperCompatLevel),
 		"dh-sequence-golang",
 		"dpkg-build-api (= 1)",
 		"golang-any"},
 		dependencies...)
 	sort.Strings(builddeps)
-	fprintfControlField(f, "Build-Depends", builddeps)
+	g.fprintfControlField(f, "Build-Depends", builddeps)
+	for _, dep := range unresolvedDeps {
+		fmt.Fprintf(f, "# TODO: build-depend on the package providing %s\n", dep)
+	}
 
 	fmt.Fprintf(f, "Testsuite: autopkgtest-pkg-go\n")
-	fmt.Fprintf(f, "Standards-Version: 4.7.0\n")
-	fmt.Fprintf(f, "Vcs-Browser: https://salsa.debian.org/go-team/packages/%s\n", debsrc)
-	fmt.Fprintf(f, "Vcs-Git: https://salsa.debian.org/go-team/packages/%s.git\n", debsrc)
-	fmt.Fprintf(f, "Homepage: %s\n", getHomepageForGopkg(gopkg))
+	fmt.Fprintf(f, "Standards-Version: %s\n", StandardsVersion)
+	fmt.Fprintf(f, "Vcs-Browser: %s%s\n", d.VcsURL, debsrc)
+	fmt.Fprintf(f, "Vcs-Git: %s%s.git\n", d.VcsURL, debsrc)
+	fmt.Fprintf(f, "Homepage: %s\n", m.Homepage())
 	fmt.Fprintf(f, "XS-Go-Import-Path: %s\n", gopkg)
+	if len(vendorMods) > 0 {
+		// Built-Using and Static-Built-Using can only name Debian source
+		// packages, which covers the packaged dependencies (see
+		// addProgramPackage) but not the vendored modules. Like
+		// XS-Vendored-Sources-Rust, this field documents the latter in the
+		// .dsc, so that they can be tracked (e.g. for security issues).
+		vendored := make([]string, len(vendorMods))
+		for i, mod := range vendorMods {
+			vendored[i] = mod.Path + "@" + mod.Version
+		}
+		g.fprintfControlField(f, "XS-Vendored-Sources-Go", vendored)
+	}
 
 	// Binary package(s):
 
 	switch pkgType {
-	case typeLibrary:
-		addLibraryPackage(f, gopkg, debLib, dependencies)
-	case typeProgram:
-		addProgramPackage(f, gopkg, debProg)
-	case typeLibraryProgram:
-		addLibraryPackage(f, gopkg, debLib, dependencies)
-		addProgramPackage(f, gopkg, debProg)
-	case typeProgramLibrary:
-		addProgramPackage(f, gopkg, debProg)
-		addLibraryPackage(f, gopkg, debLib, dependencies)
+	case TypeLibrary:
+		g.addLibraryPackage(m, f, debLib, dependencies)
+	case TypeProgram:
+		g.addProgramPackage(m, f, debProg)
+	case TypeLibraryProgram:
+		g.addLibraryPackage(m, f, debLib, dependencies)
+		g.addProgramPackage(m, f, debProg)
+	case TypeProgramLibrary:
+		g.addProgramPackage(m, f, debProg)
+		g.addLibraryPackage(m, f, debLib, dependencies)
 	default:
-		log.Fatalf("Invalid pkgType %d in writeDebianControl(), aborting", pkgType)
+		return fmt.Errorf("invalid package type %d", pkgType)
 	}
 
 	return nil
 }
 
-func writeDebianCopyright(dir, gopkg string, vendorDirs []string, hasGodeps bool) error {
-	license, fulltext, err := getLicenseForGopkg(gopkg)
-	if err != nil {
-		log.Printf("Could not determine license for %q: %v\n", gopkg, err)
-		license = "TODO"
-		fulltext = "TODO"
-	}
+func (g *Generator) writeDebianCopyright(m *Metadata, dir, gopkg string, vendorDirs []string, hasGodeps bool, vendorMods []VendoredModule) error {
+	license := cmp.Or(m.License, "TODO")
+	fulltext := cmp.Or(m.LicenseText, " TODO")
+	copyright := cmp.Or(m.Copyright, "TODO")
 
 	f, err := os.Create(filepath.Join(dir, "debian", "copyright"))
 	if err != nil {
@@ -247,15 +311,9 @@ func writeDebianCopyright(dir, gopkg string, vendorDirs []string, hasGodeps bool
 	}
 	defer f.Close()
 
-	_, copyright, err := getAuthorAndCopyrightForGopkg(gopkg)
-	if err != nil {
-		log.Printf("Could not determine copyright for %q: %v\n", gopkg, err)
-		copyright = "TODO"
-	}
-
 	var indent = "  "
 	var linebreak = ""
-	if wrapAndSort == "ast" {
+	if g.WrapAndSort == "ast" {
 		indent = " "
 		linebreak = "\n"
 	}
@@ -271,7 +329,7 @@ func writeDebianCopyright(dir, gopkg string, vendorDirs []string, hasGodeps bool
 	}
 
 	fmt.Fprintf(f, "Format: https://www.debian.org/doc/packaging-manuals/copyright-format/1.0/\n")
-	fmt.Fprintf(f, "Source: %s\n", getHomepageForGopkg(gopkg))
+	fmt.Fprintf(f, "Source: %s\n", m.Homepage())
 	fmt.Fprintf(f, "Upstream-Name: %s\n", upstreamName)
 	fmt.Fprintf(f, "Upstream-Contact: TODO\n")
 	if len(vendorDirs) > 0 || hasGodeps {
@@ -288,19 +346,23 @@ func writeDebianCopyright(dir, gopkg string, vendorDirs []string, hasGodeps bool
 	fmt.Fprintf(f, "Copyright:%s %s\n", linebreak, copyright)
 	fmt.Fprintf(f, "License: %s\n", license)
 	fmt.Fprintf(f, "\n")
+	vendoredLicenses := writeVendoredCopyright(f, vendorMods, license, linebreak)
 	fmt.Fprintf(f, "Files:%s debian/*\n", linebreak)
-	fmt.Fprintf(f, "Copyright:%s %s %s <%s>\n", linebreak, time.Now().Format("2006"), getDebianName(), getDebianEmail())
+	fmt.Fprintf(f, "Copyright:%s %s %s <%s>\n", linebreak, time.Now().Format("2006"), DebianName(), DebianEmail())
 	fmt.Fprintf(f, "License: %s\n", license)
 	fmt.Fprintf(f, "Comment: Debian packaging is licensed under the same terms as upstream\n")
 	fmt.Fprintf(f, "\n")
 	fmt.Fprintf(f, "License: %s\n", license)
 	fmt.Fprint(f, fulltext)
 	fmt.Fprint(f, "\n")
+	for _, paragraph := range vendoredLicenses {
+		fmt.Fprintf(f, "\n%s", paragraph)
+	}
 
 	return nil
 }
 
-func writeDebianRules(dir string, pkgType packageType) error {
+func writeDebianRules(dir string, pkgType PackageType) error {
 	f, err := os.Create(filepath.Join(dir, "debian", "rules"))
 	if err != nil {
 		return err
@@ -315,7 +377,7 @@ func writeDebianRules(dir string, pkgType packageType) error {
 	// in 2028+ then the dh-golang version 1.64+ that has merged
 	// https://salsa.debian.org/go-team/packages/dh-golang/-/merge_requests/26
 
-	if pkgType == typeProgram {
+	if pkgType == TypeProgram {
 		fmt.Fprintf(f, "\n")
 		fmt.Fprintf(f, "override_dh_auto_install:\n")
 		fmt.Fprintf(f, "\tdh_auto_install -- --no-source\n")
@@ -339,8 +401,8 @@ func writeDebianSourceFormat(dir string) error {
 	return nil
 }
 
-func writeDebianGbpConf(dir string, dep14, pristineTar bool) error {
-	if !(dep14 || pristineTar) {
+func writeDebianGbpConf(dir, debBranch string, dep14, pristineTar bool, components []string) error {
+	if !(dep14 || pristineTar || len(components) > 0) {
 		return nil
 	}
 
@@ -352,7 +414,7 @@ func writeDebianGbpConf(dir string, dep14, pristineTar bool) error {
 
 	fmt.Fprintf(f, "[DEFAULT]\n")
 	if dep14 {
-		fmt.Fprintf(f, "debian-branch = debian/sid\n")
+		fmt.Fprintf(f, "debian-branch = %s\n", debBranch)
 		fmt.Fprintf(f, "dist = DEP14\n")
 	}
 	if pristineTar {
@@ -361,6 +423,10 @@ func writeDebianGbpConf(dir string, dep14, pristineTar bool) error {
 pristine-tar = True
 `)
 	}
+	if len(components) > 0 {
+		fmt.Fprintf(f, "\n# Additional orig tarballs, e.g. with the vendored modules\n")
+		fmt.Fprintf(f, "components = ['%s']\n", strings.Join(components, "', '"))
+	}
 
 	// Additional text to the template which is useful for most Go packages
 	fmt.Fprint(f, `
@@ -393,12 +459,12 @@ ignore-branch = True
 	return nil
 }
 
-func writeDebianWatch(dir, gopkg, debsrc string, hasRelease bool, repack bool) error {
+func writeDebianWatch(m *Metadata, dir, gopkg, debsrc string, hasRelease bool, repack bool, components []string) error {
 	// TODO: Support other hosters too
 	host := "github.com"
 
-	owner, repo, err := findGitHubRepo(gopkg)
-	if err != nil {
+	owner, repo := m.Owner, m.Repo
+	if repo == "" {
 		log.Printf("debian/watch: Unable to resolve %s to github.com, skipping\n", gopkg)
 		return nil
 	}
@@ -415,6 +481,12 @@ func writeDebianWatch(dir, gopkg, debsrc string, hasRelease bool, repack bool) e
 	filenamemanglePattern := `s%(?:.*?)?v?(\d[\d.]*)\.tar\.gz%@PACKAGE@-$1.tar.gz%`
 	uversionmanglePattern := `s/(\d)[_\.\-\+]?(RC|rc|pre|dev|beta|alpha)[.]?(\d*)$/$1~$2$3/`
 
+	// With component tarballs, all lines share the version of the group.
+	versionPolicy := "debian"
+	if len(components) > 0 {
+		versionPolicy = "group"
+	}
+
 	if hasRelease {
 		log.Printf("Setting debian/watch to track release tarball")
 		fmt.Fprint(f, "version=4\n")
@@ -425,7 +497,7 @@ func writeDebianWatch(dir, gopkg, debsrc string, hasRelease bool, repack bool) e
 			fmt.Fprint(f, `      dversionmangle=s/\+ds\d*$//,repacksuffix=+ds1`)
 		}
 		fmt.Fprint(f, `" \`+"\n")
-		fmt.Fprintf(f, `  https://%s/%s/%s/tags .*/v?(\d\S*)\.tar\.gz debian`+"\n", host, owner, repo)
+		fmt.Fprintf(f, `  https://%s/%s/%s/tags .*/v?(\d\S*)\.tar\.gz %s`+"\n", host, owner, repo, versionPolicy)
 	} else {
 		log.Printf("Setting debian/watch to track git HEAD")
 		fmt.Fprint(f, "version=4\n")
@@ -436,7 +508,7 @@ func writeDebianWatch(dir, gopkg, debsrc string, hasRelease bool, repack bool) e
 		}
 		fmt.Fprint(f, `" \`+"\n")
 		fmt.Fprintf(f, `  https://%s/%s/%s.git \`+"\n", host, owner, repo)
-		fmt.Fprint(f, "  HEAD debian\n")
+		fmt.Fprintf(f, "  HEAD %s\n", versionPolicy)
 
 		// Anticipate that upstream would eventually switch to tagged releases
 		fmt.Fprint(f, "\n")
@@ -450,14 +522,23 @@ func writeDebianWatch(dir, gopkg, debsrc string, hasRelease bool, repack bool) e
 			fmt.Fprint(f, `#      dversionmangle=s/\+ds\d*$//,repacksuffix=+ds1`)
 		}
 		fmt.Fprint(f, `" \`+"\n")
-		fmt.Fprintf(f, `#  https://%s/%s/%s/tags .*/v?(\d\S*)\.tar\.gz debian`+"\n", host, owner, repo)
+		fmt.Fprintf(f, `#  https://%s/%s/%s/tags .*/v?(\d\S*)\.tar\.gz %s`+"\n", host, owner, repo, versionPolicy)
+	}
+
+	for _, component := range components {
+		// Components generated by "go mod vendor" cannot be downloaded.
+		fmt.Fprint(f, "\n")
+		fmt.Fprintf(f, "# The %s component is generated from the upstream sources by\n", component)
+		fmt.Fprint(f, "# \"go mod vendor\". TODO: point uscan at a location providing it:\n")
+		fmt.Fprintf(f, "#opts=\"component=%s\" \\\n", component)
+		fmt.Fprintf(f, "#  https://TODO/%s-%s-(\\d\\S*)\\.tar\\.xz group\n", debsrc, component)
 	}
 
 	return nil
 }
 
-func writeDebianPackageInstall(dir, debLib, debProg string, pkgType packageType) error {
-	if pkgType == typeLibraryProgram || pkgType == typeProgramLibrary {
+func writeDebianPackageInstall(dir, debLib, debProg string, pkgType PackageType) error {
+	if pkgType == TypeLibraryProgram || pkgType == TypeProgramLibrary {
 		f, err := os.Create(filepath.Join(dir, "debian", debProg+".install"))
 		if err != nil {
 			return err
@@ -475,12 +556,12 @@ func writeDebianPackageInstall(dir, debLib, debProg string, pkgType packageType)
 	return nil
 }
 
-func writeDebianUpstreamMetadata(dir, gopkg string) error {
+func writeDebianUpstreamMetadata(m *Metadata, dir, gopkg string) error {
 	// TODO: Support other hosters too
 	host := "github.com"
 
-	owner, repo, err := findGitHubRepo(gopkg)
-	if err != nil {
+	owner, repo := m.Owner, m.Repo
+	if repo == "" {
 		log.Printf("debian/upstream/metadata: Unable to resolve %s to github.com, skipping\n", gopkg)
 		return nil
 	}
@@ -506,7 +587,7 @@ func writeDebianUpstreamMetadata(dir, gopkg string) error {
 	return nil
 }
 
-func writeDebianGitLabCI(dir string) error {
+func writeDebianGitLabCI(dir string, suite TargetSuite) error {
 	const gitlabciymlTmpl = `# This is a template from
 # https://salsa.debian.org/salsa-ci-team/pipeline/-/raw/master/recipes/salsa-ci.yml
 #
@@ -528,6 +609,10 @@ include:
 	}
 	defer f.Close()
 	fmt.Fprint(f, gitlabciymlTmpl)
+	if suite.Name != "sid" {
+		// Build and test against the target suite instead of unstable.
+		fmt.Fprintf(f, "\nvariables:\n  RELEASE: '%s'\n", suite.Name)
+	}
 
 	// Write a compatibility shim for older tooling expecting gitlab-ci.yml
 	compatPath := filepath.Join(dir, "debian", "gitlab-ci.yml")
@@ -0,0 +1,798 @@
+package golangdeb
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"maps"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+	modzip "golang.org/x/mod/zip"
+)
+
+var errUnsupportedHoster = errors.New("unsupported hoster")
+
+// PassthroughEnv returns the environment variables to pass to go
+// commands run in a temporary GOPATH.
+func PassthroughEnv() []string {
+	var relevantVariables = []string{
+		"HOME",
+		"PATH",
+		"HTTP_PROXY", "http_proxy",
+		"HTTPS_PROXY", "https_proxy",
+		"ALL_PROXY", "all_proxy",
+		"NO_PROXY", "no_proxy",
+		"GIT_PROXY_COMMAND",
+		"GIT_HTTP_PROXY_AUTHMETHOD",
+	}
+	var result []string
+	for _, variable := range relevantVariables {
+		if value, ok := os.LookupEnv(variable); ok {
+			result = append(result, fmt.Sprintf("%s=%s", variable, value))
+		}
+	}
+	return result
+}
+
+func findVendorDirs(dir string) ([]string, error) {
+	var vendorDirs []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info != nil && !info.IsDir() {
+			return nil // nothing to do for anything but directories
+		}
+		if info.Name() == ".git" ||
+			info.Name() == ".hg" ||
+			info.Name() == ".bzr" {
+			return filepath.SkipDir
+		}
+		if info.Name() == "vendor" {
+			rel, err := filepath.Rel(dir, path)
+			if err != nil {
+				return fmt.Errorf("filepath.Rel: %w", err)
+			}
+			vendorDirs = append(vendorDirs, rel)
+		}
+		return nil
+	})
+	return vendorDirs, err
+}
+
+func downloadFile(ctx context.Context, filename, url string) error {
+	dst, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+	defer dst.Close()
+
+	resp, err := HTTPGet(ctx, url)
+	if err != nil {
+		return fmt.Errorf("http get: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("response: %s", resp.Status)
+	}
+
+	_, err = io.Copy(dst, resp.Body)
+	if err != nil {
+		return fmt.Errorf("copy: %w", err)
+	}
+
+	return nil
+}
+
+// Upstream describes the upstream repo we are about to package.
+type Upstream struct {
+	RepoRoot        *RepoRoot
+	TarPath         string           // path to the downloaded or generated orig tarball tempfile
+	Compression     string           // compression method, either "gz" or "xz"
+	Version         string           // Debian package upstream version number, e.g. 0.0~git20180204.1d24609
+	Tag             string           // Latest upstream tag, if any
+	CommitIsh       string           // commit-ish corresponding to upstream version to be packaged
+	Remote          string           // git remote, set to short hostname if upstream git history is included
+	FirstMain       string           // import path of the first main package within repo, if any
+	VendorDirs      []string         // all vendor sub directories, relative to the repo directory
+	RepoDeps        []string         // the repository paths of all dependencies (e.g. github.com/zyedidia/glob)
+	UnresolvedDeps  []string         // import paths of dependencies whose repository could not be determined
+	HasGodeps       bool             // whether the Godeps/_workspace directory exists
+	Vendored        bool             // whether the dependencies are vendored, see -vendor
+	VendorMods      []VendoredModule // the modules vendored by "go mod vendor"
+	VendorComponent bool             // whether vendor/ is shipped in an orig component tarball, see -vendor-component
+	VendorPath      string           // path to the tempdir holding vendor/ until the component tarball is generated
+	ComponentPath   string           // path to the generated component tarball tempfile
+	HasRelease      bool             // whether any release tags exist, for debian/watch
+	LastCommit      time.Time        // date of the latest upstream commit, for the ITP
+	TagDate         time.Time        // date of the latest upstream tag, for the ITP
+	IsRelease       bool             // whether what we end up packaging is a tagged release
+	FromProxy       bool             // whether the source was downloaded from the module proxy, see SourceProxy
+	FromDir         string           // the local git checkout the source was taken from, see SourceDir
+}
+
+// Where MakeUpstreamSourceTarball gets the upstream source from.
+const (
+	SourceVCS   = "vcs"   // a clone of the repository
+	SourceProxy = "proxy" // the module zip from the module proxy (GOPROXY)
+	SourceDir   = "dir"   // an existing local git checkout
+)
+
+// CloneMode selects how much of the upstream git repository
+// MakeUpstreamSourceTarball clones. The orig tarball only needs the files of
+// the packaged revision, and determining its version only the history; the
+// upstream history of the packaging repository is fetched separately by
+// CreateGitRepository.
+type CloneMode int
+
+const (
+	// CloneFull clones the complete repository, except from hosters
+	// providing release tarballs (see tarballFromHoster), for which
+	// CloneBlobless suffices.
+	CloneFull CloneMode = iota
+	// CloneBlobless clones the complete history, but only the files of the
+	// packaged revision (git clone --filter=blob:none).
+	CloneBlobless
+	// CloneTag only fetches the files of the tag to package: the given
+	// one (git fetch --depth=1), or by default the latest tag of the
+	// default branch, found in its commits without their files (git clone
+	// --filter=tree:0). It falls back to CloneBlobless when not packaging
+	// a tag.
+	CloneTag
+)
+
+func (u *Upstream) get(ctx context.Context, gopath, repo, rev string, mode CloneMode) error {
+	done := make(chan struct{})
+	defer close(done)
+	go ProgressSize("go get", filepath.Join(gopath, "src"), done)
+
+	rr, err := ResolveRepoRoot(ctx, repo)
+	if err != nil {
+		return fmt.Errorf("get repo root: %w", err)
+	}
+	u.RepoRoot = rr
+	dir := filepath.Join(gopath, "src", rr.Root)
+	if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+		return err
+	}
+	if rr.VCS == "git" {
+		if mode == CloneFull {
+			if _, err := u.tarballUrl(); err == nil {
+				mode = CloneBlobless
+			}
+		}
+		return gitClone(ctx, dir, rr.Repo, rev, mode)
+	}
+	// Run "hg clone {repo} {dir}" (or the equivalent command for svn, bzr)
+	args, err := vcsCreateArgs(rr, dir, rev)
+	if err != nil {
+		return err
+	}
+	cmd := exec.CommandContext(ctx, rr.VCS, args...)
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s %s: %w", rr.VCS, args[0], err)
+	}
+	return nil
+}
+
+// gitClone clones repo at rev (if not empty) into dir, see CloneMode.
+func gitClone(ctx context.Context, dir, repo, rev string, mode CloneMode) error {
+	if mode == CloneTag {
+		if rev == "" {
+			return cloneLatestTag(ctx, dir, repo)
+		}
+		ok, err := remoteHasTag(ctx, repo, rev)
+		if err != nil {
+			return err
+		}
+		if ok {
+			log.Printf("Fetching only tag %q\n", rev)
+			return fetchTag(ctx, dir, repo, rev)
+		}
+		log.Printf("Not packaging a tag, cloning the history to determine the version\n")
+		mode = CloneBlobless
+	}
+
+	args := []string{"clone", "--quiet"}
+	if mode == CloneBlobless {
+		args = append(args, "--filter=blob:none")
+	}
+	if err := RunGitCommandIn(ctx, filepath.Dir(dir), append(args, "--", repo, dir)...); err != nil {
+		return fmt.Errorf("git clone: %w", err)
+	}
+	if rev != "" {
+		if err := RunGitCommandIn(ctx, dir, "checkout", "--quiet", rev); err != nil {
+			return fmt.Errorf("git checkout %s: %w", rev, err)
+		}
+	}
+	return nil
+}
+
+// remoteHasTag reports whether tag is a tag of repo.
+func remoteHasTag(ctx context.Context, repo, tag string) (bool, error) {
+	cmd := exec.CommandContext(ctx, "git", "ls-remote", "--tags", "--refs", "--", repo, "refs/tags/"+tag)
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return false, fmt.Errorf("git ls-remote: %w", err)
+	}
+	return len(bytes.TrimSpace(out)) > 0, nil
+}
+
+// cloneLatestTag clones the commits of repo without their files (git clone
+// --filter=tree:0) into dir, and checks out the latest tag of the default
+// branch as found by pkgVersionFromGit (see LatestGitTag), or the default
+// branch if it has no tags. Only the files of that revision are fetched.
+func cloneLatestTag(ctx context.Context, dir, repo string) error {
+	if err := RunGitCommandIn(ctx, filepath.Dir(dir), "clone", "--quiet", "--filter=tree:0", "--no-checkout", "--", repo, dir); err != nil {
+		return fmt.Errorf("git clone: %w", err)
+	}
+	rev := "HEAD"
+	if tag, err := LatestGitTag(ctx, dir); err == nil {
+		log.Printf("Checking out only tag %q\n", tag)
+		rev = tag
+	} else {
+		log.Printf("No tag found, checking out the default branch\n")
+	}
+	if err := RunGitCommandIn(ctx, dir, "checkout", "--quiet", "--force", rev); err != nil {
+		return fmt.Errorf("git checkout %s: %w", rev, err)
+	}
+	return nil
+}
+
+// fetchTag fetches only the commit of tag from repo into the new git
+// repository dir, and checks it out.
+func fetchTag(ctx context.Context, dir, repo, tag string) error {
+	if err := os.Mkdir(dir, 0755); err != nil {
+		return err
+	}
+	if err := RunGitCommandIn(ctx, dir, "init", "--quiet"); err != nil {
+		return fmt.Errorf("git init: %w", err)
+	}
+	ref := "refs/tags/" + tag
+	if err := RunGitCommandIn(ctx, dir, "fetch", "--quiet", "--depth=1", "--", repo, ref+":"+ref); err != nil {
+		return fmt.Errorf("git fetch %s: %w", tag, err)
+	}
+	if err := RunGitCommandIn(ctx, dir, "checkout", "--quiet", ref); err != nil {
+		return fmt.Errorf("git checkout %s: %w", tag, err)
+	}
+	return nil
+}
+
+// downloadedModule is the output of "go mod download -json".
+type downloadedModule struct {
+	Path    string
+	Version string
+	Error   string
+	Info    string // path to the .info file
+	Zip     string // path to the module zip
+}
+
+// getFromProxy downloads the module repo at revision (a module version or
+// query such as a branch name, or its latest version) from the module proxy
+// and unpacks it into gopath. Modules of major version 2 and up are looked
+// up with their major version suffix, e.g. repo/v2 for v2.1.0.
+func (u *Upstream) getFromProxy(ctx context.Context, gopath, repo, revision string) error {
+	query := cmp.Or(revision, "latest")
+	paths := []string{repo}
+	if major := semver.Major(query); major != "" && major != "v0" && major != "v1" && !strings.HasSuffix(query, "+incompatible") {
+		paths = []string{repo + "/" + major, repo}
+	}
+	var (
+		mod *downloadedModule
+		err error
+	)
+	for _, path := range paths {
+		if mod, err = goModDownload(ctx, gopath, path+"@"+query); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return err
+	}
+	log.Printf("Downloaded module %s@%s\n", mod.Path, mod.Version)
+
+	dir := filepath.Join(gopath, "src", repo)
+	if err := modzip.Unzip(dir, module.Version{Path: mod.Path, Version: mod.Version}, mod.Zip); err != nil {
+		return fmt.Errorf("unzip %s: %w", mod.Zip, err)
+	}
+	// The files are unpacked read-only, like in the module cache.
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		return os.Chmod(path, 0644)
+	})
+	if err != nil {
+		return err
+	}
+
+	var info moduleInfo
+	if b, err := os.ReadFile(mod.Info); err != nil {
+		log.Printf("Could not read module info: %v\n", err)
+	} else if err := json.Unmarshal(b, &info); err != nil {
+		log.Printf("Could not parse module info %s: %v\n", mod.Info, err)
+	}
+	if u.Version, err = pkgVersionFromModule(u, mod.Version, info.Time); err != nil {
+		return err
+	}
+
+	u.FromProxy = true
+	if u.RepoRoot, err = ResolveRepoRoot(ctx, repo); err != nil {
+		log.Printf("Could not determine the repository of %s: %v\n", repo, err)
+		u.RepoRoot = &RepoRoot{Root: repo, VCS: "mod", Module: mod.Path}
+	}
+	return nil
+}
+
+// goModDownload downloads the module version query (path@version) into the
+// module cache with "go mod download", which honours GOPROXY, GOPRIVATE,
+// GONOSUMDB etc. and verifies the checksum of the module.
+func goModDownload(ctx context.Context, dir, query string) (*downloadedModule, error) {
+	cmd := exec.CommandContext(ctx, "go", "mod", "download", "-json", query)
+	cmd.Dir = dir // outside of any module
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	var mod downloadedModule
+	if jsonErr := json.Unmarshal(out, &mod); jsonErr != nil {
+		if err != nil {
+			return nil, fmt.Errorf("go mod download %s: %w", query, err)
+		}
+		return nil, fmt.Errorf("go mod download %s: %w", query, jsonErr)
+	}
+	if mod.Error != "" {
+		return nil, fmt.Errorf("go mod download: %s", mod.Error)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("go mod download %s: %w", query, err)
+	}
+	return &mod, nil
+}
+
+func (u *Upstream) tarballUrl() (string, error) {
+	repo := strings.TrimSuffix(u.RepoRoot.Repo, ".git")
+	repoU, err := url.Parse(repo)
+	if err != nil {
+		return "", fmt.Errorf("parse URL: %w", err)
+	}
+
+	switch repoU.Host {
+	case "github.com":
+		return fmt.Sprintf("%s/archive/%s.tar.%s",
+			repo, u.Tag, u.Compression), nil
+	case "gitlab.com", "salsa.debian.org":
+		parts := strings.Split(repoU.Path, "/")
+		if len(parts) < 3 {
+			return "", fmt.Errorf("incomplete repo URL: %s", u.RepoRoot.Repo)
+		}
+		project := parts[2]
+		return fmt.Sprintf("%s/-/archive/%s/%s-%s.tar.%s",
+			repo, u.Tag, project, u.Tag, u.Compression), nil
+	case "git.sr.ht":
+		return fmt.Sprintf("%s/archive/%s.tar.%s",
+			repo, u.Tag, u.Compression), nil
+	case "codeberg.org":
+		return fmt.Sprintf("%s/archive/%s.tar.%s",
+			repo, u.Tag, u.Compression), nil
+	default:
+		return "", errUnsupportedHoster
+	}
+}
+
+func (u *Upstream) tarballFromHoster(ctx context.Context) error {
+	tarURL, err := u.tarballUrl()
+	if err != nil {
+		return err
+	}
+
+	done := make(chan struct{})
+	go ProgressSize("Download", u.TarPath, done)
+
+	log.Printf("Downloading %s", tarURL)
+	err = downloadFile(ctx, u.TarPath, tarURL)
+
+	close(done)
+
+	return err
+}
+
+func (u *Upstream) tar(ctx context.Context, gopath, repo string) error {
+	f, err := os.CreateTemp("", "dh-make-golang")
+	if err != nil {
+		return fmt.Errorf("create temp file: %w", err)
+	}
+	u.TarPath = f.Name()
+	f.Close()
+
+	if u.IsRelease {
+		if u.FromProxy {
+			log.Printf("Packaging the module zip, not downloading tarball from hoster.")
+		} else if u.FromDir != "" {
+			log.Printf("Packaging the local checkout, not downloading tarball from hoster.")
+		} else if u.HasGodeps {
+			log.Printf("Godeps/_workspace exists, not downloading tarball from hoster.")
+		} else if u.Vendored && !u.VendorComponent {
+			log.Printf("Dependencies are vendored, not downloading tarball from hoster.")
+		} else {
+			u.Compression = "gz"
+			if err := u.tarballFromHoster(ctx); err == nil {
+				return nil
+			} else if err == errUnsupportedHoster {
+				log.Printf("INFO: Hoster does not provide release tarball\n")
+			} else {
+				return fmt.Errorf("tarball from hoster: %w", err)
+			}
+		}
+	}
+
+	u.Compression = "xz"
+	base := filepath.Base(repo)
+	log.Printf("Generating temp tarball as %q\n", u.TarPath)
+	dir := filepath.Dir(repo)
+	cmd := exec.CommandContext(ctx,
+		"tar",
+		"cJf",
+		u.TarPath,
+		"--exclude=.git",
+		"--exclude=Godeps/_workspace",
+		"--exclude="+base+"/debian",
+		base)
+	cmd.Dir = filepath.Join(gopath, "src", dir)
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// TarVendorComponent generates the vendor component tarball from
+// u.vendorPath, leaving out the modules which are packaged in
+// golangBinaries. It returns the binary packages of the latter, which need
+// to become build dependencies.
+func (u *Upstream) TarVendorComponent(ctx context.Context, golangBinaries map[string]DebianPackage) ([]string, error) {
+	defer os.RemoveAll(u.VendorPath)
+
+	mods, binaries, err := removePackagedModules(filepath.Join(u.VendorPath, VendorComponent), u.VendorMods, golangBinaries)
+	if err != nil {
+		return nil, fmt.Errorf("remove packaged modules: %w", err)
+	}
+	if len(mods) == 0 {
+		log.Printf("All dependencies are packaged, not generating a %s component tarball\n", VendorComponent)
+		u.Vendored = false
+		u.VendorComponent = false
+		u.VendorMods = nil
+		return nil, nil
+	}
+	u.VendorMods = mods
+
+	f, err := os.CreateTemp("", "dh-make-golang")
+	if err != nil {
+		return nil, fmt.Errorf("create temp file: %w", err)
+	}
+	u.ComponentPath = f.Name()
+	f.Close()
+
+	log.Printf("Generating temp %s component tarball as %q\n", VendorComponent, u.ComponentPath)
+	cmd := exec.CommandContext(ctx, "tar", "cJf", u.ComponentPath, VendorComponent)
+	cmd.Dir = u.VendorPath
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("tar: %w", err)
+	}
+	return binaries, nil
+}
+
+// findMains finds main packages within the repo (useful to auto-detect the
+// package type).
+func (u *Upstream) findMains(ctx context.Context, gopath, repo string) error {
+	cmd := exec.CommandContext(ctx, "go", "list", "-e", "-f", "{{.ImportPath}} {{.Name}}", repo+"/...")
+	cmd.Dir = filepath.Join(gopath, "src", repo)
+	cmd.Env = PassthroughEnv()
+	cmd.Stderr = os.Stderr
+	log.Println("findMains: Running", cmd, "in", cmd.Dir)
+	out, err := cmd.Output()
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		log.Println("WARNING: In findMains:", fmt.Errorf("%q: %w", cmd.Args, err))
+		// See https://bugs.debian.org/992610
+		log.Printf("Retrying without appending \"/...\" to repo")
+		cmd = exec.CommandContext(ctx, "go", "list", "-e", "-f", "{{.ImportPath}} {{.Name}}", repo)
+		cmd.Dir = filepath.Join(gopath, "src", repo)
+		cmd.Env = PassthroughEnv()
+		cmd.Stderr = os.Stderr
+		log.Println("findMains: Running", cmd, "in", cmd.Dir)
+		out, err = cmd.Output()
+		if err != nil {
+			log.Println("WARNING: In findMains:", fmt.Errorf("%q: %w", cmd.Args, err))
+		}
+	}
+	for line := range strings.SplitSeq(strings.TrimSpace(string(out)), "\n") {
+		if strings.Contains(line, "/vendor/") ||
+			strings.Contains(line, "/Godeps/") ||
+			strings.Contains(line, "/samples/") ||
+			strings.Contains(line, "/examples/") ||
+			strings.Contains(line, "/example/") {
+			continue
+		}
+		if before, ok := strings.CutSuffix(line, " main"); ok {
+			u.FirstMain = before
+			break
+		}
+	}
+	return nil
+}
+
+func (u *Upstream) findDependencies(ctx context.Context, gopath, repo string) error {
+	log.Printf("Determining dependencies\n")
+
+	cmd := exec.CommandContext(ctx, "go", "list", "-e", "-f", "{{join .Imports \"\\n\"}}\n{{join .TestImports \"\\n\"}}\n{{join .XTestImports \"\\n\"}}", repo+"/...")
+	cmd.Dir = filepath.Join(gopath, "src", repo)
+	cmd.Env = PassthroughEnv()
+	cmd.Stderr = os.Stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		log.Println("WARNING: In findDependencies:", fmt.Errorf("%q: %w", cmd.Args, err))
+		// See https://bugs.debian.org/992610
+		log.Printf("Retrying without appending \"/...\" to repo")
+		cmd = exec.CommandContext(ctx, "go", "list", "-e", "-f", "{{join .Imports \"\\n\"}}\n{{join .TestImports \"\\n\"}}\n{{join .XTestImports \"\\n\"}}", repo)
+		cmd.Dir = filepath.Join(gopath, "src", repo)
+		cmd.Env = PassthroughEnv()
+		cmd.Stderr = os.Stderr
+		out, err = cmd.Output()
+		if err != nil {
+			log.Println("WARNING: In findDependencies:", fmt.Errorf("%q: %w", cmd.Args, err))
+		}
+	}
+
+	godependencies := make(map[string]bool)
+	for p := range strings.SplitSeq(strings.TrimSpace(string(out)), "\n") {
+		if p == "" {
+			continue // skip separators between import types
+		}
+		// Strip packages that are included in the repository we are packaging.
+		if strings.HasPrefix(p, repo+"/") || p == repo {
+			continue
+		}
+		if p == "C" {
+			// TODO: maybe parse the comments to figure out C deps from pkg-config files?
+		} else {
+			godependencies[p] = true
+		}
+	}
+
+	if len(godependencies) == 0 {
+		return nil
+	}
+
+	// Remove all packages which are in the standard lib.
+	cmd = exec.CommandContext(ctx, "go", "list", "std")
+	cmd.Stderr = os.Stderr
+	cmd.Env = PassthroughEnv()
+
+	out, err = cmd.Output()
+	if err != nil {
+		return fmt.Errorf("go list std: (args: %v): %w", cmd.Args, err)
+	}
+
+	for line := range strings.SplitSeq(strings.TrimSpace(string(out)), "\n") {
+		delete(godependencies, line)
+	}
+
+	// Resolve all packages to the root of their repository.
+	depRoots, err := DefaultRepoRootResolver.RepoRoots(ctx, slices.Collect(maps.Keys(godependencies)))
+	if err != nil {
+		return err
+	}
+	roots := make(map[string]bool)
+	for _, root := range depRoots {
+		roots[root] = true
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	for dep := range godependencies {
+		if _, ok := depRoots[dep]; ok {
+			continue
+		}
+		// E.g. without network access: fall back to the module
+		// requirements, which usually name the repository.
+		if mod := requiredModule(filepath.Join(gopath, "src", repo), dep); mod != "" {
+			roots[repoRootFromModule(mod, "")] = true
+			continue
+		}
+		u.UnresolvedDeps = append(u.UnresolvedDeps, dep)
+	}
+	if len(u.UnresolvedDeps) > 0 {
+		slices.Sort(u.UnresolvedDeps)
+		log.Printf("WARNING: Could not determine the repositories of %q, they are TODOs in debian/control\n", u.UnresolvedDeps)
+	}
+
+	u.RepoDeps = make([]string, 0, len(roots))
+	for root := range roots {
+		u.RepoDeps = append(u.RepoDeps, root)
+	}
+
+	return nil
+}
+
+// requiredModule returns the module required by dir/go.mod which provides
+// the package importPath, or "" if there is none.
+func requiredModule(dir, importPath string) string {
+	b, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		return ""
+	}
+	f, err := modfile.ParseLax("go.mod", b, nil)
+	if err != nil {
+		return ""
+	}
+	var found string
+	for _, r := range f.Require {
+		if p := r.Mod.Path; (importPath == p || strings.HasPrefix(importPath, p+"/")) && len(p) > len(found) {
+			found = p
+		}
+	}
+	return found
+}
+
+// MakeUpstreamSourceTarball downloads repo at revision (or its latest
+// version) from source, SourceVCS (cloned as selected by clone),
+// SourceProxy or SourceDir (the local git checkout dir, at revision or its
+// HEAD), and creates the orig tarball from it. The temporary files are
+// removed on failure, including when ctx is cancelled.
+func MakeUpstreamSourceTarball(ctx context.Context, repo, revision, source, dir string, clone CloneMode, forcePrerelease, vendor, component bool) (_ *Upstream, err error) {
+	gopath, err := os.MkdirTemp("", "dh-make-golang")
+	if err != nil {
+		return nil, fmt.Errorf("create tmp dir: %w", err)
+	}
+	defer ForceRemoveAll(gopath)
+	repoDir := filepath.Join(gopath, "src", repo)
+
+	var u Upstream
+	defer func() {
+		if err != nil {
+			u.RemoveTempFiles()
+		}
+	}()
+
+	switch source {
+	case SourceVCS:
+		if clone == CloneTag && forcePrerelease {
+			clone = CloneBlobless
+		}
+		log.Printf("Downloading %q\n", repo+"/...")
+		if err := u.get(ctx, gopath, repo, revision, clone); err != nil {
+			return nil, fmt.Errorf("go get: %w", err)
+		}
+
+		// Verify early this repository uses git (we call pkgVersionFromGit later):
+		if _, err := os.Stat(filepath.Join(repoDir, ".git")); os.IsNotExist(err) {
+			return nil, fmt.Errorf("not a git repository; dh-make-golang currently only supports git")
+		}
+	case SourceProxy:
+		if forcePrerelease && revision == "" {
+			return nil, errors.New("the module proxy only knows the default branch by name, specify it as revision instead")
+		}
+		log.Printf("Downloading %q from the module proxy\n", repo)
+		if err := u.getFromProxy(ctx, gopath, repo, revision); err != nil {
+			return nil, fmt.Errorf("module proxy: %w", err)
+		}
+	case SourceDir:
+		log.Printf("Using the local checkout %q of %q\n", dir, repo)
+		if err := u.getFromDir(ctx, gopath, repo, dir, revision); err != nil {
+			return nil, fmt.Errorf("local checkout: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unknown source %q", source)
+	}
+
+	if _, err := os.Stat(filepath.Join(repoDir, "debian")); err == nil {
+		log.Printf("WARNING: ignoring debian/ directory that came with the upstream sources\n")
+	}
+
+	u.VendorDirs, err = findVendorDirs(repoDir)
+	if err != nil {
+		return nil, fmt.Errorf("find vendor dirs: %w", err)
+	}
+	if len(u.VendorDirs) > 0 {
+		log.Printf("Deleting upstream vendor/ directories")
+		for _, dir := range u.VendorDirs {
+			if err := os.RemoveAll(filepath.Join(repoDir, dir)); err != nil {
+				return nil, fmt.Errorf("remove all: %w", err)
+			}
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(repoDir, "Godeps", "_workspace")); !os.IsNotExist(err) {
+		log.Println("Godeps/_workspace detected")
+		u.HasGodeps = true
+	}
+
+	// The version of modules is determined from the module version by
+	// getFromProxy.
+	if !u.FromProxy {
+		log.Printf("Determining upstream version number\n")
+
+		u.Version, err = pkgVersionFromGit(ctx, repoDir, &u, revision, forcePrerelease)
+		if err != nil {
+			return nil, fmt.Errorf("get package version from Git: %w", err)
+		}
+
+		if u.LastCommit, err = gitCommitDate(ctx, repoDir, "HEAD"); err != nil {
+			log.Printf("Could not determine date of the latest upstream commit: %v\n", err)
+		}
+		if u.Tag != "" {
+			if u.TagDate, err = gitCommitDate(ctx, repoDir, u.Tag); err != nil {
+				log.Printf("Could not determine date of upstream tag %q: %v\n", u.Tag, err)
+			}
+		}
+	}
+
+	log.Printf("Package version is %q\n", u.Version)
+
+	if err := u.findMains(ctx, gopath, repo); err != nil {
+		return nil, fmt.Errorf("find mains: %w", err)
+	}
+
+	if err := u.findDependencies(ctx, gopath, repo); err != nil {
+		return nil, fmt.Errorf("find dependencies: %w", err)
+	}
+
+	if vendor {
+		u.Vendored = true
+		u.VendorMods, err = goModVendor(ctx, repoDir)
+		if err != nil {
+			return nil, fmt.Errorf("vendor: %w", err)
+		}
+		log.Printf("Vendored %d modules\n", len(u.VendorMods))
+
+		if component && len(u.VendorMods) > 0 {
+			// Keep vendor/ out of the orig tarball, the component tarball
+			// is generated once the packaged dependencies are known.
+			u.VendorComponent = true
+			u.VendorPath, err = os.MkdirTemp("", "dh-make-golang")
+			if err != nil {
+				return nil, fmt.Errorf("create tmp dir: %w", err)
+			}
+			if err := os.Rename(filepath.Join(repoDir, VendorComponent), filepath.Join(u.VendorPath, VendorComponent)); err != nil {
+				return nil, fmt.Errorf("move vendor/ aside: %w", err)
+			}
+		}
+	}
+
+	if err := u.tar(ctx, gopath, repo); err != nil {
+		return nil, fmt.Errorf("tar: %w", err)
+	}
+
+	return &u, nil
+}
+
+// RemoveTempFiles removes the temporary files of u: the orig tarball, the
+// vendor/ directory and the component tarball.
+func (u *Upstream) RemoveTempFiles() {
+	for _, path := range []string{u.TarPath, u.VendorPath, u.ComponentPath} {
+		if path != "" {
+			os.RemoveAll(path)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package golangdeb
+
+import (
+	"archive/zip"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+var tarballUrl = []struct {
+	repoRoot    string
+	tag         string
+	compression string
+	url         string
+}{
+	{"https://github.com/Debian/dh-make-golang", "0.6.0", "gz", "https://github.com/Debian/dh-make-golang/archive/0.6.0.tar.gz"},
+	{"https://github.com/Debian/dh-make-golang.git", "0.6.0", "gz", "https://github.com/Debian/dh-make-golang/archive/0.6.0.tar.gz"},
+	{"https://gitlab.com/gitlab-org/labkit", "1.3.0", "gz", "https://gitlab.com/gitlab-org/labkit/-/archive/1.3.0/labkit-1.3.0.tar.gz"},
+	{"https://git.sr.ht/~sircmpwn/getopt", "v1.0.0", "gz", "https://git.sr.ht/~sircmpwn/getopt/archive/v1.0.0.tar.gz"},
+}
+
+func TestUpstreamTarmballUrl(t *testing.T) {
+	for _, tt := range tarballUrl {
+		u := Upstream{
+			RepoRoot:    &RepoRoot{Repo: tt.repoRoot},
+			Compression: tt.compression,
+			Tag:         tt.tag,
+		}
+
+		url, _ := u.tarballUrl()
+		if url != tt.url {
+			t.Errorf("TestUpstreamTarmballUrl(%q) => %q, want %q", tt.repoRoot, url, tt.url)
+		}
+	}
+}
+
+// writeModuleZip adds the module mod at version with the given files to the
+// file-based module proxy in dir.
+func writeModuleZip(t *testing.T, dir, mod, version string, files map[string]string) {
+	vdir := filepath.Join(dir, filepath.FromSlash(mod), "@v")
+	if err := os.MkdirAll(vdir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Create(filepath.Join(vdir, version+".zip"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+	for name, content := range files {
+		w, err := zw.Create(mod + "@" + version + "/" + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.WriteString(w, content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(vdir, version+".mod"), []byte(files["go.mod"]), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMakeUpstreamSourceTarballFromProxy(t *testing.T) {
+	proxy := writeFileProxy(t, map[string]map[string]string{
+		"example.org/hello": {
+			"v1.0.0": `{"Version": "v1.0.0", "Time": "2024-01-02T03:04:05Z", "Origin": {"VCS": "git", "URL": "https://git.example.org/hello"}}`,
+			"v1.1.0": `{"Version": "v1.1.0", "Time": "2024-02-03T04:05:06Z", "Origin": {"VCS": "git", "URL": "https://git.example.org/hello"}}`,
+		},
+	})
+	files := map[string]string{
+		"go.mod":  "module example.org/hello\n\ngo 1.21\n",
+		"main.go": "package main\n\nfunc main() {}\n",
+	}
+	for _, version := range []string{"v1.0.0", "v1.1.0"} {
+		writeModuleZip(t, strings.TrimPrefix(proxy, "file://"), "example.org/hello", version, files)
+	}
+	setGoModuleEnv(t, proxy, "")
+	t.Setenv("GOSUMDB", "off")
+	t.Setenv("GOMODCACHE", t.TempDir())
+	t.Setenv("GOFLAGS", "-modcacherw")
+
+	for _, tt := range []struct {
+		revision string
+		want     string
+	}{
+		{"", "1.1.0"},
+		{"v1.0.0", "1.0.0"},
+	} {
+		u, err := MakeUpstreamSourceTarball(t.Context(), "example.org/hello", tt.revision, SourceProxy, "", CloneFull, false, false, false)
+		if err != nil {
+			t.Fatalf("MakeUpstreamSourceTarball(%q): %v", tt.revision, err)
+		}
+		defer u.RemoveTempFiles()
+		if u.Version != tt.want || !u.IsRelease || !u.FromProxy || u.Compression != "xz" {
+			t.Errorf("MakeUpstreamSourceTarball(%q): got version %q (release %v, from proxy %v, compression %q), want %q",
+				tt.revision, u.Version, u.IsRelease, u.FromProxy, u.Compression, tt.want)
+		}
+		if u.FirstMain != "example.org/hello" {
+			t.Errorf("MakeUpstreamSourceTarball(%q): got first main %q, want example.org/hello", tt.revision, u.FirstMain)
+		}
+		if want := (&RepoRoot{Root: "example.org/hello", Repo: "https://git.example.org/hello", VCS: "git", Module: "example.org/hello"}); *u.RepoRoot != *want {
+			t.Errorf("MakeUpstreamSourceTarball(%q): got repo root %+v, want %+v", tt.revision, u.RepoRoot, want)
+		}
+		out, err := exec.Command("tar", "tJf", u.TarPath).Output()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := strings.Fields(string(out))
+		slices.Sort(got)
+		if want := []string{"hello/", "hello/go.mod", "hello/main.go"}; !slices.Equal(got, want) {
+			t.Errorf("MakeUpstreamSourceTarball(%q): got tarball contents %q, want %q", tt.revision, got, want)
+		}
+	}
+}
+
+// newTaggedRepo creates a git repository whose main branch has three
+// commits with the tags v0.9.0, v1.0.0 (annotated) and sub/v2.0.0, and the
+// branches release-1.0 and next from v1.0.0 and main with one commit tagged
+// v1.0.1 and v2.0.0-rc.1 respectively. The file "file" contains the tag or
+// branch of each commit. It returns the file:// URL of the repository.
+func newTaggedRepo(t *testing.T) string {
+	dir := t.TempDir()
+	gitCmdOrFatal(t, dir, "init", "--quiet", "--initial-branch=main")
+	gitCmdOrFatal(t, dir, "config", "user.email", "unittest@example.com")
+	gitCmdOrFatal(t, dir, "config", "user.name", "Unit Test")
+	commit := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, "file"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		gitCmdOrFatal(t, dir, "add", "file")
+		gitCmdOrFatal(t, dir, "commit", "--quiet", "-m", "Commit "+content)
+	}
+	commit("v0.9.0")
+	gitCmdOrFatal(t, dir, "tag", "v0.9.0")
+	commit("v1.0.0")
+	gitCmdOrFatal(t, dir, "tag", "-a", "-m", "Release v1.0.0", "v1.0.0")
+	commit("main")
+	gitCmdOrFatal(t, dir, "tag", "sub/v2.0.0")
+
+	gitCmdOrFatal(t, dir, "checkout", "--quiet", "-b", "release-1.0", "v1.0.0")
+	commit("v1.0.1")
+	gitCmdOrFatal(t, dir, "tag", "v1.0.1")
+	gitCmdOrFatal(t, dir, "checkout", "--quiet", "-b", "next", "main")
+	commit("v2.0.0-rc.1")
+	gitCmdOrFatal(t, dir, "tag", "v2.0.0-rc.1")
+	gitCmdOrFatal(t, dir, "checkout", "--quiet", "main")
+	return "file://" + filepath.ToSlash(dir)
+}
+
+func TestRemoteHasTag(t *testing.T) {
+	repo := newTaggedRepo(t)
+	for _, tt := range []struct {
+		tag  string
+		want bool
+	}{
+		{"v0.9.0", true},
+		{"v1.0.1", true},
+		{"main", false},
+		{"v1", false},
+	} {
+		got, err := remoteHasTag(t.Context(), repo, tt.tag)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("remoteHasTag(%q) = %v, want %v", tt.tag, got, tt.want)
+		}
+	}
+}
+
+func TestGitClone(t *testing.T) {
+	repo := newTaggedRepo(t)
+	for _, tt := range []struct {
+		name     string
+		rev      string
+		mode     CloneMode
+		wantFile string
+		commits  string // number of commits in the clone
+	}{
+		{"full", "", CloneFull, "main", "3"},
+		{"blobless", "v0.9.0", CloneBlobless, "v0.9.0", "1"},
+		// The latest tag of the main branch, as pkgVersionFromGit picks it
+		// from a full clone, not the highest one (v2.0.0-rc.1 or v1.0.1).
+		{"tag", "", CloneTag, "v1.0.0", "2"},
+		{"given tag", "v1.0.1", CloneTag, "v1.0.1", "1"},
+		{"tag not a tag", "main", CloneTag, "main", "3"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "clone")
+			if err := gitClone(t.Context(), dir, repo, tt.rev, tt.mode); err != nil {
+				t.Fatal(err)
+			}
+			if b, err := os.ReadFile(filepath.Join(dir, "file")); err != nil || string(b) != tt.wantFile {
+				t.Errorf("checked out file = %q, %v, want %q", b, err, tt.wantFile)
+			}
+			cmd := exec.Command("git", "rev-list", "--count", "HEAD")
+			cmd.Dir = dir
+			out, err := cmd.Output()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.TrimSpace(string(out)); got != tt.commits {
+				t.Errorf("got %s commits, want %s", got, tt.commits)
+			}
+		})
+	}
+}
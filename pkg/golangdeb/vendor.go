@@ -0,0 +1,306 @@
+package golangdeb
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// VendoredModule is a module copied into vendor/ by “go mod vendor”.
+type VendoredModule struct {
+	Path        string
+	Version     string
+	License     string   // Debian short name, e.g. “Expat”, or “TODO”
+	Copyright   []string // copyright lines found in the license files
+	LicenseText string   // content of the license file, if any
+}
+
+// licenseFileRegexp matches the names of the files “go mod vendor” copies
+// from the module root and which contain the license of a module.
+var licenseFileRegexp = regexp.MustCompile(`(?i)^(licen[cs]e|copying|copyright|unlicense)`)
+
+// copyrightLineRegexp matches copyright statements in license files.
+var copyrightLineRegexp = regexp.MustCompile(`(?i)^\s*copyright\s+(\(c\)\s*|©\s*)?(\d{4}.*)$`)
+
+// licensePatterns maps Debian license short names to phrases identifying
+// them in license files. The first matching entry wins.
+var licensePatterns = []struct {
+	license string
+	phrases []string
+}{
+	{"Apache-2.0", []string{"Apache License", "Version 2.0"}},
+	{"MPL-2.0", []string{"Mozilla Public License", "2.0"}},
+	{"LGPL-3.0", []string{"GNU LESSER GENERAL PUBLIC LICENSE", "Version 3"}},
+	{"LGPL-2.1", []string{"GNU LESSER GENERAL PUBLIC LICENSE", "Version 2.1"}},
+	{"GPL-3.0", []string{"GNU GENERAL PUBLIC LICENSE", "Version 3"}},
+	{"GPL-2.0", []string{"GNU GENERAL PUBLIC LICENSE", "Version 2"}},
+	{"Expat", []string{"Permission is hereby granted, free of charge"}},
+	{"ISC", []string{"Permission to use, copy, modify, and", "distribute this software for any"}},
+	{"BSD-3-clause", []string{"Redistribution and use in source and binary forms", "Neither the name"}},
+	{"BSD-3-clause", []string{"Redistribution and use in source and binary forms", "names of its contributors"}},
+	{"BSD-2-clause", []string{"Redistribution and use in source and binary forms"}},
+	{"CC0-1.0", []string{"CC0 1.0 Universal"}},
+	{"Unlicense", []string{"This is free and unencumbered software released into the public domain"}},
+}
+
+// detectLicense returns the Debian short name of the license in text, or
+// “TODO” if it is not recognized.
+func detectLicense(text string) string {
+	// Normalize whitespace so that phrases match across line breaks.
+	text = strings.Join(strings.Fields(text), " ")
+	for _, p := range licensePatterns {
+		matches := true
+		for _, phrase := range p.phrases {
+			if !strings.Contains(text, phrase) {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return p.license
+		}
+	}
+	return "TODO"
+}
+
+// copyrightLines returns the copyright statements in text, without the
+// “Copyright (c)” prefix.
+func copyrightLines(text string) []string {
+	var lines []string
+	for line := range strings.SplitSeq(text, "\n") {
+		if m := copyrightLineRegexp.FindStringSubmatch(line); m != nil {
+			if c := strings.TrimSpace(m[2]); !slices.Contains(lines, c) {
+				lines = append(lines, c)
+			}
+		}
+	}
+	return lines
+}
+
+// parseVendorModules parses the vendor/modules.txt file written by “go mod
+// vendor”, returning the vendored modules sorted by path. Modules replaced
+// by a local directory are skipped, as their sources are not vendored.
+func parseVendorModules(r io.Reader) ([]VendoredModule, error) {
+	var modules []VendoredModule
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		// Module lines look like “# path version [=> replacement [version]]”,
+		// annotations like “## explicit; go 1.21”.
+		line, ok := strings.CutPrefix(scanner.Text(), "# ")
+		if !ok {
+			continue
+		}
+		orig, replacement, replaced := strings.Cut(line, " => ")
+		fields := strings.Fields(orig)
+		if len(fields) == 0 {
+			continue
+		}
+		mod := VendoredModule{Path: fields[0]}
+		if len(fields) > 1 {
+			mod.Version = fields[1]
+		}
+		if replaced {
+			rfields := strings.Fields(replacement)
+			if len(rfields) < 2 {
+				continue // replaced by a local directory
+			}
+			mod.Version = rfields[1]
+		}
+		modules = append(modules, mod)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	slices.SortFunc(modules, func(a, b VendoredModule) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+	return modules, nil
+}
+
+// scanVendoredLicenses determines the license and copyright of the modules
+// vendored in vendorDir, based on their license files.
+func scanVendoredLicenses(vendorDir string, modules []VendoredModule) {
+	for i := range modules {
+		mod := &modules[i]
+		mod.License = "TODO"
+		entries, err := os.ReadDir(filepath.Join(vendorDir, filepath.FromSlash(mod.Path)))
+		if err != nil {
+			log.Printf("Could not determine license of vendored module %s: %v", mod.Path, err)
+			continue
+		}
+		for _, entry := range entries {
+			if entry.IsDir() || !licenseFileRegexp.MatchString(entry.Name()) {
+				continue
+			}
+			b, err := os.ReadFile(filepath.Join(vendorDir, filepath.FromSlash(mod.Path), entry.Name()))
+			if err != nil {
+				log.Printf("Could not read license of vendored module %s: %v", mod.Path, err)
+				continue
+			}
+			mod.Copyright = append(mod.Copyright, copyrightLines(string(b))...)
+			if mod.License == "TODO" {
+				mod.License = detectLicense(string(b))
+				mod.LicenseText = string(b)
+			}
+		}
+		if mod.License == "TODO" {
+			log.Printf("Could not determine license of vendored module %s, please check debian/copyright", mod.Path)
+		}
+	}
+}
+
+// goModVendor runs “go mod vendor” in repoDir and returns the vendored
+// modules.
+func goModVendor(ctx context.Context, repoDir string) ([]VendoredModule, error) {
+	if _, err := os.Stat(filepath.Join(repoDir, "go.mod")); err != nil {
+		return nil, fmt.Errorf("vendoring requires a go.mod file: %w", err)
+	}
+
+	// The module cache is read-only, so it needs to be removed with
+	// ForceRemoveAll.
+	modcache, err := os.MkdirTemp("", "dh-make-golang")
+	if err != nil {
+		return nil, fmt.Errorf("create temp dir: %w", err)
+	}
+	defer func() {
+		if err := ForceRemoveAll(modcache); err != nil {
+			log.Printf("could not remove all %s: %v", modcache, err)
+		}
+	}()
+
+	log.Printf("Running \"go mod vendor\"\n")
+	cmd := exec.CommandContext(ctx, "go", "mod", "vendor")
+	cmd.Dir = repoDir
+	cmd.Stderr = os.Stderr
+	cmd.Env = append([]string{
+		"GO111MODULE=on",
+		"GOFLAGS=-mod=mod",
+		"GOMODCACHE=" + modcache,
+		"GOPATH=" + modcache,
+	}, PassthroughEnv()...)
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("go mod vendor: %w", err)
+	}
+
+	return ReadVendoredModules(repoDir)
+}
+
+// ReadVendoredModules returns the modules vendored in the vendor/ directory
+// of repoDir according to vendor/modules.txt, including their licenses.
+func ReadVendoredModules(repoDir string) ([]VendoredModule, error) {
+	vendorDir := filepath.Join(repoDir, "vendor")
+	f, err := os.Open(filepath.Join(vendorDir, "modules.txt"))
+	if os.IsNotExist(err) {
+		return nil, nil // no dependencies
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	modules, err := parseVendorModules(f)
+	if err != nil {
+		return nil, fmt.Errorf("parse vendor/modules.txt: %w", err)
+	}
+	scanVendoredLicenses(vendorDir, modules)
+	return modules, nil
+}
+
+// VendorComponent is the name of the orig component tarball containing
+// vendor/, see -vendor-component.
+const VendorComponent = "vendor"
+
+// removePackagedModules removes the modules which are available as packages
+// in golangBinaries from vendorDir, so that they are used as build
+// dependencies instead. It returns the modules which remain vendored and the
+// binary packages providing the removed ones.
+func removePackagedModules(vendorDir string, modules []VendoredModule, golangBinaries map[string]DebianPackage) ([]VendoredModule, []string, error) {
+	var remaining []VendoredModule
+	var binaries []string
+	for _, mod := range modules {
+		pkg, ok := golangBinaries[mod.Path]
+		if !ok || hasNestedModule(modules, mod.Path) {
+			remaining = append(remaining, mod)
+			continue
+		}
+		log.Printf("Not vendoring %s, it is packaged as %s\n", mod.Path, pkg.Binary)
+		dir := filepath.Join(vendorDir, filepath.FromSlash(mod.Path))
+		if err := os.RemoveAll(dir); err != nil {
+			return nil, nil, err
+		}
+		// Remove parent directories which are now empty, e.g.
+		// vendor/github.com/foo/ after removing vendor/github.com/foo/bar/.
+		for parent := filepath.Dir(dir); parent != vendorDir; parent = filepath.Dir(parent) {
+			if err := os.Remove(parent); err != nil {
+				break // not empty
+			}
+		}
+		if !slices.Contains(binaries, pkg.Binary) {
+			binaries = append(binaries, pkg.Binary)
+		}
+	}
+	return remaining, binaries, nil
+}
+
+// hasNestedModule returns whether any of modules is located in a
+// subdirectory of the module with the given path, e.g. example.com/foo/v2
+// within example.com/foo, which prevents removing the latter from vendor/.
+func hasNestedModule(modules []VendoredModule, path string) bool {
+	return slices.ContainsFunc(modules, func(mod VendoredModule) bool {
+		return strings.HasPrefix(mod.Path, path+"/")
+	})
+}
+
+// formatLicenseText formats text as the body of a License paragraph in
+// debian/copyright.
+func formatLicenseText(text string) string {
+	var b strings.Builder
+	for line := range strings.SplitSeq(strings.TrimSpace(text), "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" {
+			line = "."
+		}
+		b.WriteString(" " + line + "\n")
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// writeVendoredCopyright writes the Files paragraphs of the vendored
+// modules to w, and returns the License paragraphs of the licenses which
+// differ from the license of the main package.
+func writeVendoredCopyright(w io.Writer, modules []VendoredModule, mainLicense, linebreak string) (licenses []string) {
+	seen := map[string]bool{mainLicense: true, "TODO": true}
+	sep := "\n" + strings.Repeat(" ", len("Copyright: "))
+	if linebreak != "" {
+		sep = "\n "
+	}
+	for _, mod := range modules {
+		copyright := "TODO"
+		if len(mod.Copyright) > 0 {
+			copyright = strings.Join(mod.Copyright, sep)
+		}
+		fmt.Fprintf(w, "Files:%s vendor/%s/*\n", linebreak, mod.Path)
+		fmt.Fprintf(w, "Copyright:%s %s\n", linebreak, copyright)
+		fmt.Fprintf(w, "License: %s\n", mod.License)
+		fmt.Fprintf(w, "Comment: vendored module %s %s\n", mod.Path, mod.Version)
+		fmt.Fprintf(w, "\n")
+
+		if seen[mod.License] {
+			continue
+		}
+		seen[mod.License] = true
+		text := debianLicenseText[mod.License]
+		if text == "" {
+			text = formatLicenseText(mod.LicenseText)
+		}
+		licenses = append(licenses, fmt.Sprintf("License: %s\n%s\n", mod.License, text))
+	}
+	return licenses
+}
@@ -0,0 +1,168 @@
+package golangdeb
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const testModulesTxt = `# golang.org/x/sys v0.20.0
+## explicit; go 1.18
+golang.org/x/sys/unix
+# github.com/spf13/pflag v1.0.5
+## explicit
+github.com/spf13/pflag
+# github.com/foo/bar v1.0.0 => github.com/fork/bar v1.0.1
+## explicit
+github.com/foo/bar
+# example.com/local v0.0.0 => ../local
+## explicit
+example.com/local
+`
+
+func TestParseVendorModules(t *testing.T) {
+	got, err := parseVendorModules(strings.NewReader(testModulesTxt))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []VendoredModule{
+		{Path: "github.com/foo/bar", Version: "v1.0.1"},
+		{Path: "github.com/spf13/pflag", Version: "v1.0.5"},
+		{Path: "golang.org/x/sys", Version: "v0.20.0"},
+	}
+	if diff := cmp.Diff(want, got, cmp.AllowUnexported(VendoredModule{})); diff != "" {
+		t.Errorf("parseVendorModules: diff (-want +got):\n%s", diff)
+	}
+}
+
+const testBSD3License = `Copyright (c) 2009 The Go Authors. All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are
+met:
+
+   * Neither the name of Google Inc. nor the names of its
+contributors may be used to endorse or promote products derived from
+this software without specific prior written permission.
+`
+
+func TestDetectLicense(t *testing.T) {
+	for _, tt := range []struct {
+		text string
+		want string
+	}{
+		{testBSD3License, "BSD-3-clause"},
+		{"Copyright (c) 2014 Foo\n\nPermission is hereby granted, free of charge, to any person", "Expat"},
+		{"                                 Apache License\n                           Version 2.0, January 2004", "Apache-2.0"},
+		{"Mozilla Public License Version 2.0\n==================================", "MPL-2.0"},
+		{"All rights reserved.", "TODO"},
+	} {
+		if got := detectLicense(tt.text); got != tt.want {
+			t.Errorf("detectLicense(%.30q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestCopyrightLines(t *testing.T) {
+	text := "Copyright (c) 2009 The Go Authors. All rights reserved.\n" +
+		"Copyright 2015 Jane Doe\n" +
+		"copyright notice below\n" +
+		"Copyright (c) 2009 The Go Authors. All rights reserved.\n"
+	want := []string{"2009 The Go Authors. All rights reserved.", "2015 Jane Doe"}
+	if diff := cmp.Diff(want, copyrightLines(text)); diff != "" {
+		t.Errorf("copyrightLines: diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestWriteVendoredCopyright(t *testing.T) {
+	vendorDir := t.TempDir()
+	modules, err := parseVendorModules(strings.NewReader(testModulesTxt))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Join(vendorDir, "golang.org", "x", "sys")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "LICENSE"), []byte(testBSD3License), 0644); err != nil {
+		t.Fatal(err)
+	}
+	scanVendoredLicenses(vendorDir, modules)
+
+	var buf bytes.Buffer
+	licenses := writeVendoredCopyright(&buf, modules, "Expat", "")
+	if got, want := buf.String(), `Files: vendor/github.com/foo/bar/*
+Copyright: TODO
+License: TODO
+Comment: vendored module github.com/foo/bar v1.0.1
+
+Files: vendor/github.com/spf13/pflag/*
+Copyright: TODO
+License: TODO
+Comment: vendored module github.com/spf13/pflag v1.0.5
+
+Files: vendor/golang.org/x/sys/*
+Copyright: 2009 The Go Authors. All rights reserved.
+License: BSD-3-clause
+Comment: vendored module golang.org/x/sys v0.20.0
+
+`; got != want {
+		t.Errorf("writeVendoredCopyright: got\n%s\nwant\n%s", got, want)
+	}
+	if len(licenses) != 1 || !strings.HasPrefix(licenses[0], "License: BSD-3-clause\n Copyright (c) 2009 The Go Authors.") || !strings.Contains(licenses[0], "\n .\n") {
+		t.Errorf("writeVendoredCopyright: unexpected License paragraphs %q", licenses)
+	}
+}
+
+func TestRemovePackagedModules(t *testing.T) {
+	vendorDir := t.TempDir()
+	modules := []VendoredModule{
+		{Path: "example.com/nested"},
+		{Path: "example.com/nested/v2"},
+		{Path: "github.com/foo/bar"},
+		{Path: "github.com/foo/baz"},
+		{Path: "golang.org/x/sys"},
+	}
+	for _, mod := range modules {
+		if err := os.MkdirAll(filepath.Join(vendorDir, filepath.FromSlash(mod.Path)), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	golangBinaries := map[string]DebianPackage{
+		"example.com/nested": {Binary: "golang-example-nested-dev", Source: "golang-example-nested"},
+		"github.com/foo/bar": {Binary: "golang-github-foo-bar-dev", Source: "golang-github-foo-bar"},
+		"golang.org/x/sys":   {Binary: "golang-golang-x-sys-dev", Source: "golang-golang-x-sys"},
+	}
+
+	remaining, binaries, err := removePackagedModules(vendorDir, modules, golangBinaries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantRemaining := []VendoredModule{
+		{Path: "example.com/nested"},
+		{Path: "example.com/nested/v2"},
+		{Path: "github.com/foo/baz"},
+	}
+	if diff := cmp.Diff(wantRemaining, remaining, cmp.AllowUnexported(VendoredModule{})); diff != "" {
+		t.Errorf("removePackagedModules: remaining diff (-want +got):\n%s", diff)
+	}
+	wantBinaries := []string{"golang-github-foo-bar-dev", "golang-golang-x-sys-dev"}
+	if diff := cmp.Diff(wantBinaries, binaries); diff != "" {
+		t.Errorf("removePackagedModules: binaries diff (-want +got):\n%s", diff)
+	}
+
+	for _, path := range []string{"github.com/foo/bar", "golang.org"} {
+		if _, err := os.Stat(filepath.Join(vendorDir, path)); !os.IsNotExist(err) {
+			t.Errorf("%s was not removed from vendor/", path)
+		}
+	}
+	for _, path := range []string{"example.com/nested/v2", "github.com/foo/baz"} {
+		if _, err := os.Stat(filepath.Join(vendorDir, path)); err != nil {
+			t.Errorf("%s was removed from vendor/: %v", path, err)
+		}
+	}
+}
@@ -0,0 +1,280 @@
+package golangdeb
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+	"pault.ag/go/debian/version"
+)
+
+var (
+	// describeRegexp parses the count and revision part of the “git describe --long” output.
+	describeRegexp = regexp.MustCompile(`-\d+-g([0-9a-f]+)\s*$`)
+
+	// semverRegexp checks if a string is a valid Go semver,
+	// from https://semver.org/#is-there-a-suggested-regular-expression-regex-to-check-a-semver-string
+	// with leading "v" added.
+	semverRegexp = regexp.MustCompile(`^v(?P<major>0|[1-9]\d*)\.(?P<minor>0|[1-9]\d*)\.(?P<patch>0|[1-9]\d*)(?:-(?P<prerelease>(?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*)(?:\.(?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*))*))?(?:\+(?P<buildmetadata>[0-9a-zA-Z-]+(?:\.[0-9a-zA-Z-]+)*))?$`)
+
+	// uversionPrereleaseRegexp checks for upstream pre-release
+	// so that '-' can be replaced with '~' in pkgVersionFromGit.
+	// To be kept in sync with the regexp portion of uversionmanglePattern in template.go
+	uversionPrereleaseRegexp = regexp.MustCompile(`(\d)[_\.\-\+]?(RC|rc|pre|dev|beta|alpha)[.]?(\d*)$`)
+)
+
+// LatestGitTag returns the latest version tag (whether annotated or not)
+// reachable from HEAD in the git repository gitdir. Tags of submodules
+// (e.g. “foo/v1.2.3”) are ignored.
+func LatestGitTag(ctx context.Context, gitdir string) (string, error) {
+	return latestGitTagOf(ctx, gitdir, "HEAD")
+}
+
+// latestGitTagOf is LatestGitTag for the revision rev instead of HEAD.
+func latestGitTagOf(ctx context.Context, gitdir, rev string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", "describe", "--abbrev=0", "--tags", "--exclude", "*/v*", rev)
+	cmd.Dir = gitdir
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git describe: %w", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// UpstreamVersionFromTag mangles an upstream version tag into a Debian
+// upstream_version, e.g. “v1.2.0-rc1” → “1.2.0~rc1”.
+func UpstreamVersionFromTag(tag string) string {
+	return strings.TrimLeftFunc(
+		uversionPrereleaseRegexp.ReplaceAllString(tag, "$1~$2$3"),
+		func(r rune) bool {
+			return !unicode.IsNumber(r)
+		},
+	)
+}
+
+// gitCommitDate returns the committer date of the given revision.
+func gitCommitDate(ctx context.Context, gitdir, rev string) (time.Time, error) {
+	cmd := exec.CommandContext(ctx, "git", "log", "--pretty=format:%ct", "-n1", "--no-show-signature", rev)
+	cmd.Dir = gitdir
+	out, err := cmd.Output()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("git log: %w", err)
+	}
+	unix, err := strconv.ParseInt(strings.TrimSpace(string(out)), 0, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parse commit date: %w", err)
+	}
+	return time.Unix(unix, 0).UTC(), nil
+}
+
+// RemoteActivity returns the date of the latest commit fetched from the git
+// remote of the repository gitdir (e.g. the upstream remote of a packaging
+// repository), and the latest version tag reachable from that commit with
+// its date. tag is empty if upstream has not tagged any release.
+func RemoteActivity(ctx context.Context, gitdir, remote string) (lastCommit time.Time, tag string, tagDate time.Time, err error) {
+	cmd := exec.CommandContext(ctx, "git", "for-each-ref", "--sort=-committerdate", "--count=1", "--format=%(refname)", "refs/remotes/"+remote+"/")
+	cmd.Dir = gitdir
+	out, err := cmd.Output()
+	if err != nil {
+		return time.Time{}, "", time.Time{}, fmt.Errorf("git for-each-ref: %w", err)
+	}
+	ref := strings.TrimSpace(string(out))
+	if ref == "" {
+		return time.Time{}, "", time.Time{}, fmt.Errorf("no branches of remote %q in %s", remote, gitdir)
+	}
+	if lastCommit, err = gitCommitDate(ctx, gitdir, ref); err != nil {
+		return time.Time{}, "", time.Time{}, err
+	}
+	// Fails if there is no tag.
+	if tag, _ = latestGitTagOf(ctx, gitdir, ref); tag != "" {
+		if tagDate, err = gitCommitDate(ctx, gitdir, tag); err != nil {
+			return lastCommit, tag, time.Time{}, err
+		}
+	}
+	return lastCommit, tag, tagDate, nil
+}
+
+// pkgVersionFromGit determines the actual version to be packaged
+// from the git repository status and user preference.
+// Besides returning the Debian upstream version, the "upstream" struct
+// struct fields u.version, u.commitIsh, u.hasRelease and u.isRelease
+// are also set.
+// `preferredRev` should be empty if there are no user preferences.
+// TODO: also support other VCS
+func pkgVersionFromGit(ctx context.Context, gitdir string, u *Upstream, preferredRev string, forcePrerelease bool) (string, error) {
+	var latestTag string
+	var commitsAhead int
+
+	var cmd *exec.Cmd // the temporary shell commands we execute
+
+	// If the user specifies a valid tag as the preferred revision, that tag should be used without additional heuristics.
+	if preferredRev != "" {
+		if err := RunGitCommandIn(ctx, gitdir, "rev-parse", "--verify", "--quiet", "refs/tags/"+preferredRev); err == nil {
+			latestTag = preferredRev
+		}
+	}
+
+	// Find @latest version tag (whether annotated or not) when the user
+	// (1) does not specify a version tag, or
+	// (2) specifies an invalid version tag.
+	if len(latestTag) == 0 {
+		latestTag, _ = LatestGitTag(ctx, gitdir)
+	}
+
+	if len(latestTag) > 0 {
+		u.HasRelease = true
+		u.Tag = latestTag
+		log.Printf("Found latest tag %q", latestTag)
+
+		if !semverRegexp.MatchString(latestTag) {
+			log.Printf("WARNING: Latest tag %q is not a valid SemVer version\n", latestTag)
+			// TODO: Enforce strict sementic versioning with leading "v"?
+		}
+
+		// Count number of commits since @latest version
+		cmd = exec.CommandContext(ctx, "git", "rev-list", "--count", latestTag+"..HEAD")
+		cmd.Dir = gitdir
+		out, err := cmd.Output()
+		if err != nil {
+			return "", fmt.Errorf("git rev-list: %w", err)
+		}
+		commitsAhead, err = strconv.Atoi(strings.TrimSpace(string(out)))
+		if err != nil {
+			return "", fmt.Errorf("parse commits ahead: %w", err)
+		}
+
+		if commitsAhead == 0 {
+			// Equivalent to "git describe --exact-match --tags"
+			log.Printf("Latest tag %q matches master", latestTag)
+		} else {
+			log.Printf("INFO: master is ahead of %q by %v commits", latestTag, commitsAhead)
+		}
+
+		u.CommitIsh = latestTag
+
+		u.Version = UpstreamVersionFromTag(latestTag)
+
+		if forcePrerelease {
+			log.Printf("INFO: Force packaging master (prerelease) as requested by user")
+			// Fallthrough to package @master (prerelease)
+		} else {
+			u.IsRelease = true
+			return u.Version, nil
+		}
+	}
+
+	// Packaging @master (prerelease)
+
+	// 1.0~rc1 < 1.0 < 1.0+b1, as per
+	// https://www.debian.org/doc/manuals/maint-guide/first.en.html#namever
+	mainVer := "0.0~"
+	if u.HasRelease {
+		mainVer = u.Version + "+"
+	}
+
+	// Find committer date, UNIX timestamp
+	cmd = exec.CommandContext(ctx, "git", "log", "--pretty=format:%ct", "-n1", "--no-show-signature")
+	cmd.Dir = gitdir
+	lastCommitUnixBytes, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git log: %w", err)
+	}
+	lastCommitUnix, err := strconv.ParseInt(strings.TrimSpace(string(lastCommitUnixBytes)), 0, 64)
+	if err != nil {
+		return "", fmt.Errorf("parse last commit date: %w", err)
+	}
+
+	// This results in an output like "v4.10.2-232-g9f107c8"
+	cmd = exec.CommandContext(ctx, "git", "describe", "--long", "--tags")
+	cmd.Dir = gitdir
+	lastCommitHash := ""
+	describeBytes, err := cmd.Output()
+	if err != nil {
+		// In case there are no tags at all, we just use the sha of the current commit
+		cmd = exec.CommandContext(ctx, "git", "rev-parse", "--short", "HEAD")
+		cmd.Dir = gitdir
+		cmd.Stderr = os.Stderr
+		revparseBytes, err := cmd.Output()
+		if err != nil {
+			return "", fmt.Errorf("git rev-parse: %w", err)
+		}
+		lastCommitHash = strings.TrimSpace(string(revparseBytes))
+		u.CommitIsh = lastCommitHash
+	} else {
+		submatches := describeRegexp.FindSubmatch(describeBytes)
+		if submatches == nil {
+			return "", fmt.Errorf("git describe output %q does not match expected format", string(describeBytes))
+		}
+		lastCommitHash = string(submatches[1])
+		u.CommitIsh = strings.TrimSpace(string(describeBytes))
+	}
+	u.Version = fmt.Sprintf("%sgit%s.%s",
+		mainVer,
+		time.Unix(lastCommitUnix, 0).UTC().Format("20060102"),
+		lastCommitHash)
+	return u.Version, nil
+}
+
+// pkgVersionFromModule determines the version to be packaged from the
+// module version modVersion, published at t, like pkgVersionFromGit: a
+// release “v1.2.0-rc1” becomes “1.2.0~rc1”, a pseudo-version following it
+// “1.2.0~rc1+git20240102.abcdef1”, and one without any release before it
+// “0.0~git20240102.abcdef1”. The same fields of u are set.
+func pkgVersionFromModule(u *Upstream, modVersion string, t time.Time) (string, error) {
+	v := strings.TrimSuffix(modVersion, "+incompatible")
+	if !semver.IsValid(v) {
+		return "", fmt.Errorf("invalid module version %q", modVersion)
+	}
+	if !module.IsPseudoVersion(v) {
+		u.HasRelease = true
+		u.IsRelease = true
+		u.Tag = v
+		u.CommitIsh = v
+		u.TagDate = t
+		u.LastCommit = t
+		u.Version = UpstreamVersionFromTag(v)
+		return u.Version, nil
+	}
+
+	base, err := module.PseudoVersionBase(v)
+	if err != nil {
+		return "", err
+	}
+	rev, err := module.PseudoVersionRev(v)
+	if err != nil {
+		return "", err
+	}
+	if u.LastCommit, err = module.PseudoVersionTime(v); err != nil {
+		return "", err
+	}
+	mainVer := "0.0~"
+	if base != "" {
+		u.HasRelease = true
+		u.Tag = base
+		mainVer = UpstreamVersionFromTag(base) + "+"
+	}
+	u.CommitIsh = rev
+	// Abbreviated to 7 characters, like git does by default.
+	u.Version = fmt.Sprintf("%sgit%s.%s", mainVer, u.LastCommit.Format("20060102"), rev[:min(7, len(rev))])
+	return u.Version, nil
+}
+
+// CompareVersions compares two Debian version strings, falling back to a
+// string comparison if either of them cannot be parsed.
+func CompareVersions(a, b string) int {
+	va, errA := version.Parse(a)
+	vb, errB := version.Parse(b)
+	if errA != nil || errB != nil {
+		return strings.Compare(a, b)
+	}
+	return version.Compare(va, vb)
+}
@@ -0,0 +1,206 @@
+package golangdeb
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func gitCmdOrFatal(t *testing.T, tempdir string, arg ...string) {
+	cmd := exec.Command("git", arg...)
+	cmd.Dir = tempdir
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("Could not run %v: %v", cmd.Args, err)
+	}
+}
+
+func TestSnapshotVersion(t *testing.T) {
+	os.Setenv("TZ", "UTC")
+	defer os.Unsetenv("TZ")
+
+	tempdir, err := os.MkdirTemp("", "dh-make-golang")
+	if err != nil {
+		t.Fatalf("Could not create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempdir)
+
+	tempfile := filepath.Join(tempdir, "test")
+	if err := os.WriteFile(tempfile, []byte("testcase"), 0644); err != nil {
+		t.Fatalf("Could not write temp file %q: %v", tempfile, err)
+	}
+
+	// Suppress git hint about default branch name by setting init.defaultBranch
+	gitCmdOrFatal(t, tempdir, "init", "--initial-branch=master")
+	gitCmdOrFatal(t, tempdir, "config", "user.email", "unittest@example.com")
+	gitCmdOrFatal(t, tempdir, "config", "user.name", "Unit Test")
+	gitCmdOrFatal(t, tempdir, "add", "test")
+	cmd := exec.Command("git", "commit", "-a", "-m", "initial commit")
+	cmd.Env = append(os.Environ(), "GIT_COMMITTER_DATE=2015-04-20T11:22:33")
+	cmd.Dir = tempdir
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("Could not run %v: %v", cmd.Args, err)
+	}
+
+	var u Upstream
+	got, err := pkgVersionFromGit(t.Context(), tempdir, &u, "", false)
+	if err != nil {
+		t.Fatalf("Determining package version from git failed: %v", err)
+	}
+	if want := "0.0~git20150420."; !strings.HasPrefix(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	date, err := gitCommitDate(t.Context(), tempdir, "HEAD")
+	if err != nil {
+		t.Fatalf("Determining commit date from git failed: %v", err)
+	}
+	if want := time.Date(2015, 4, 20, 11, 22, 33, 0, time.UTC); !date.Equal(want) {
+		t.Errorf("gitCommitDate: got %v, want %v", date, want)
+	}
+
+	gitCmdOrFatal(t, tempdir, "tag", "-a", "v1", "-m", "release v1")
+
+	got, err = pkgVersionFromGit(t.Context(), tempdir, &u, "", false)
+	if err != nil {
+		t.Fatalf("Determining package version from git failed: %v", err)
+	}
+	if want := "1"; got != want {
+		t.Logf("got %q, want %q", got, want)
+	}
+
+	if err := os.WriteFile(tempfile, []byte("testcase 2"), 0644); err != nil {
+		t.Fatalf("Could not write temp file %q: %v", tempfile, err)
+	}
+
+	cmd = exec.Command("git", "commit", "-a", "-m", "first change")
+	cmd.Env = append(os.Environ(), "GIT_COMMITTER_DATE=2015-05-07T11:22:33")
+	cmd.Dir = tempdir
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("Could not run %v: %v", cmd.Args, err)
+	}
+
+	got, err = pkgVersionFromGit(t.Context(), tempdir, &u, "", false)
+	if err != nil {
+		t.Fatalf("Determining package version from git failed: %v", err)
+	}
+	if want := "1+git20150507.1."; !strings.HasPrefix(got, want) {
+		t.Logf("got %q, want %q", got, want)
+	}
+
+	if err := os.WriteFile(tempfile, []byte("testcase 3"), 0644); err != nil {
+		t.Fatalf("Could not write temp file %q: %v", tempfile, err)
+	}
+
+	cmd = exec.Command("git", "commit", "-a", "-m", "second change")
+	cmd.Env = append(os.Environ(), "GIT_COMMITTER_DATE=2015-05-08T11:22:33")
+	cmd.Dir = tempdir
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("Could not run %v: %v", cmd.Args, err)
+	}
+
+	got, err = pkgVersionFromGit(t.Context(), tempdir, &u, "", false)
+	if err != nil {
+		t.Fatalf("Determining package version from git failed: %v", err)
+	}
+	if want := "1+git20150508.2."; !strings.HasPrefix(got, want) {
+		t.Logf("got %q, want %q", got, want)
+	}
+}
+
+func TestRemoteActivity(t *testing.T) {
+	upstream := newLocalCheckout(t, nil)
+	cmd := exec.Command("git", "commit", "--quiet", "--allow-empty", "-m", "Latest change")
+	cmd.Env = append(os.Environ(), "GIT_COMMITTER_DATE=2026-09-01T12:00:00Z")
+	cmd.Dir = upstream
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("Could not run %v: %v", cmd.Args, err)
+	}
+	tagDate, err := gitCommitDate(t.Context(), upstream, "v1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A packaging repository, whose own tags are not upstream releases.
+	dir := t.TempDir()
+	gitCmdOrFatal(t, dir, "init", "--quiet", "--initial-branch=debian/sid")
+	gitCmdOrFatal(t, dir, "config", "user.email", "unittest@example.com")
+	gitCmdOrFatal(t, dir, "config", "user.name", "Unit Test")
+	gitCmdOrFatal(t, dir, "commit", "--quiet", "--allow-empty", "-m", "Initial packaging")
+	gitCmdOrFatal(t, dir, "tag", "debian/1.0.0-1")
+
+	if _, _, _, err := RemoteActivity(t.Context(), dir, "example"); err == nil {
+		t.Error("RemoteActivity without remote: got no error")
+	}
+
+	gitCmdOrFatal(t, dir, "remote", "add", "example", upstream)
+	gitCmdOrFatal(t, dir, "fetch", "--quiet", "--tags", "example")
+	lastCommit, tag, gotTagDate, err := RemoteActivity(t.Context(), dir, "example")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2026, 9, 1, 12, 0, 0, 0, time.UTC); !lastCommit.Equal(want) {
+		t.Errorf("RemoteActivity: got last commit %v, want %v", lastCommit, want)
+	}
+	if tag != "v1.0.0" || !gotTagDate.Equal(tagDate) {
+		t.Errorf("RemoteActivity: got tag %q (%v), want v1.0.0 (%v)", tag, gotTagDate, tagDate)
+	}
+}
+
+var tagVersions = []struct {
+	tag  string
+	want string
+}{
+	{"v1.2.3", "1.2.3"},
+	{"1.2.3", "1.2.3"},
+	{"release-2.0", "2.0"},
+	{"v1.0.0-rc1", "1.0.0~rc1"},
+	{"v2.1.0-beta.2", "2.1.0~beta2"},
+}
+
+func TestUpstreamVersionFromTag(t *testing.T) {
+	for _, tt := range tagVersions {
+		if got := UpstreamVersionFromTag(tt.tag); got != tt.want {
+			t.Errorf("upstreamVersionFromTag(%q) => %q, want %q", tt.tag, got, tt.want)
+		}
+	}
+}
+
+var moduleVersions = []struct {
+	modVersion string
+	want       string
+	tag        string
+	isRelease  bool
+}{
+	{"v1.2.3", "1.2.3", "v1.2.3", true},
+	{"v1.0.0-rc1", "1.0.0~rc1", "v1.0.0-rc1", true},
+	{"v2.0.0+incompatible", "2.0.0", "v2.0.0", true},
+	{"v0.0.0-20240102030405-abcdef123456", "0.0~git20240102.abcdef1", "", false},
+	{"v1.2.4-0.20240102030405-abcdef123456", "1.2.3+git20240102.abcdef1", "v1.2.3", false},
+	{"v1.0.0-rc1.0.20240102030405-abcdef123456", "1.0.0~rc1+git20240102.abcdef1", "v1.0.0-rc1", false},
+}
+
+func TestPkgVersionFromModule(t *testing.T) {
+	for _, tt := range moduleVersions {
+		var u Upstream
+		got, err := pkgVersionFromModule(&u, tt.modVersion, time.Time{})
+		if err != nil {
+			t.Errorf("pkgVersionFromModule(%q): %v", tt.modVersion, err)
+			continue
+		}
+		if got != tt.want || u.Tag != tt.tag || u.IsRelease != tt.isRelease || u.HasRelease != (tt.tag != "") {
+			t.Errorf("pkgVersionFromModule(%q) => %q (tag %q, release %v), want %q (tag %q, release %v)",
+				tt.modVersion, got, u.Tag, u.IsRelease, tt.want, tt.tag, tt.isRelease)
+		}
+	}
+	if _, err := pkgVersionFromModule(&Upstream{}, "master", time.Time{}); err == nil {
+		t.Errorf("pkgVersionFromModule(%q): got nil error", "master")
+	}
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/Debian/dh-make-golang/pkg/golangdeb"
+	"golang.org/x/mod/modfile"
+)
+
+// rdep is a source package which build-depends on a library, directly or
+// through other libraries.
+type rdep struct {
+	source   string
+	children []*rdep
+}
+
+// resolveSourcePackage maps the argument of the rdeps command, which is
+// either a Go import path or a Debian source or binary package name, to a
+// source package in sources.
+func resolveSourcePackage(arg string, sources map[string]sourceEntry, golangBinaries map[string]golangdeb.DebianPackage) (string, error) {
+	if pkg, ok := golangBinaries[arg]; ok {
+		return pkg.Source, nil
+	}
+	if _, ok := sources[arg]; ok {
+		return arg, nil
+	}
+	for _, entry := range sources {
+		if slices.Contains(entry.binaries, arg) || slices.Contains(entry.importPaths, arg) {
+			return entry.name, nil
+		}
+	}
+	return "", fmt.Errorf("%q is neither a known Go import path nor a Debian package", arg)
+}
+
+// reverseDependencies returns the tree of source packages which
+// transitively build-depend on the binary packages of the source package
+// root. Every source package appears only once in the tree, at the
+// shallowest level at which it was found.
+func reverseDependencies(sources map[string]sourceEntry, root string) []*rdep {
+	// binary package name → source packages build-depending on it
+	buildDependedBy := make(map[string][]string)
+	for _, entry := range sources {
+		for _, dep := range entry.buildDepends {
+			buildDependedBy[dep] = append(buildDependedBy[dep], entry.name)
+		}
+	}
+
+	seen := map[string]bool{root: true}
+	directRdeps := func(source string) []*rdep {
+		var names []string
+		for _, bin := range sources[source].binaries {
+			for _, name := range buildDependedBy[bin] {
+				if !seen[name] {
+					seen[name] = true
+					names = append(names, name)
+				}
+			}
+		}
+		slices.Sort(names)
+		rdeps := make([]*rdep, len(names))
+		for i, name := range names {
+			rdeps[i] = &rdep{source: name}
+		}
+		return rdeps
+	}
+
+	// Walk breadth-first so that packages are listed at their shallowest level.
+	top := directRdeps(root)
+	queue := slices.Clone(top)
+	for len(queue) > 0 {
+		r := queue[0]
+		queue = queue[1:]
+		r.children = directRdeps(r.source)
+		queue = append(queue, r.children...)
+	}
+	return top
+}
+
+// modulePathMajor splits a module path into its path without major version
+// suffix and its major version (1 for paths without suffix).
+func modulePathMajor(path string) (string, int) {
+	matches := majorVersionRegexp.FindStringSubmatch(path)
+	if matches == nil {
+		return path, 1
+	}
+	major, _ := strconv.Atoi(matches[2])
+	return path[:len(path)-len(matches[0])], major
+}
+
+// fetchGoMod downloads the go.mod file of the Debian packaging repository
+// at vcsBrowser, which needs to be hosted on a GitLab instance like salsa.
+func fetchGoMod(ctx context.Context, vcsBrowser string) (*modfile.File, error) {
+	if vcsBrowser == "" {
+		return nil, fmt.Errorf("no Vcs-Browser")
+	}
+	url := strings.TrimSuffix(vcsBrowser, "/") + "/-/raw/HEAD/go.mod"
+	resp, err := golangdeb.HTTPGet(ctx, url)
+	if err != nil {
+		return nil, fmt.Errorf("getting %q: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if got, want := resp.StatusCode, http.StatusOK; got != want {
+		return nil, fmt.Errorf("getting %q: unexpected HTTP status code: got %d, want %d", url, got, want)
+	}
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return modfile.Parse(url, b, nil)
+}
+
+// majorVersionImpact determines whether a source package using the given
+// go.mod would break if the library with the given import paths moved to
+// the major version newMajor. It returns the required module paths of the
+// library and whether any of them has a different major version.
+func majorVersionImpact(mf *modfile.File, libPaths []string, newMajor int) (used []string, breaks bool) {
+	bases := make(map[string]bool)
+	for _, p := range libPaths {
+		base, _ := modulePathMajor(p)
+		bases[base] = true
+	}
+	for _, req := range mf.Require {
+		base, major := modulePathMajor(req.Mod.Path)
+		if !bases[base] {
+			continue
+		}
+		used = append(used, req.Mod.Path+"@"+req.Mod.Version)
+		if major != newMajor {
+			breaks = true
+		}
+	}
+	return used, breaks
+}
+
+func execRdeps(ctx context.Context, args []string) {
+	fs := flag.NewFlagSet("rdeps", flag.ExitOnError)
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s rdeps [flags] <go-package-importpath|package-name>\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Lists the Debian source packages which build-depend on the given\n"+
+			"Go library, transitively, based on the local APT Sources indices.\n")
+		fmt.Fprintf(os.Stderr, "Example: %s rdeps -major 2 github.com/spf13/cobra\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "\n")
+		fmt.Fprintf(os.Stderr, "Flags:\n")
+		fs.PrintDefaults()
+	}
+
+	var sourcesGlob string
+	fs.StringVar(&sourcesGlob,
+		"sources",
+		defaultSourcesGlob,
+		"Glob pattern of the APT Sources indices (plain or .gz) to read\n"+
+			"the Build-Depends from. Requires deb-src lines in your APT sources.")
+
+	var newMajor int
+	fs.IntVar(&newMajor,
+		"major",
+		0,
+		"If set, check for every direct reverse dependency whether it would\n"+
+			"break if the library moved to this major version, based on the\n"+
+			"module paths in the go.mod of its packaging repository.")
+
+	arch := addArchiveFlags(fs)
+
+	if err := fs.Parse(args); err != nil {
+		log.Fatalf("parse args: %s", err)
+	}
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	sources, err := loadSourcesIndices(ctx, sourcesGlob)
+	if err != nil {
+		log.Fatalf("load Sources indices: %s", err)
+	}
+
+	golangBinaries, err := arch.golangBinaries(ctx)
+	if err != nil {
+		log.Printf("Could not get Go packages from the archive, using Sources indices only: %v", err)
+	}
+
+	root, err := resolveSourcePackage(fs.Arg(0), sources, golangBinaries)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if _, ok := sources[root]; !ok {
+		log.Fatalf("source package %s is not in the Sources indices", root)
+	}
+	libPaths := sources[root].importPaths
+	for importPath, pkg := range golangBinaries {
+		if pkg.Source == root && !slices.Contains(libPaths, importPath) {
+			libPaths = append(libPaths, importPath)
+		}
+	}
+
+	rdeps := reverseDependencies(sources, root)
+	if len(rdeps) == 0 {
+		log.Printf("No source package build-depends on %s", root)
+		return
+	}
+	log.Printf("Source packages build-depending on %s:", root)
+
+	var visit func(r *rdep, indent int)
+	visit = func(r *rdep, indent int) {
+		line := strings.Repeat("  ", indent) + r.source
+		if newMajor > 0 && indent == 0 {
+			mf, err := fetchGoMod(ctx, sources[r.source].vcsBrowser)
+			switch {
+			case err != nil:
+				line += hiblackf(" (impact unknown: %v)", err)
+			default:
+				used, breaks := majorVersionImpact(mf, libPaths, newMajor)
+				switch {
+				case len(used) == 0:
+					line += hiblackf(" (impact unknown: not in go.mod)")
+				case breaks:
+					line += fmt.Sprintf(" BREAKS with v%d (uses %s)", newMajor, strings.Join(used, ", "))
+				default:
+					line += hiblackf(" (uses %s)", strings.Join(used, ", "))
+				}
+			}
+		}
+		fmt.Println(line)
+		for _, child := range r.children {
+			visit(child, indent+1)
+		}
+	}
+	for _, r := range rdeps {
+		visit(r, 0)
+	}
+}
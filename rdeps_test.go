@@ -0,0 +1,86 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/mod/modfile"
+)
+
+func testSources(t *testing.T) map[string]sourceEntry {
+	t.Helper()
+	entries, err := parseSourcesIndex(strings.NewReader(testSourcesIndex))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sources := make(map[string]sourceEntry)
+	for _, entry := range entries {
+		sources[entry.name] = entry
+	}
+	return sources
+}
+
+func flattenRdeps(rdeps []*rdep, indent int) []string {
+	var lines []string
+	for _, r := range rdeps {
+		lines = append(lines, strings.Repeat(" ", indent)+r.source)
+		lines = append(lines, flattenRdeps(r.children, indent+1)...)
+	}
+	return lines
+}
+
+func TestReverseDependencies(t *testing.T) {
+	sources := testSources(t)
+	got := flattenRdeps(reverseDependencies(sources, "golang-github-example-foo"), 0)
+	want := []string{
+		"golang-github-example-bar",
+		" baz",
+		"qux",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("reverseDependencies() => %q, want %q", got, want)
+	}
+}
+
+func TestResolveSourcePackage(t *testing.T) {
+	sources := testSources(t)
+	for _, arg := range []string{
+		"golang-github-example-bar",
+		"golang-github-example-bar-dev",
+		"github.com/example/bar/v2",
+	} {
+		got, err := resolveSourcePackage(arg, sources, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := "golang-github-example-bar"; got != want {
+			t.Errorf("resolveSourcePackage(%q) => %q, want %q", arg, got, want)
+		}
+	}
+	if _, err := resolveSourcePackage("example.org/unknown", sources, nil); err == nil {
+		t.Errorf("resolveSourcePackage() for unknown package did not fail")
+	}
+}
+
+func TestMajorVersionImpact(t *testing.T) {
+	mf, err := modfile.Parse("go.mod", []byte(`module example.org/baz
+
+require (
+	github.com/example/bar v1.4.0
+	github.com/example/other v1.0.0
+)
+`), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	libPaths := []string{"github.com/example/bar"}
+
+	used, breaks := majorVersionImpact(mf, libPaths, 2)
+	if want := []string{"github.com/example/bar@v1.4.0"}; !reflect.DeepEqual(used, want) || !breaks {
+		t.Errorf("majorVersionImpact(v2) => %v, %v, want %v, true", used, breaks, want)
+	}
+	if _, breaks := majorVersionImpact(mf, libPaths, 1); breaks {
+		t.Errorf("majorVersionImpact(v1) => breaks, want no breakage")
+	}
+}
@@ -0,0 +1,332 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/Debian/dh-make-golang/pkg/golangdeb"
+	"pault.ag/go/debian/changelog"
+	"pault.ag/go/debian/control"
+)
+
+// regeneratedFiles are the files in debian/ which "make -regenerate"
+// writes. The others (e.g. changelog and rules) are usually edited by hand
+// soon after the packaging was created.
+var regeneratedFiles = []string{
+	"control",
+	"copyright",
+	"watch",
+	"upstream/metadata",
+	"gbp.conf",
+	"salsa-ci.yml",
+}
+
+// fixedBuildDepends are the build dependencies which writeDebianControl
+// always adds, and which are therefore not carried over.
+var fixedBuildDepends = []string{
+	"debhelper-compat",
+	"dh-golang",
+	"dh-sequence-golang",
+	"dpkg-build-api",
+	"golang-any",
+	"golang-go",
+}
+
+// existingPackaging holds the parameters of the packaging in an existing
+// repository which WriteTemplates needs to regenerate it.
+type existingPackaging struct {
+	gopkg        string
+	debsrc       string
+	debLib       string
+	debProg      string
+	debversion   string
+	pkgType      golangdeb.PackageType
+	dependencies []string
+	dep14        bool
+	pristineTar  bool
+	upstream     golangdeb.Upstream
+}
+
+// readExistingPackaging determines the parameters of the packaging in dir
+// from its debian/ files and, for vendored packages, vendor/modules.txt.
+func readExistingPackaging(dir string) (*existingPackaging, error) {
+	ctrl, err := control.ParseControlFile(filepath.Join(dir, "debian", "control"))
+	if err != nil {
+		return nil, fmt.Errorf("parse debian/control: %w", err)
+	}
+	src := ctrl.Source.Values
+	p := &existingPackaging{debsrc: strings.TrimSpace(src["Source"])}
+	if importPaths := golangdeb.SplitList(src["XS-Go-Import-Path"]); len(importPaths) > 0 {
+		p.gopkg = importPaths[0]
+	}
+	if p.gopkg == "" {
+		return nil, fmt.Errorf("no XS-Go-Import-Path in debian/control")
+	}
+	for _, dep := range golangdeb.SplitList(src["Build-Depends"]) {
+		name, _, _ := strings.Cut(strings.Fields(dep)[0], ":")
+		if !slices.Contains(fixedBuildDepends, name) {
+			p.dependencies = append(p.dependencies, dep)
+		}
+	}
+
+	var libFirst bool
+	for _, bin := range ctrl.Binaries {
+		name := strings.TrimSpace(bin.Package)
+		switch {
+		case strings.HasSuffix(name, "-dev") && p.debLib == "":
+			p.debLib = name
+			libFirst = p.debProg == ""
+		case !strings.HasSuffix(name, "-dev") && p.debProg == "":
+			p.debProg = name
+		}
+	}
+	switch {
+	case p.debLib != "" && p.debProg != "" && libFirst:
+		p.pkgType = golangdeb.TypeLibraryProgram
+	case p.debLib != "" && p.debProg != "":
+		p.pkgType = golangdeb.TypeProgramLibrary
+	case p.debLib != "":
+		p.pkgType = golangdeb.TypeLibrary
+	case p.debProg != "":
+		p.pkgType = golangdeb.TypeProgram
+	default:
+		return nil, fmt.Errorf("no binary packages in debian/control")
+	}
+
+	entry, err := changelog.ParseFileOne(filepath.Join(dir, "debian", "changelog"))
+	if err != nil {
+		return nil, fmt.Errorf("parse debian/changelog: %w", err)
+	}
+	p.debversion = entry.Version.String()
+	// Snapshots of repositories without any tags are versioned 0.0~git…,
+	// see pkgVersionFromGit.
+	p.upstream.HasRelease = !strings.HasPrefix(entry.Version.Version, "0.0~")
+
+	conf, err := parseGbpConf(filepath.Join(dir, "debian", "gbp.conf"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("parse debian/gbp.conf: %w", err)
+	}
+	if branch := conf["DEFAULT"]["debian-branch"]; branch != "" && branch != "master" {
+		p.dep14 = true
+	}
+	p.pristineTar = strings.EqualFold(conf["DEFAULT"]["pristine-tar"], "true")
+	p.upstream.VendorComponent = strings.Contains(conf["DEFAULT"]["components"], "'"+golangdeb.VendorComponent+"'")
+
+	if p.upstream.VendorDirs, p.upstream.HasGodeps, err = readFilesExcluded(filepath.Join(dir, "debian", "copyright")); err != nil {
+		return nil, fmt.Errorf("parse debian/copyright: %w", err)
+	}
+
+	if _, ok := src["XS-Vendored-Sources-Go"]; ok {
+		p.upstream.Vendored = true
+		if p.upstream.VendorMods, err = golangdeb.ReadVendoredModules(dir); err != nil {
+			return nil, fmt.Errorf("read vendored modules: %w", err)
+		}
+	}
+	return p, nil
+}
+
+// readFilesExcluded returns the vendor directories in the Files-Excluded
+// field of the debian/copyright file at path, and whether Godeps/_workspace
+// is excluded.
+func readFilesExcluded(path string) (vendorDirs []string, hasGodeps bool, _ error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	defer f.Close()
+	pr, err := control.NewParagraphReader(f, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	header, err := pr.Next()
+	if err != nil {
+		return nil, false, err
+	}
+	for _, excluded := range strings.Fields(header.Values["Files-Excluded"]) {
+		switch {
+		case excluded == "Godeps/_workspace":
+			hasGodeps = true
+		case filepath.Base(excluded) == "vendor":
+			vendorDirs = append(vendorDirs, excluded)
+		}
+	}
+	return vendorDirs, hasGodeps, nil
+}
+
+// parseRegeneratedFiles parses the comma-separated list of files in debian/
+// to regenerate (see -regenerate-files), all regeneratedFiles if empty.
+func parseRegeneratedFiles(list string) ([]string, error) {
+	if strings.TrimSpace(list) == "" {
+		return regeneratedFiles, nil
+	}
+	var names []string
+	for _, name := range golangdeb.SplitList(list) {
+		name = strings.TrimPrefix(name, "debian/")
+		if !slices.Contains(regeneratedFiles, name) {
+			return nil, fmt.Errorf("cannot regenerate %q, only %s", name, strings.Join(regeneratedFiles, ", "))
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// regenerateTemplates regenerates the given regeneratedFiles of the
+// packaging in dir and prints the differences to the existing files as
+// unified diffs. Only with write, the files are updated (see
+// updateRegeneratedFiles).
+func regenerateTemplates(ctx context.Context, gen *golangdeb.Generator, dir string, names []string, write bool, d *golangdeb.DistroProfile, suite golangdeb.TargetSuite) error {
+	p, err := readExistingPackaging(dir)
+	if err != nil {
+		return err
+	}
+	log.Printf("Regenerating debian/ of %s (%s)\n", p.debsrc, p.gopkg)
+	if gen.Metadata, err = golangdeb.FetchMetadata(ctx, newGitHubClient(), p.gopkg); err != nil {
+		return err
+	}
+
+	tmp, err := os.MkdirTemp("", "dh-make-golang")
+	if err != nil {
+		return fmt.Errorf("create temp dir: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+	if err := gen.WriteTemplates(tmp, p.gopkg, p.debsrc, p.debLib, p.debProg, p.debversion,
+		p.pkgType, p.dependencies, &p.upstream, d, suite, p.dep14, p.pristineTar); err != nil {
+		return err
+	}
+	return updateRegeneratedFiles(ctx, dir, tmp, names, write)
+}
+
+// updateRegeneratedFiles prints the differences between the given files in
+// the debian/ directories of the packaging in dir and of the regenerated
+// packaging in tmp. With write, it then copies the changed files into dir,
+// but only if the existing ones are committed to git unmodified, so that
+// "git checkout" reverts the changes.
+func updateRegeneratedFiles(ctx context.Context, dir, tmp string, names []string, write bool) error {
+	var changed, created []string
+	for _, name := range names {
+		generated := filepath.Join(tmp, "debian", name)
+		if _, err := os.Stat(generated); os.IsNotExist(err) {
+			continue // e.g. no gbp.conf without -dep14
+		}
+		dst := filepath.Join(dir, "debian", name)
+		same, err := sameContents(dst, generated)
+		if err != nil {
+			return err
+		}
+		if same {
+			continue
+		}
+		if err := printDiff(ctx, "debian/"+name, dst, generated); err != nil {
+			return err
+		}
+		if _, err := os.Stat(dst); os.IsNotExist(err) {
+			created = append(created, "debian/"+name)
+		} else {
+			changed = append(changed, "debian/"+name)
+		}
+	}
+
+	switch {
+	case len(changed)+len(created) == 0:
+		log.Printf("debian/ is up to date\n")
+		return nil
+	case !write:
+		log.Printf("Use -write to apply the changes to %s\n", strings.Join(append(changed, created...), ", "))
+		return nil
+	}
+
+	for _, name := range changed {
+		// Untracked, ignored or modified files print a status line.
+		status, err := gitStatus(ctx, dir, name)
+		if err != nil {
+			return fmt.Errorf("git status %s: %w", name, err)
+		}
+		if status != "" {
+			return fmt.Errorf("%s is not committed to git unmodified, not overwriting it", name)
+		}
+	}
+	for _, name := range append(changed, created...) {
+		dst := filepath.Join(dir, name)
+		mode := os.FileMode(0644)
+		if fi, err := os.Stat(dst); err == nil {
+			mode = fi.Mode().Perm()
+		}
+		generated, err := os.ReadFile(filepath.Join(tmp, name))
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(dst, generated, mode); err != nil {
+			return err
+		}
+	}
+
+	if len(changed) > 0 {
+		log.Printf("Updated %s, review the changes with \"git diff\" (\"git checkout -- %s\" reverts them)\n",
+			strings.Join(changed, ", "), strings.Join(changed, " "))
+	}
+	if len(created) > 0 {
+		log.Printf("Created %s\n", strings.Join(created, ", "))
+	}
+	return nil
+}
+
+// sameContents reports whether the file a (which might not exist) has the
+// same contents as the file b.
+func sameContents(a, b string) (bool, error) {
+	existing, err := os.ReadFile(a)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	generated, err := os.ReadFile(b)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(existing, generated), nil
+}
+
+// gitStatus returns the short git status of the file name in the git
+// checkout dir, including whether it is ignored. It is empty for files
+// committed unmodified.
+func gitStatus(ctx context.Context, dir, name string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", "status", "--porcelain", "--ignored", "--", name)
+	cmd.Dir = dir
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	return strings.TrimSpace(string(out)), err
+}
+
+// printDiff prints the differences between the files old (which might not
+// exist) and new, labelled as name, as a unified diff.
+func printDiff(ctx context.Context, name, old, new string) error {
+	if _, err := os.Stat(old); os.IsNotExist(err) {
+		old = os.DevNull
+	}
+	cmd := exec.CommandContext(ctx, "diff", "-u", "--label", "a/"+name, "--label", "b/"+name, old, new)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	// diff exits with status 1 if the files differ.
+	if err := cmd.Run(); err != nil {
+		if exitErr := (*exec.ExitError)(nil); errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+			return nil
+		}
+		return fmt.Errorf("diff: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/Debian/dh-make-golang/pkg/golangdeb"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestReadExistingPackaging(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"control": `Source: golang-github-foo-bar
+Section: golang
+Maintainer: Debian Go Packaging Team <team+pkg-go@tracker.debian.org>
+Build-Depends: debhelper-compat (= 13),
+               dh-sequence-golang,
+               golang-any,
+               golang-github-baz-qux-dev (>= 1.2),
+               golang-golang-x-sys-dev
+Standards-Version: 4.7.0
+XS-Go-Import-Path: github.com/foo/bar, github.com/foo/bar/v2
+
+Package: bar
+Architecture: any
+Description: bar (program)
+
+Package: golang-github-foo-bar-dev
+Architecture: all
+Description: bar (library)
+`,
+		"changelog": `golang-github-foo-bar (1.0.0-1) unstable; urgency=medium
+
+  * Initial release (Closes: #1234)
+
+ -- Jane Doe <jdoe@example.org>  Mon, 01 Jan 2024 00:00:00 +0000
+`,
+		"gbp.conf": `[DEFAULT]
+debian-branch = debian/sid
+dist = DEP14
+`,
+		"copyright": `Format: https://www.debian.org/doc/packaging-manuals/copyright-format/1.0/
+Source: https://github.com/foo/bar
+Files-Excluded:
+  vendor
+  internal/vendor
+  Godeps/_workspace
+
+Files: *
+Copyright: 2024 Foo
+License: Expat
+`,
+	}
+	if err := os.Mkdir(filepath.Join(dir, "debian"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, "debian", name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := readExistingPackaging(dir)
+	if err != nil {
+		t.Fatalf("readExistingPackaging: %v", err)
+	}
+	want := &existingPackaging{
+		gopkg:        "github.com/foo/bar",
+		debsrc:       "golang-github-foo-bar",
+		debLib:       "golang-github-foo-bar-dev",
+		debProg:      "bar",
+		debversion:   "1.0.0-1",
+		pkgType:      golangdeb.TypeProgramLibrary,
+		dependencies: []string{"golang-github-baz-qux-dev (>= 1.2)", "golang-golang-x-sys-dev"},
+		dep14:        true,
+		upstream: golangdeb.Upstream{
+			HasRelease: true,
+			VendorDirs: []string{"vendor", "internal/vendor"},
+			HasGodeps:  true,
+		},
+	}
+	if diff := cmp.Diff(want, got, cmp.AllowUnexported(existingPackaging{})); diff != "" {
+		t.Errorf("readExistingPackaging: unexpected result (-want +got):\n%s", diff)
+	}
+}
+
+func TestParseRegeneratedFiles(t *testing.T) {
+	got, err := parseRegeneratedFiles("control, debian/copyright")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"control", "copyright"}; !slices.Equal(got, want) {
+		t.Errorf("parseRegeneratedFiles: got %q, want %q", got, want)
+	}
+	if got, err := parseRegeneratedFiles(""); err != nil || !slices.Equal(got, regeneratedFiles) {
+		t.Errorf("parseRegeneratedFiles(\"\"): got %q, %v, want %q", got, err, regeneratedFiles)
+	}
+	if _, err := parseRegeneratedFiles("control,rules"); err == nil {
+		t.Error("parseRegeneratedFiles(\"control,rules\"): got no error")
+	}
+}
+
+func TestUpdateRegeneratedFiles(t *testing.T) {
+	dir, tmp := t.TempDir(), t.TempDir()
+	readFile := func(name string) string {
+		t.Helper()
+		b, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+
+	gitCmdOrFatal(t, dir, "init", "--quiet")
+	gitCmdOrFatal(t, dir, "config", "user.email", "unittest@example.com")
+	gitCmdOrFatal(t, dir, "config", "user.name", "Unit Test")
+	writeFiles(t, dir, map[string]string{"debian/control": "old control\n", "debian/copyright": "old copyright\n"})
+	gitCmdOrFatal(t, dir, "add", ".")
+	gitCmdOrFatal(t, dir, "commit", "--quiet", "-m", "Initial packaging")
+	writeFiles(t, dir, map[string]string{"debian/watch": "untracked watch\n"})
+	writeFiles(t, tmp, map[string]string{
+		"debian/control":      "new control\n",
+		"debian/copyright":    "old copyright\n",
+		"debian/watch":        "new watch\n",
+		"debian/salsa-ci.yml": "new salsa-ci.yml\n",
+	})
+
+	// Only the differences are shown by default.
+	if err := updateRegeneratedFiles(t.Context(), dir, tmp, regeneratedFiles, false); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile("debian/control"); got != "old control\n" {
+		t.Errorf("without write: debian/control = %q, want it unchanged", got)
+	}
+
+	// The untracked debian/watch is not overwritten.
+	if err := updateRegeneratedFiles(t.Context(), dir, tmp, regeneratedFiles, true); err == nil {
+		t.Error("overwriting untracked debian/watch: got no error")
+	}
+	if got := readFile("debian/control"); got != "old control\n" {
+		t.Errorf("after refusing: debian/control = %q, want it unchanged", got)
+	}
+
+	if err := updateRegeneratedFiles(t.Context(), dir, tmp, []string{"control", "salsa-ci.yml"}, true); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{
+		"debian/control":      "new control\n",
+		"debian/salsa-ci.yml": "new salsa-ci.yml\n",
+		"debian/watch":        "untracked watch\n",
+	} {
+		if got := readFile(name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+
+	// Uncommitted changes are not overwritten either.
+	writeFiles(t, tmp, map[string]string{"debian/control": "newer control\n"})
+	if err := updateRegeneratedFiles(t.Context(), dir, tmp, []string{"control"}, true); err == nil {
+		t.Error("overwriting modified debian/control: got no error")
+	}
+}
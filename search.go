@@ -1,87 +1,192 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
-	"net/http"
 	"os"
 	"regexp"
+	"slices"
+	"sort"
 	"strings"
-)
 
-const (
-	golangBinariesURL = "https://api.ftp-master.debian.org/binary/by_metadata/Go-Import-Path"
+	"github.com/Debian/dh-make-golang/pkg/golangdeb"
+	"golang.org/x/sync/errgroup"
 )
 
-type debianPackage struct {
-	binary string
-	source string
+// searchResult is a Go package in Debian matching a search.
+type searchResult struct {
+	ImportPath string `json:"import_path"`
+	Binary     string `json:"binary"`
+	Source     string `json:"source"`
+	Version    string `json:"version,omitempty"`     // version in the archive, if known
+	NewVersion string `json:"new_version,omitempty"` // version in the NEW queue, if any
 }
 
-type ftpMasterApiResult struct {
-	Binary        string `json:"binary"`
-	MetadataValue string `json:"metadata_value"`
-	Source        string `json:"source"`
+// fuzzyMatch reports whether all characters of pattern appear in s in the
+// same order, ignoring case.
+func fuzzyMatch(pattern, s string) bool {
+	s = strings.ToLower(s)
+	for _, r := range strings.ToLower(pattern) {
+		i := strings.IndexRune(s, r)
+		if i < 0 {
+			return false
+		}
+		s = s[i+len(string(r)):]
+	}
+	return true
 }
 
-type getGolangBinariesConfig struct {
-	url string
+// search returns the packages whose import path, binary or source package
+// name matches, sorted by import path.
+func search(golangBinaries map[string]golangdeb.DebianPackage, sourcesInNew map[string]string, match func(string) bool) []searchResult {
+	var results []searchResult
+	for importPath, pkg := range golangBinaries {
+		if !match(importPath) && !match(pkg.Binary) && !match(pkg.Source) {
+			continue
+		}
+		results = append(results, searchResult{
+			ImportPath: importPath,
+			Binary:     pkg.Binary,
+			Source:     pkg.Source,
+			Version:    pkg.Version,
+			NewVersion: sourcesInNew[pkg.Source],
+		})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].ImportPath < results[j].ImportPath
+	})
+	return results
 }
 
-type getGolangBinariesOption func(cfg *getGolangBinariesConfig)
+// didYouMean returns the packaged other major versions of the given
+// import path if it is not packaged itself: lower ones as guessed by
+// [otherVersions], and higher ones with the same path prefix.
+func didYouMean(golangBinaries map[string]golangdeb.DebianPackage, sourcesInNew map[string]string, importPath string) []searchResult {
+	if _, ok := golangBinaries[importPath]; ok {
+		return nil
+	}
+	base, major := modulePathMajor(importPath)
+	mods := otherVersions(importPath)
+	var higher []string
+	for mod := range golangBinaries {
+		if b, m := modulePathMajor(mod); b == base && m > major {
+			higher = append(higher, mod)
+		}
+	}
+	sort.Slice(higher, func(i, j int) bool {
+		_, mi := modulePathMajor(higher[i])
+		_, mj := modulePathMajor(higher[j])
+		return mi > mj
+	})
+	mods = append(higher, mods...)
 
-func getGolangBinariesUrl(url string) getGolangBinariesOption {
-	return func(cfg *getGolangBinariesConfig) {
-		cfg.url = url
+	var results []searchResult
+	for _, mod := range mods {
+		if pkg, ok := golangBinaries[mod]; ok {
+			results = append(results, searchResult{
+				ImportPath: mod,
+				Binary:     pkg.Binary,
+				Source:     pkg.Source,
+				Version:    pkg.Version,
+				NewVersion: sourcesInNew[pkg.Source],
+			})
+		}
 	}
+	return results
 }
 
-func getGolangBinaries(opts ...getGolangBinariesOption) (map[string]debianPackage, error) {
-	cfg := &getGolangBinariesConfig{url: golangBinariesURL}
-	for _, opt := range opts {
-		opt(cfg)
-	}
-	golangBinaries := make(map[string]debianPackage)
+// maxVersionLookups limits the number of source packages addVersions looks
+// up, so that broad searches do not send hundreds of requests.
+const maxVersionLookups = 50
 
-	resp, err := http.Get(cfg.url)
-	if err != nil {
-		return nil, fmt.Errorf("getting %q: %w", cfg.url, err)
+// addVersions fills in the archive versions missing from results, which
+// the ftp-master API does not provide, by looking up the version of each
+// source package in the development suite of d.
+func addVersions(ctx context.Context, d *golangdeb.DistroProfile, results ...[]searchResult) error {
+	var sources []string
+	for _, r := range slices.Concat(results...) {
+		if r.Version == "" && !slices.Contains(sources, r.Source) {
+			sources = append(sources, r.Source)
+		}
 	}
-	defer resp.Body.Close()
-	if got, want := resp.StatusCode, http.StatusOK; got != want {
-		return nil, fmt.Errorf("unexpected HTTP status code: got %d, want %d", got, want)
+	if len(sources) == 0 || d.DscInSuiteURL == "" {
+		return nil
 	}
-	var pkgs []ftpMasterApiResult
-	if err := json.NewDecoder(resp.Body).Decode(&pkgs); err != nil {
-		return nil, fmt.Errorf("decode: %w", err)
+	if len(sources) > maxVersionLookups {
+		return fmt.Errorf("not looking up the versions of %d source packages, use -archive=apt or -archive=mirror", len(sources))
 	}
-	for _, pkg := range pkgs {
-		if !strings.HasSuffix(pkg.Binary, "-dev") {
-			continue // skip -dbgsym packages etc.
-		}
-		for importPath := range strings.SplitSeq(pkg.MetadataValue, ",") {
-			// XS-Go-Import-Path can be comma-separated and contain spaces.
-			golangBinaries[strings.TrimSpace(importPath)] = debianPackage{
-				binary: pkg.Binary,
-				source: pkg.Source,
+
+	versions := make([]string, len(sources))
+	var eg errgroup.Group
+	eg.SetLimit(8)
+	for i, source := range sources {
+		eg.Go(func() error {
+			var err error
+			versions[i], err = getDebianVersion(ctx, d, d.DevelSuite, source)
+			return err
+		})
+	}
+	err := eg.Wait()
+	for _, rs := range results {
+		for i := range rs {
+			if rs[i].Version == "" {
+				rs[i].Version = versions[slices.Index(sources, rs[i].Source)]
 			}
 		}
 	}
-	return golangBinaries, nil
+	return err
 }
 
-func execSearch(args []string) {
+func (r searchResult) String() string {
+	source := r.Source
+	if r.Version != "" {
+		source += " " + r.Version
+	}
+	s := fmt.Sprintf("%s: %s (source: %s)", r.Binary, r.ImportPath, source)
+	if r.NewVersion != "" {
+		s += fmt.Sprintf(" [in NEW as %s]", r.NewVersion)
+	}
+	return s
+}
+
+func execSearch(ctx context.Context, args []string) {
 	fs := flag.NewFlagSet("search", flag.ExitOnError)
 
 	fs.Usage = func() {
-		fmt.Fprintf(os.Stderr, `Usage: %s search <pattern>
+		fmt.Fprintf(os.Stderr, `Usage: %s search [flags] <pattern>
+Searches Go import paths, binary and source package names in Debian.
 Uses Go's default regexp syntax (https://golang.org/pkg/regexp/syntax/)
 Example: %s search 'debi.*'
+
+Flags:
 `, os.Args[0], os.Args[0])
+		fs.PrintDefaults()
 	}
 
+	var ignoreCase bool
+	fs.BoolVar(&ignoreCase,
+		"i",
+		false,
+		"Match case-insensitively.")
+
+	var fuzzy bool
+	fs.BoolVar(&fuzzy,
+		"fuzzy",
+		false,
+		"Match fuzzily: the characters of <pattern> need to appear in order,\n"+
+			"but not necessarily next to each other (implies -i).")
+
+	var jsonOutput bool
+	fs.BoolVar(&jsonOutput,
+		"json",
+		false,
+		"Print the results as JSON.")
+
+	arch := addArchiveFlags(fs)
+
 	err := fs.Parse(args)
 	if err != nil {
 		log.Fatal(err)
@@ -92,19 +197,66 @@ Example: %s search 'debi.*'
 		os.Exit(1)
 	}
 
-	pattern, err := regexp.Compile(fs.Arg(0))
+	var match func(string) bool
+	if fuzzy {
+		match = func(s string) bool { return fuzzyMatch(fs.Arg(0), s) }
+	} else {
+		expr := fs.Arg(0)
+		if ignoreCase {
+			expr = "(?i)" + expr
+		}
+		pattern, err := regexp.Compile(expr)
+		if err != nil {
+			log.Fatal(err)
+		}
+		match = pattern.MatchString
+	}
+
+	d, err := arch.distro()
 	if err != nil {
 		log.Fatal(err)
 	}
-
-	golangBinaries, err := getGolangBinaries()
+	golangBinaries, err := arch.golangBinaries(ctx)
 	if err != nil {
 		log.Fatal(err)
 	}
+	sourcesInNew, err := getSourcesInNew(ctx, d)
+	if err != nil {
+		log.Printf("Could not get packages in NEW: %v", err)
+	}
 
-	for importPath, pkg := range golangBinaries {
-		if pattern.MatchString(importPath) {
-			fmt.Printf("%s: %s\n", pkg.binary, importPath)
+	results := search(golangBinaries, sourcesInNew, match)
+	alternatives := didYouMean(golangBinaries, sourcesInNew, fs.Arg(0))
+	if err := addVersions(ctx, d, results, alternatives); err != nil {
+		log.Printf("Could not get the versions of the packages: %v", err)
+	}
+
+	if jsonOutput {
+		out := struct {
+			Results    []searchResult `json:"results"`
+			DidYouMean []searchResult `json:"did_you_mean,omitempty"`
+		}{
+			Results:    results,
+			DidYouMean: alternatives,
+		}
+		if out.Results == nil {
+			out.Results = []searchResult{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(out); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	for _, r := range results {
+		fmt.Println(r)
+	}
+	if len(alternatives) > 0 {
+		fmt.Printf("\nDid you mean (other major versions packaged in Debian):\n")
+		for _, r := range alternatives {
+			fmt.Printf("    %s\n", r)
 		}
 	}
 }
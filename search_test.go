@@ -2,106 +2,142 @@ package main
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"path"
+	"regexp"
+	"slices"
+	"sync"
 	"testing"
 
+	"github.com/Debian/dh-make-golang/pkg/golangdeb"
 	"github.com/google/go-cmp/cmp"
-	"github.com/google/go-cmp/cmp/cmpopts"
 )
 
-func TestGetGolangBinaries(t *testing.T) {
+var testGolangBinaries = map[string]golangdeb.DebianPackage{
+	"github.com/example/foo":     {Binary: "golang-github-example-foo-dev", Source: "golang-github-example-foo"},
+	"github.com/example/foo/v3":  {Binary: "golang-github-example-foo-v3-dev", Source: "golang-github-example-foo-v3"},
+	"github.com/example/foo/v10": {Binary: "golang-github-example-foo-v10-dev", Source: "golang-github-example-foo-v10"},
+	"example.org/bar":            {Binary: "golang-example-bar-dev", Source: "bar", Version: "0.9-2"},
+}
+
+func TestSearch(t *testing.T) {
 	t.Parallel()
+	sourcesInNew := map[string]string{"bar": "1.0-1"}
 	for _, tc := range []struct {
-		desc    string
-		results []ftpMasterApiResult
-		want    map[string]debianPackage
+		desc  string
+		match func(string) bool
+		want  []string
 	}{
 		{
-			desc:    "no results",
-			results: nil,
-			want:    map[string]debianPackage{},
-		},
-		{
-			desc: "leading whitespace",
-			results: []ftpMasterApiResult{{
-				Binary:        "golang-example-foo-dev",
-				MetadataValue: " \t\n\rexample.com/foo",
-				Source:        "golang-example-foo",
-			}},
-			want: map[string]debianPackage{
-				"example.com/foo": {
-					binary: "golang-example-foo-dev",
-					source: "golang-example-foo",
-				},
-			},
+			desc:  "import path",
+			match: regexp.MustCompile(`foo/v`).MatchString,
+			want:  []string{"github.com/example/foo/v10", "github.com/example/foo/v3"},
 		},
 		{
-			desc: "trailing whitespace",
-			results: []ftpMasterApiResult{{
-				Binary:        "golang-example-foo-dev",
-				MetadataValue: "example.com/foo \t\n\r",
-				Source:        "golang-example-foo",
-			}},
-			want: map[string]debianPackage{
-				"example.com/foo": {
-					binary: "golang-example-foo-dev",
-					source: "golang-example-foo",
-				},
-			},
+			desc:  "source name",
+			match: regexp.MustCompile(`^bar$`).MatchString,
+			want:  []string{"example.org/bar"},
 		},
 		{
-			desc: "comma separation",
-			results: []ftpMasterApiResult{{
-				Binary:        "golang-example-foo-dev",
-				MetadataValue: "example.com/foo,example.com/bar",
-				Source:        "golang-example-foo",
-			}},
-			want: map[string]debianPackage{
-				"example.com/foo": {
-					binary: "golang-example-foo-dev",
-					source: "golang-example-foo",
-				},
-				"example.com/bar": {
-					binary: "golang-example-foo-dev",
-					source: "golang-example-foo",
-				},
-			},
+			desc:  "case-insensitive",
+			match: regexp.MustCompile(`(?i)EXAMPLE-BAR`).MatchString,
+			want:  []string{"example.org/bar"},
 		},
 		{
-			desc: "space around comma",
-			results: []ftpMasterApiResult{{
-				Binary:        "golang-example-foo-dev",
-				MetadataValue: "example.com/foo ,\n\texample.com/bar",
-				Source:        "golang-example-foo",
-			}},
-			want: map[string]debianPackage{
-				"example.com/foo": {
-					binary: "golang-example-foo-dev",
-					source: "golang-example-foo",
-				},
-				"example.com/bar": {
-					binary: "golang-example-foo-dev",
-					source: "golang-example-foo",
-				},
-			},
+			desc:  "fuzzy",
+			match: func(s string) bool { return fuzzyMatch("EXMPLBR", s) },
+			want:  []string{"example.org/bar"},
 		},
 	} {
 		t.Run(tc.desc, func(t *testing.T) {
 			t.Parallel()
-			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-				if err := json.NewEncoder(w).Encode(tc.results); err != nil {
-					t.Fatal(err)
-				}
-			}))
-			defer ts.Close()
-			got, err := getGolangBinaries(getGolangBinariesUrl(ts.URL))
-			if err != nil {
-				t.Fatal(err)
+			var got []string
+			for _, r := range search(testGolangBinaries, sourcesInNew, tc.match) {
+				got = append(got, r.ImportPath)
 			}
-			if diff := cmp.Diff(tc.want, got, cmpopts.EquateComparable(debianPackage{})); diff != "" {
+			if diff := cmp.Diff(tc.want, got); diff != "" {
 				t.Fatalf("unexpected result (-want +got):\n%s", diff)
 			}
 		})
 	}
+
+	results := search(testGolangBinaries, sourcesInNew, regexp.MustCompile(`bar`).MatchString)
+	if got, want := results[0].String(), "golang-example-bar-dev: example.org/bar (source: bar 0.9-2) [in NEW as 1.0-1]"; got != want {
+		t.Errorf("searchResult.String() => %q, want %q", got, want)
+	}
+	b, err := json.Marshal(results[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(b), `{"import_path":"example.org/bar","binary":"golang-example-bar-dev","source":"bar","version":"0.9-2","new_version":"1.0-1"}`; got != want {
+		t.Errorf("json.Marshal(searchResult) => %s, want %s", got, want)
+	}
+
+	// Without a known archive version, the source is shown alone.
+	results = search(testGolangBinaries, nil, regexp.MustCompile(`foo/v3`).MatchString)
+	if got, want := results[0].String(), "golang-github-example-foo-v3-dev: github.com/example/foo/v3 (source: golang-github-example-foo-v3)"; got != want {
+		t.Errorf("searchResult.String() => %q, want %q", got, want)
+	}
+}
+
+func TestDidYouMean(t *testing.T) {
+	t.Parallel()
+	var got []string
+	for _, r := range didYouMean(testGolangBinaries, nil, "github.com/example/foo/v5") {
+		got = append(got, r.ImportPath)
+	}
+	want := []string{"github.com/example/foo/v10", "github.com/example/foo/v3", "github.com/example/foo"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("unexpected result (-want +got):\n%s", diff)
+	}
+	if got := didYouMean(testGolangBinaries, nil, "github.com/example/foo"); got != nil {
+		t.Errorf("didYouMean() for packaged import path => %v, want nil", got)
+	}
+}
+
+func TestAddVersions(t *testing.T) {
+	var mu sync.Mutex
+	var requested []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requested = append(requested, r.URL.Path)
+		mu.Unlock()
+		fmt.Fprintf(w, `[{"version": "1.0-%d"}]`, len(path.Base(r.URL.Path)))
+	}))
+	defer ts.Close()
+	d := golangdeb.DebianDistro
+	d.DscInSuiteURL = ts.URL + "/dsc_in_suite/"
+
+	results := search(testGolangBinaries, nil, regexp.MustCompile(`^github.com/example/foo(/v3)?$|bar`).MatchString)
+	alternatives := didYouMean(testGolangBinaries, nil, "github.com/example/foo/v2")
+	if err := addVersions(t.Context(), &d, results, alternatives); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, r := range slices.Concat(results, alternatives) {
+		got = append(got, r.Source+" "+r.Version)
+	}
+	want := []string{
+		"bar 0.9-2",
+		"golang-github-example-foo 1.0-25",
+		"golang-github-example-foo-v3 1.0-28",
+		"golang-github-example-foo-v10 1.0-29",
+		"golang-github-example-foo-v3 1.0-28",
+		"golang-github-example-foo 1.0-25",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("addVersions: diff (-want +got):\n%s", diff)
+	}
+	// Only the hits without version are looked up, once per source package.
+	slices.Sort(requested)
+	wantRequested := []string{
+		"/dsc_in_suite/sid/golang-github-example-foo",
+		"/dsc_in_suite/sid/golang-github-example-foo-v10",
+		"/dsc_in_suite/sid/golang-github-example-foo-v3",
+	}
+	if diff := cmp.Diff(wantRequested, requested); diff != "" {
+		t.Errorf("addVersions requests: diff (-want +got):\n%s", diff)
+	}
 }
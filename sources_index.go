@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/Debian/dh-make-golang/pkg/golangdeb"
+	"pault.ag/go/debian/control"
+	debdependency "pault.ag/go/debian/dependency"
+)
+
+// defaultSourcesGlob matches the Sources indices downloaded by apt.
+const defaultSourcesGlob = "/var/lib/apt/lists/*_Sources"
+
+// sourceEntry is the subset of a Sources index paragraph dh-make-golang
+// cares about.
+type sourceEntry struct {
+	name         string
+	version      string
+	binaries     []string
+	buildDepends []string // package names, without version or arch restrictions
+	importPaths  []string // from the Go-Import-Path field
+	vcsBrowser   string
+	vcsGit       string
+}
+
+// parseSourcesIndex parses an APT Sources index, as found on mirrors and
+// in /var/lib/apt/lists/.
+func parseSourcesIndex(r io.Reader) ([]sourceEntry, error) {
+	pr, err := control.NewParagraphReader(r, nil)
+	if err != nil {
+		return nil, err
+	}
+	var entries []sourceEntry
+	for {
+		p, err := pr.Next()
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		entry := sourceEntry{
+			name:        strings.TrimSpace(p.Values["Package"]),
+			version:     strings.TrimSpace(p.Values["Version"]),
+			binaries:    golangdeb.SplitList(p.Values["Binary"]),
+			importPaths: golangdeb.SplitList(p.Values["Go-Import-Path"]),
+			vcsBrowser:  strings.TrimSpace(p.Values["Vcs-Browser"]),
+			vcsGit:      strings.TrimSpace(p.Values["Vcs-Git"]),
+		}
+		idx := control.SourceIndex{Paragraph: *p}
+		for _, dep := range []debdependency.Dependency{idx.GetBuildDepends(), idx.GetBuildDependsArch(), idx.GetBuildDependsIndep()} {
+			for _, poss := range dep.GetAllPossibilities() {
+				entry.buildDepends = append(entry.buildDepends, strings.TrimSpace(poss.Name))
+			}
+		}
+		entries = append(entries, entry)
+	}
+}
+
+// readSourcesIndexFile parses the Sources index at path, which may be
+// gzip-compressed.
+func readSourcesIndexFile(ctx context.Context, path string) ([]sourceEntry, error) {
+	rc, err := golangdeb.OpenIndex(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	entries, err := parseSourcesIndex(rc)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return entries, nil
+}
+
+// loadSourcesIndices parses all Sources indices matching the given glob
+// pattern. When a source package appears in more than one index, the
+// entry with the highest version wins.
+func loadSourcesIndices(ctx context.Context, pattern string) (map[string]sourceEntry, error) {
+	paths, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no Sources index matches %q (is there a deb-src line in your APT sources?)", pattern)
+	}
+	sources := make(map[string]sourceEntry)
+	for _, path := range paths {
+		entries, err := readSourcesIndexFile(ctx, path)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if prev, ok := sources[entry.name]; ok && golangdeb.CompareVersions(prev.version, entry.version) >= 0 {
+				continue
+			}
+			sources[entry.name] = entry
+		}
+	}
+	return sources, nil
+}
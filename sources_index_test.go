@@ -0,0 +1,61 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testSourcesIndex = `Package: golang-github-example-foo
+Binary: golang-github-example-foo-dev
+Version: 1.2.0-1
+Build-Depends: debhelper-compat (= 13), dh-sequence-golang, golang-any
+Go-Import-Path: github.com/example/foo
+Vcs-Browser: https://salsa.debian.org/go-team/packages/golang-github-example-foo
+Vcs-Git: https://salsa.debian.org/go-team/packages/golang-github-example-foo.git
+
+Package: golang-github-example-bar
+Binary: golang-github-example-bar-dev
+Version: 0.1.0-2
+Build-Depends: debhelper-compat (= 13), dh-sequence-golang, golang-any,
+ golang-github-example-foo-dev (>= 1.0)
+Go-Import-Path: github.com/example/bar, github.com/example/bar/v2
+Vcs-Browser: https://salsa.debian.org/go-team/packages/golang-github-example-bar
+
+Package: baz
+Binary: baz
+Version: 3.0-1
+Build-Depends: debhelper-compat (= 13), dh-sequence-golang
+Build-Depends-Indep: golang-github-example-bar-dev
+Go-Import-Path: example.org/baz
+
+Package: qux
+Binary: qux
+Version: 1.0-1
+Build-Depends: debhelper-compat (= 13), golang-github-example-foo-dev [amd64] | gccgo
+Go-Import-Path: example.org/qux
+`
+
+func TestParseSourcesIndex(t *testing.T) {
+	entries, err := parseSourcesIndex(strings.NewReader(testSourcesIndex))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(entries), 4; got != want {
+		t.Fatalf("parseSourcesIndex() returned %d entries, want %d", got, want)
+	}
+	want := sourceEntry{
+		name:         "golang-github-example-bar",
+		version:      "0.1.0-2",
+		binaries:     []string{"golang-github-example-bar-dev"},
+		buildDepends: []string{"debhelper-compat", "dh-sequence-golang", "golang-any", "golang-github-example-foo-dev"},
+		importPaths:  []string{"github.com/example/bar", "github.com/example/bar/v2"},
+		vcsBrowser:   "https://salsa.debian.org/go-team/packages/golang-github-example-bar",
+	}
+	if got := entries[1]; !reflect.DeepEqual(got, want) {
+		t.Errorf("parseSourcesIndex() entry 1 => %+v, want %+v", got, want)
+	}
+	if got, want := entries[3].buildDepends, []string{"debhelper-compat", "golang-github-example-foo-dev", "gccgo"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("parseSourcesIndex() alternatives => %v, want %v", got, want)
+	}
+}
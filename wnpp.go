@@ -0,0 +1,239 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/Debian/dh-make-golang/pkg/golangdeb"
+)
+
+// debbugsSOAPURL is the SOAP interface of the Debian bug tracker, see
+// https://wiki.debian.org/DebbugsSoapInterface
+const debbugsSOAPURL = "https://bugs.debian.org/cgi-bin/soap.cgi"
+
+// wnppBug is an open bug against the WNPP (Work-Needing and Prospective
+// Packages) pseudo-package.
+type wnppBug struct {
+	Number    int    `json:"bug"`
+	Subject   string `json:"subject"`
+	Submitter string `json:"submitter,omitempty"`
+	URL       string `json:"url,omitempty"` // upstream URL, if known
+}
+
+// wnppSubjectRegexp matches subjects like “ITP: golang-foo -- description”.
+var wnppSubjectRegexp = regexp.MustCompile(`^(ITP|RFP|ITA|RFA|RFH|O):\s*(\S+)`)
+
+// kind returns the type of the bug (e.g. “ITP” or “RFP”) and the package
+// it is about, according to its subject.
+func (b wnppBug) kind() (kind, pkg string) {
+	m := wnppSubjectRegexp.FindStringSubmatch(strings.TrimSpace(b.Subject))
+	if m == nil {
+		return "", ""
+	}
+	return m[1], m[2]
+}
+
+// wnppSource provides the open WNPP bugs.
+type wnppSource interface {
+	// wnppBugs returns the open WNPP bugs, only those reported by submitter
+	// (an email address) if non-empty.
+	wnppBugs(ctx context.Context, submitter string) ([]wnppBug, error)
+}
+
+// newWNPPSource returns the wnppSource for location, which is either the
+// URL of a debbugs SOAP interface, or the URL or path of a JSON file
+// containing a list of wnppBug objects (ending in .json).
+func newWNPPSource(location string) wnppSource {
+	if strings.HasSuffix(location, ".json") {
+		return wnppJSON{location: location}
+	}
+	return debbugsSOAP{url: location}
+}
+
+// findWNPPDuplicates returns the ITP and RFP bugs in bugs about the
+// package debsrc or the given upstream, e.g. an RFP filed under a
+// different name.
+func findWNPPDuplicates(bugs []wnppBug, debsrc, gopkg, homepage string) []wnppBug {
+	trimURL := func(url string) string {
+		url = strings.TrimPrefix(strings.TrimPrefix(url, "http://"), "https://")
+		return strings.TrimSuffix(url, "/")
+	}
+	upstreams := []string{gopkg}
+	if h := trimURL(homepage); h != "" && h != gopkg {
+		upstreams = append(upstreams, h)
+	}
+
+	var dups []wnppBug
+	for _, b := range bugs {
+		kind, pkg := b.kind()
+		if kind != "ITP" && kind != "RFP" {
+			continue
+		}
+		if pkg == debsrc ||
+			slices.Contains(upstreams, trimURL(b.URL)) ||
+			slices.ContainsFunc(upstreams, func(u string) bool { return strings.Contains(b.Subject, u) }) {
+			dups = append(dups, b)
+		}
+	}
+	return dups
+}
+
+// wnppJSON reads WNPP bugs from a JSON file, e.g. a local mirror.
+type wnppJSON struct {
+	location string // URL or path
+}
+
+func (w wnppJSON) wnppBugs(ctx context.Context, submitter string) ([]wnppBug, error) {
+	var r io.ReadCloser
+	if strings.HasPrefix(w.location, "http://") || strings.HasPrefix(w.location, "https://") {
+		resp, err := golangdeb.HTTPGet(ctx, w.location)
+		if err != nil {
+			return nil, fmt.Errorf("getting %q: %w", w.location, err)
+		}
+		if got, want := resp.StatusCode, http.StatusOK; got != want {
+			resp.Body.Close()
+			return nil, fmt.Errorf("unexpected HTTP status code: got %d, want %d", got, want)
+		}
+		r = resp.Body
+	} else {
+		f, err := os.Open(w.location)
+		if err != nil {
+			return nil, err
+		}
+		r = f
+	}
+	defer r.Close()
+
+	var bugs []wnppBug
+	if err := json.NewDecoder(r).Decode(&bugs); err != nil {
+		return nil, fmt.Errorf("decode %s: %w", w.location, err)
+	}
+	if submitter == "" {
+		return bugs, nil
+	}
+	var filtered []wnppBug
+	for _, b := range bugs {
+		if strings.Contains(b.Submitter, submitter) {
+			filtered = append(filtered, b)
+		}
+	}
+	return filtered, nil
+}
+
+// debbugsSOAP queries the SOAP interface of a debbugs instance.
+type debbugsSOAP struct {
+	url string
+}
+
+// soapStatusBatch is the number of bugs to request the status of at once.
+const soapStatusBatch = 500
+
+func (s debbugsSOAP) wnppBugs(ctx context.Context, submitter string) ([]wnppBug, error) {
+	query := []string{"package", "wnpp"}
+	if submitter != "" {
+		query = append(query, "submitter", submitter)
+	}
+	params := make([]string, len(query))
+	for i, q := range query {
+		params[i] = fmt.Sprintf(`<v%d xsi:type="xsd:string">%s</v%d>`, i+1, html.EscapeString(q), i+1)
+	}
+	var bugsResp struct {
+		Body struct {
+			Response struct {
+				Result struct {
+					Items []int `xml:"item"`
+				} `xml:",any"`
+			} `xml:",any"`
+		} `xml:"Body"`
+	}
+	if err := s.call(ctx, "get_bugs", strings.Join(params, ""), &bugsResp); err != nil {
+		return nil, err
+	}
+
+	numbers := bugsResp.Body.Response.Result.Items
+	var bugs []wnppBug
+	for len(numbers) > 0 {
+		batch := numbers[:min(soapStatusBatch, len(numbers))]
+		numbers = numbers[len(batch):]
+		items := make([]string, len(batch))
+		for i, n := range batch {
+			items[i] = fmt.Sprintf(`<item xsi:type="xsd:int">%d</item>`, n)
+		}
+		param := fmt.Sprintf(`<v1 xsi:type="soapenc:Array" soapenc:arrayType="xsd:int[%d]">%s</v1>`, len(batch), strings.Join(items, ""))
+		var statusResp struct {
+			Body struct {
+				Response struct {
+					Result struct {
+						Items []struct {
+							Key   int `xml:"key"`
+							Value struct {
+								Subject    string `xml:"subject"`
+								Originator string `xml:"originator"`
+								Done       string `xml:"done"`
+							} `xml:"value"`
+						} `xml:"item"`
+					} `xml:",any"`
+				} `xml:",any"`
+			} `xml:"Body"`
+		}
+		if err := s.call(ctx, "get_status", param, &statusResp); err != nil {
+			return nil, err
+		}
+		for _, item := range statusResp.Body.Response.Result.Items {
+			if item.Value.Done != "" {
+				continue // closed, but not yet archived
+			}
+			bugs = append(bugs, wnppBug{
+				Number:    item.Key,
+				Subject:   item.Value.Subject,
+				Submitter: item.Value.Originator,
+			})
+		}
+	}
+	return bugs, nil
+}
+
+// call calls the given SOAP method with the given (XML encoded) parameters
+// and decodes the response into v.
+func (s debbugsSOAP) call(ctx context.Context, method, params string, v any) error {
+	var body bytes.Buffer
+	fmt.Fprintf(&body, `<?xml version="1.0" encoding="UTF-8"?>
+<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/"
+ xmlns:soapenc="http://schemas.xmlsoap.org/soap/encoding/"
+ xmlns:xsd="http://www.w3.org/2001/XMLSchema"
+ xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance"
+ soap:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/">
+<soap:Body><ns:%s xmlns:ns="Debbugs/SOAP">%s</ns:%s></soap:Body>
+</soap:Envelope>
+`, method, params, method)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/xml; charset=utf-8")
+	req.Header.Set("SOAPAction", strconv.Quote("Debbugs/SOAP#"+method))
+	resp, err := golangdeb.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s: %w", method, err)
+	}
+	defer resp.Body.Close()
+	if got, want := resp.StatusCode, http.StatusOK; got != want {
+		return fmt.Errorf("%s: unexpected HTTP status code: got %d, want %d", method, got, want)
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("%s: decode: %w", method, err)
+	}
+	return nil
+}